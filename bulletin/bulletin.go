@@ -0,0 +1,147 @@
+package bulletin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	board "github.com/simonlangowski/lightning1/bulletin/messages"
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+/*
+Public append-only bulletin board
+
+Each entry commits to the hash of the entry before it,
+so anyone holding the hash of the head can check that an earlier entry
+was included and has not been changed since
+
+Anytrust groups post the signed submissions of their clients
+and the final messages of each round
+Clients check their submission was posted, and auditors check the whole chain
+
+- Boards index entries by the hash of their data, so a client finds its submission with one request
+- Inclusion is checked by fetching the entries from the submission to the head in ranges,
+  so a client only reads the entries posted after its submission
+*/
+
+type BulletinBoard interface {
+	// Append data and return the new entry
+	Post(data []byte) (*board.Entry, error)
+	// Get the entry at an index
+	Get(index int64) (*board.Entry, error)
+	// Get the length and hash of the last entry
+	Head() (*board.BoardHead, error)
+	// Get the index of the first entry with data of the hash, or -1
+	Find(dataHash []byte) (int64, error)
+	// Get the entries from index from up to to, at most MAX_RANGE at a time
+	GetRange(from, to int64) ([]*board.Entry, error)
+}
+
+const HASH_SIZE = sha256.Size
+
+// the most entries returned by one GetRange
+const MAX_RANGE = 1024
+
+// the previous hash of the first entry
+var genesis = make([]byte, HASH_SIZE)
+
+func EntryHash(index int64, prev, data []byte) []byte {
+	h := sha256.New()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(index))
+	h.Write(b[:])
+	h.Write(prev)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func NewEntry(index int64, prev, data []byte) *board.Entry {
+	return &board.Entry{
+		Index: index,
+		Prev:  prev,
+		Data:  data,
+		Hash:  EntryHash(index, prev, data),
+	}
+}
+
+func DataHash(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+// check the entry is at index and follows prev
+func VerifyEntry(e *board.Entry, index int64, prev []byte) bool {
+	if e == nil || e.Index != index || !bytes.Equal(e.Prev, prev) {
+		return false
+	}
+	return bytes.Equal(e.Hash, EntryHash(e.Index, e.Prev, e.Data))
+}
+
+// Check that data was posted at index in the board ending at the trusted head
+func VerifyInclusion(b BulletinBoard, index int64, data []byte, head *board.BoardHead) bool {
+	if index < 0 || index >= head.Length {
+		return false
+	}
+	var prev []byte
+	// follow the chain up to the head
+	err := walk(b, index, head.Length, func(e *board.Entry) bool {
+		if e.Index == index {
+			if !bytes.Equal(e.Data, data) {
+				return false
+			}
+			prev = e.Prev
+		}
+		if !VerifyEntry(e, e.Index, prev) {
+			return false
+		}
+		prev = e.Hash
+		return true
+	})
+	return err == nil && bytes.Equal(prev, head.Hash)
+}
+
+// Call f on each entry from index from up to to, fetched in ranges
+// stops with an error if an entry is missing or f returns false
+func walk(b BulletinBoard, from, to int64, f func(e *board.Entry) bool) error {
+	for i := from; i < to; {
+		end := i + MAX_RANGE
+		if end > to {
+			end = to
+		}
+		entries, err := b.GetRange(i, end)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return errors.EntryNotFound(int(i))
+		}
+		for _, e := range entries {
+			if e.Index != i || !f(e) {
+				return errors.ChainInvalid()
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// Check every entry from the start of the board, and return the verified head
+func VerifyChain(b BulletinBoard) (*board.BoardHead, bool) {
+	head, err := b.Head()
+	if err != nil {
+		return nil, false
+	}
+	prev := genesis
+	err = walk(b, 0, head.Length, func(e *board.Entry) bool {
+		if !VerifyEntry(e, e.Index, prev) {
+			return false
+		}
+		prev = e.Hash
+		return true
+	})
+	if err != nil || !bytes.Equal(prev, head.Hash) {
+		return nil, false
+	}
+	return head, true
+}
@@ -0,0 +1,205 @@
+package bulletin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	board "github.com/simonlangowski/lightning1/bulletin/messages"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"google.golang.org/grpc"
+)
+
+func TestFileBoard(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "test.board")
+	b, err := NewFileBoard(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vk, sk := crypto.NewSigningKeyPair()
+	submissions := make([]*messages.SignedMessage, 5)
+	for i := range submissions {
+		m := messages.NewSignedMessage(100, 1, 0, i, 0, 0, 1, messages.NetworkMessage_ClientMessageSubmission)
+		m.Signature = crypto.SignData(sk, m.GetSignedData())
+		submissions[i] = m
+		_, err = b.Post(SubmissionEntry(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	head, ok := VerifyChain(b)
+	if !ok || head.Length != int64(len(submissions)) {
+		t.Fatalf("Chain did not verify")
+	}
+	index := FindSubmission(b, 0, submissions[3])
+	if index != 3 || !VerifyInclusion(b, index, SubmissionEntry(submissions[3]), head) {
+		t.Fatalf("Submission not found")
+	}
+	if VerifyInclusion(b, 2, SubmissionEntry(submissions[3]), head) {
+		t.Fatalf("Submission found at the wrong index")
+	}
+	if FindSubmission(b, 4, submissions[3]) != -1 {
+		t.Fatalf("Submission found before the start index")
+	}
+	// readers asking past the head do not stop the board
+	for i := 0; i < 2; i++ {
+		if _, err := b.Get(head.Length); err == nil {
+			t.Fatalf("Entry past the head found")
+		}
+		for _, r := range [][2]int64{{head.Length + 1, head.Length}, {-1, 2}, {3, 1}} {
+			if _, err := b.GetRange(r[0], r[1]); err == nil {
+				t.Fatalf("Range %v found", r)
+			}
+		}
+	}
+	parsed := ParseSubmission(PackSubmission(submissions[1]))
+	if parsed.Sender != 1 || !crypto.Verify(vk, parsed.GetSignedData(), parsed.Signature) {
+		t.Fatalf("Submission not parsed")
+	}
+
+	// the board is reloaded from the file
+	b.Close()
+	b, err = NewFileBoard(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := b.Head()
+	if err != nil || reloaded.Length != head.Length || string(reloaded.Hash) != string(head.Hash) {
+		t.Fatalf("Board not reloaded")
+	}
+	_, err = b.Post([]byte("next"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an old head is still a prefix of the board
+	if !VerifyInclusion(b, 0, SubmissionEntry(submissions[0]), head) {
+		t.Fatalf("Inclusion under old head did not verify")
+	}
+	b.Close()
+
+	// a record that was only partly written at the end is dropped
+	raw, _ := os.ReadFile(fn)
+	os.WriteFile(fn, raw[:len(raw)-3], 0600)
+	b, err = NewFileBoard(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err = b.Head()
+	if err != nil || reloaded.Length != head.Length || string(reloaded.Hash) != string(head.Hash) {
+		t.Fatalf("Partial record not dropped")
+	}
+	b.Close()
+
+	// modifying a length breaks the chain, and leaves the later entries in the file
+	raw, _ = os.ReadFile(fn)
+	modified := append([]byte{}, raw...)
+	modified[1] ^= 1
+	os.WriteFile(fn, modified, 0600)
+	_, err = NewFileBoard(fn)
+	if err == nil {
+		t.Fatalf("Board with a modified length loaded")
+	}
+	if after, _ := os.ReadFile(fn); len(after) != len(raw) {
+		t.Fatalf("Board with a modified length truncated")
+	}
+
+	// modifying an entry breaks the chain
+	modified = append([]byte{}, raw...)
+	modified[10] ^= 1
+	os.WriteFile(fn, modified, 0600)
+	_, err = NewFileBoard(fn)
+	if err == nil {
+		t.Fatalf("Modified board loaded")
+	}
+}
+
+func TestRemoteAudit(t *testing.T) {
+	b, err := NewFileBoard(filepath.Join(t.TempDir(), "test.board"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grpc.NewServer()
+	NewServer(b).Register(g)
+	go g.Serve(lis)
+	defer g.Stop()
+	cc, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	r := NewRemoteBoard(cc)
+
+	vks := make([]crypto.VerificationKey, 3)
+	sks := make([]crypto.SigningKey, 3)
+	for i := range vks {
+		vks[i], sks[i] = crypto.NewSigningKeyPair()
+	}
+	msgs := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	// members of the group post the same messages in different orders
+	posts := []*FinalMessages{
+		NewFinalMessages(1, 0, 0, msgs, sks[0]),
+		NewFinalMessages(1, 0, 1, [][]byte{msgs[2], msgs[0], msgs[1]}, sks[1]),
+		NewFinalMessages(1, 0, 2, msgs[:2], sks[2]),
+	}
+	for _, f := range posts {
+		_, err = b.Post(f.Marshal())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// signed by the wrong server
+	forged := NewFinalMessages(1, 0, 1, msgs, sks[0])
+	_, err = b.Post(forged.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no one else can post to the board
+	if _, err = r.Post(forged.Marshal()); err == nil {
+		t.Fatalf("Remote post accepted")
+	}
+	if _, err = board.NewBulletinBoardClient(cc).Post(context.Background(), &board.Entry{Data: forged.Marshal()}); err == nil {
+		t.Fatalf("Post accepted over the network")
+	}
+
+	// the first member to post in the next round posts different messages
+	posts = []*FinalMessages{
+		NewFinalMessages(2, 0, 0, msgs[:1], sks[0]),
+		NewFinalMessages(2, 0, 1, msgs, sks[1]),
+		NewFinalMessages(2, 0, 2, msgs, sks[2]),
+	}
+	for _, f := range posts {
+		_, err = b.Post(f.Marshal())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bad, ok := Audit(r, vks)
+	if !ok {
+		t.Fatalf("Chain did not verify")
+	}
+	if len(bad) != 3 || bad[0] != 2 || bad[1] != 3 || bad[2] != 4 {
+		t.Fatalf("Audit found %v", bad)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := r.Find(DataHash(posts[1].Marshal()))
+	if err != nil || index != 5 || !VerifyInclusion(r, index, posts[1].Marshal(), head) {
+		t.Fatalf("Posting not found on the remote board")
+	}
+	f := &FinalMessages{}
+	e, err := r.Get(1)
+	if err != nil || f.InterpretFrom(e.Data) != nil || f.Poster != 1 || len(f.Messages) != 3 {
+		t.Fatalf("Final messages not read back")
+	}
+}
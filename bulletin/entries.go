@@ -0,0 +1,228 @@
+package bulletin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	board "github.com/simonlangowski/lightning1/bulletin/messages"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+)
+
+// the first byte of an entry is its type
+const (
+	SUBMISSION     byte = 1
+	FINAL_MESSAGES byte = 2
+)
+
+// The messages released by an anytrust group at the end of a lightning round
+// Signed by the group member that posts them
+type FinalMessages struct {
+	Round     int
+	Group     int
+	Poster    int
+	Messages  [][]byte
+	Signature crypto.Signature
+}
+
+// The signed data and signature of a client submission
+func PackSubmission(m *messages.SignedMessage) []byte {
+	signed := m.GetSignedData()
+	b := make([]byte, len(signed)+crypto.SIGNATURE_SIZE)
+	copy(b, signed)
+	copy(b[len(signed):], m.Signature)
+	return b
+}
+
+// returns nil if the data is too short to contain a submission
+func ParseSubmission(b []byte) *messages.SignedMessage {
+	if len(b) < messages.Metadata_size+crypto.SIGNATURE_SIZE {
+		return nil
+	}
+	split := len(b) - crypto.SIGNATURE_SIZE
+	return messages.ParseSignedMessage(&messages.NetworkMessage{
+		Data:      b[:split],
+		Signature: b[split:],
+	})
+}
+
+func SubmissionEntry(m *messages.SignedMessage) []byte {
+	return append([]byte{SUBMISSION}, PackSubmission(m)...)
+}
+
+func NewFinalMessages(round, group, poster int, msgs [][]byte, key crypto.SigningKey) *FinalMessages {
+	f := &FinalMessages{
+		Round:    round,
+		Group:    group,
+		Poster:   poster,
+		Messages: msgs,
+	}
+	f.Signature = crypto.Sign(key, f.signedData())
+	return f
+}
+
+func (f *FinalMessages) Verify(vk crypto.VerificationKey) bool {
+	return crypto.Verify(vk, f.signedData(), f.Signature)
+}
+
+func (f *FinalMessages) signedData() []byte {
+	b := make([]byte, f.Len())
+	f.PackTo(b)
+	return b[:len(b)-crypto.SIGNATURE_SIZE]
+}
+
+func (f *FinalMessages) Len() int {
+	l := 1 + 4*4 + crypto.SIGNATURE_SIZE
+	for _, m := range f.Messages {
+		l += 4 + len(m)
+	}
+	return l
+}
+
+// type, round, group, poster, count, length prefixed messages, signature
+func (f *FinalMessages) PackTo(b []byte) {
+	if len(b) != f.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	b[0] = FINAL_MESSAGES
+	binary.LittleEndian.PutUint32(b[1:5], uint32(f.Round))
+	binary.LittleEndian.PutUint32(b[5:9], uint32(f.Group))
+	binary.LittleEndian.PutUint32(b[9:13], uint32(f.Poster))
+	binary.LittleEndian.PutUint32(b[13:17], uint32(len(f.Messages)))
+	pos := 17
+	for _, m := range f.Messages {
+		binary.LittleEndian.PutUint32(b[pos:pos+4], uint32(len(m)))
+		pos += 4
+		pos += copy(b[pos:], m)
+	}
+	copy(b[pos:], f.Signature)
+}
+
+func (f *FinalMessages) InterpretFrom(b []byte) error {
+	if len(b) < 17+crypto.SIGNATURE_SIZE || b[0] != FINAL_MESSAGES {
+		return errors.Rejected("length")
+	}
+	f.Round = int(binary.LittleEndian.Uint32(b[1:5]))
+	f.Group = int(binary.LittleEndian.Uint32(b[5:9]))
+	f.Poster = int(binary.LittleEndian.Uint32(b[9:13]))
+	count := int(binary.LittleEndian.Uint32(b[13:17]))
+	end := len(b) - crypto.SIGNATURE_SIZE
+	pos := 17
+	f.Messages = make([][]byte, 0)
+	for i := 0; i < count; i++ {
+		if pos+4 > end {
+			return errors.Rejected("length")
+		}
+		l := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+		pos += 4
+		if pos+l > end {
+			return errors.Rejected("length")
+		}
+		f.Messages = append(f.Messages, b[pos:pos+l])
+		pos += l
+	}
+	if pos != end {
+		return errors.Rejected("length")
+	}
+	f.Signature = b[end:]
+	return nil
+}
+
+func (f *FinalMessages) Marshal() []byte {
+	b := make([]byte, f.Len())
+	f.PackTo(b)
+	return b
+}
+
+// Find the entry holding the submission, at index from or later
+// returns -1 if it was not posted, the caller checks the entry with VerifyInclusion
+func FindSubmission(b BulletinBoard, from int64, m *messages.SignedMessage) int64 {
+	index, err := b.Find(DataHash(SubmissionEntry(m)))
+	if err != nil || index < from {
+		return -1
+	}
+	return index
+}
+
+// Check the chain, the signature on each posting of final messages,
+// and that the members of each group posted the same messages
+// The messages a majority of the members that posted agree on are taken as correct, whoever posted first,
+// and when there is no majority every posting of the group's round is reported
+// Returns the indexes of the entries that failed, or false if the chain is broken
+func Audit(b BulletinBoard, vks []crypto.VerificationKey) ([]int64, bool) {
+	head, ok := VerifyChain(b)
+	if !ok {
+		return nil, false
+	}
+	type roundGroup struct{ round, group int }
+	type posting struct {
+		index  int64
+		poster int
+		msgs   [][]byte
+	}
+	posted := make(map[roundGroup][]posting)
+	bad := make([]int64, 0)
+	err := walk(b, 0, head.Length, func(e *board.Entry) bool {
+		if len(e.Data) == 0 || e.Data[0] != FINAL_MESSAGES {
+			return true
+		}
+		f := &FinalMessages{}
+		if f.InterpretFrom(e.Data) != nil || f.Poster < 0 || f.Poster >= len(vks) || !f.Verify(vks[f.Poster]) {
+			bad = append(bad, e.Index)
+			return true
+		}
+		// the order messages arrive in can differ between members
+		msgs := append([][]byte{}, f.Messages...)
+		sort.Slice(msgs, func(i, j int) bool { return bytes.Compare(msgs[i], msgs[j]) < 0 })
+		k := roundGroup{f.Round, f.Group}
+		posted[k] = append(posted[k], posting{e.Index, f.Poster, msgs})
+		return true
+	})
+	if err != nil {
+		return nil, false
+	}
+	for _, postings := range posted {
+		// each member votes with its first posting
+		votes := make([]int, len(postings))
+		voted := make(map[int]bool)
+		for _, p := range postings {
+			if voted[p.poster] {
+				continue
+			}
+			voted[p.poster] = true
+			for j := range postings {
+				if equalMessages(postings[j].msgs, p.msgs) {
+					votes[j]++
+				}
+			}
+		}
+		majority := -1
+		for i := range postings {
+			if 2*votes[i] > len(voted) {
+				majority = i
+				break
+			}
+		}
+		for _, p := range postings {
+			if majority < 0 || !equalMessages(p.msgs, postings[majority].msgs) {
+				bad = append(bad, p.index)
+			}
+		}
+	}
+	sort.Slice(bad, func(i, j int) bool { return bad[i] < bad[j] })
+	return bad, true
+}
+
+func equalMessages(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,211 @@
+package bulletin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"sync"
+
+	board "github.com/simonlangowski/lightning1/bulletin/messages"
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+// A bulletin board stored in an append-only file
+// Each record is the length of the data with its checksum, the data, and the entry hash
+// a length that does not match its checksum is corruption, rather than a record that was only partly written
+type FileBoard struct {
+	f       *os.File
+	offsets []int64 // start of each record
+	hashes  [][]byte
+	byData  map[[HASH_SIZE]byte]int64 // index of the first entry with data of the hash
+	size    int64
+	mu      sync.RWMutex
+}
+
+// Open the board in fn, checking the chain of any existing entries
+func NewFileBoard(fn string) (*FileBoard, error) {
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	b := &FileBoard{
+		f:       f,
+		offsets: make([]int64, 0),
+		hashes:  make([][]byte, 0),
+		byData:  make(map[[HASH_SIZE]byte]int64),
+	}
+	err = b.load()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+const recordHeaderSize = 8
+
+func (b *FileBoard) load() error {
+	info, err := b.f.Stat()
+	if err != nil {
+		return err
+	}
+	prev := genesis
+	header := make([]byte, recordHeaderSize)
+	for b.size < info.Size() {
+		_, err = b.f.ReadAt(header, b.size)
+		if err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(header[:4]) != binary.LittleEndian.Uint32(header[4:]) {
+			return errors.ChainInvalid()
+		}
+		l := int64(binary.LittleEndian.Uint32(header[:4]))
+		if b.size+recordHeaderSize+l+HASH_SIZE > info.Size() {
+			break
+		}
+		record := make([]byte, l+HASH_SIZE)
+		_, err = b.f.ReadAt(record, b.size+recordHeaderSize)
+		if err != nil {
+			return err
+		}
+		index := int64(len(b.offsets))
+		hash := record[l:]
+		if !bytes.Equal(hash, EntryHash(index, prev, record[:l])) {
+			return errors.ChainInvalid()
+		}
+		b.offsets = append(b.offsets, b.size)
+		b.hashes = append(b.hashes, hash)
+		b.index(index, record[:l])
+		b.size += recordHeaderSize + l + HASH_SIZE
+		prev = hash
+	}
+	if b.size < info.Size() {
+		// remove the last record, which was only partly written
+		return b.f.Truncate(b.size)
+	}
+	return nil
+}
+
+func (b *FileBoard) Post(data []byte) (*board.Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	index := int64(len(b.offsets))
+	e := NewEntry(index, b.headHash(), data)
+	record := make([]byte, recordHeaderSize+len(data)+HASH_SIZE)
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(record[0:4]))
+	copy(record[recordHeaderSize:], data)
+	copy(record[recordHeaderSize+len(data):], e.Hash)
+	_, err := b.f.WriteAt(record, b.size)
+	if err != nil {
+		return nil, err
+	}
+	// entries are final once posted
+	err = b.f.Sync()
+	if err != nil {
+		return nil, err
+	}
+	b.offsets = append(b.offsets, b.size)
+	b.hashes = append(b.hashes, e.Hash)
+	b.index(index, data)
+	b.size += int64(len(record))
+	return e, nil
+}
+
+func (b *FileBoard) index(index int64, data []byte) {
+	h := sha256.Sum256(data)
+	if _, exists := b.byData[h]; !exists {
+		b.byData[h] = index
+	}
+}
+
+func (b *FileBoard) Get(index int64) (*board.Entry, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.get(index)
+}
+
+func (b *FileBoard) get(index int64) (*board.Entry, error) {
+	if index < 0 || index >= int64(len(b.offsets)) {
+		return nil, errors.EntryNotFound(int(index))
+	}
+	length := make([]byte, 4)
+	_, err := b.f.ReadAt(length, b.offsets[index])
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint32(length))
+	_, err = b.f.ReadAt(data, b.offsets[index]+recordHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	prev := genesis
+	if index > 0 {
+		prev = b.hashes[index-1]
+	}
+	return &board.Entry{
+		Index: index,
+		Prev:  prev,
+		Data:  data,
+		Hash:  b.hashes[index],
+	}, nil
+}
+
+func (b *FileBoard) Find(dataHash []byte) (int64, error) {
+	h := [HASH_SIZE]byte{}
+	copy(h[:], dataHash)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	index, exists := b.byData[h]
+	if !exists {
+		return -1, nil
+	}
+	return index, nil
+}
+
+func (b *FileBoard) GetRange(from, to int64) ([]*board.Entry, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if from < 0 || from >= int64(len(b.offsets)) || to < from {
+		return nil, errors.EntryNotFound(int(from))
+	}
+	if to > int64(len(b.offsets)) {
+		to = int64(len(b.offsets))
+	}
+	if to > from+MAX_RANGE {
+		to = from + MAX_RANGE
+	}
+	entries := make([]*board.Entry, 0, to-from)
+	for i := from; i < to; i++ {
+		e, err := b.get(i)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (b *FileBoard) Head() (*board.BoardHead, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return &board.BoardHead{
+		Length: int64(len(b.offsets)),
+		Hash:   b.headHash(),
+	}, nil
+}
+
+func (b *FileBoard) headHash() []byte {
+	if len(b.hashes) == 0 {
+		return genesis
+	}
+	return b.hashes[len(b.hashes)-1]
+}
+
+func (b *FileBoard) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.f.Close()
+}
@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package board
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// BulletinBoardClient is the client API for BulletinBoard service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BulletinBoardClient interface {
+	// Append data and return the new entry
+	Post(ctx context.Context, in *Entry, opts ...grpc.CallOption) (*Entry, error)
+	// Get the entry at an index
+	Get(ctx context.Context, in *EntryRequest, opts ...grpc.CallOption) (*Entry, error)
+	// Get the length and hash of the last entry
+	Head(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BoardHead, error)
+	// Get the index of the first entry with the data, or -1
+	Find(ctx context.Context, in *DataHash, opts ...grpc.CallOption) (*EntryRequest, error)
+	// Get consecutive entries, at most MAX_RANGE at a time
+	GetRange(ctx context.Context, in *EntryRange, opts ...grpc.CallOption) (*Entries, error)
+}
+
+type bulletinBoardClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBulletinBoardClient(cc grpc.ClientConnInterface) BulletinBoardClient {
+	return &bulletinBoardClient{cc}
+}
+
+func (c *bulletinBoardClient) Post(ctx context.Context, in *Entry, opts ...grpc.CallOption) (*Entry, error) {
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, "/board.BulletinBoard/Post", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bulletinBoardClient) Get(ctx context.Context, in *EntryRequest, opts ...grpc.CallOption) (*Entry, error) {
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, "/board.BulletinBoard/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bulletinBoardClient) Head(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BoardHead, error) {
+	out := new(BoardHead)
+	err := c.cc.Invoke(ctx, "/board.BulletinBoard/Head", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bulletinBoardClient) Find(ctx context.Context, in *DataHash, opts ...grpc.CallOption) (*EntryRequest, error) {
+	out := new(EntryRequest)
+	err := c.cc.Invoke(ctx, "/board.BulletinBoard/Find", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bulletinBoardClient) GetRange(ctx context.Context, in *EntryRange, opts ...grpc.CallOption) (*Entries, error) {
+	out := new(Entries)
+	err := c.cc.Invoke(ctx, "/board.BulletinBoard/GetRange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BulletinBoardServer is the server API for BulletinBoard service.
+// All implementations must embed UnimplementedBulletinBoardServer
+// for forward compatibility
+type BulletinBoardServer interface {
+	// Append data and return the new entry
+	Post(context.Context, *Entry) (*Entry, error)
+	// Get the entry at an index
+	Get(context.Context, *EntryRequest) (*Entry, error)
+	// Get the length and hash of the last entry
+	Head(context.Context, *Empty) (*BoardHead, error)
+	// Get the index of the first entry with the data, or -1
+	Find(context.Context, *DataHash) (*EntryRequest, error)
+	// Get consecutive entries, at most MAX_RANGE at a time
+	GetRange(context.Context, *EntryRange) (*Entries, error)
+	mustEmbedUnimplementedBulletinBoardServer()
+}
+
+// UnimplementedBulletinBoardServer must be embedded to have forward compatible implementations.
+type UnimplementedBulletinBoardServer struct {
+}
+
+func (UnimplementedBulletinBoardServer) Post(context.Context, *Entry) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Post not implemented")
+}
+func (UnimplementedBulletinBoardServer) Get(context.Context, *EntryRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedBulletinBoardServer) Head(context.Context, *Empty) (*BoardHead, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Head not implemented")
+}
+func (UnimplementedBulletinBoardServer) Find(context.Context, *DataHash) (*EntryRequest, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Find not implemented")
+}
+func (UnimplementedBulletinBoardServer) GetRange(context.Context, *EntryRange) (*Entries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRange not implemented")
+}
+func (UnimplementedBulletinBoardServer) mustEmbedUnimplementedBulletinBoardServer() {}
+
+// UnsafeBulletinBoardServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BulletinBoardServer will
+// result in compilation errors.
+type UnsafeBulletinBoardServer interface {
+	mustEmbedUnimplementedBulletinBoardServer()
+}
+
+func RegisterBulletinBoardServer(s grpc.ServiceRegistrar, srv BulletinBoardServer) {
+	s.RegisterService(&BulletinBoard_ServiceDesc, srv)
+}
+
+func _BulletinBoard_Post_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Entry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BulletinBoardServer).Post(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/board.BulletinBoard/Post",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BulletinBoardServer).Post(ctx, req.(*Entry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BulletinBoard_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BulletinBoardServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/board.BulletinBoard/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BulletinBoardServer).Get(ctx, req.(*EntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BulletinBoard_Head_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BulletinBoardServer).Head(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/board.BulletinBoard/Head",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BulletinBoardServer).Head(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BulletinBoard_Find_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataHash)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BulletinBoardServer).Find(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/board.BulletinBoard/Find",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BulletinBoardServer).Find(ctx, req.(*DataHash))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BulletinBoard_GetRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntryRange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BulletinBoardServer).GetRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/board.BulletinBoard/GetRange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BulletinBoardServer).GetRange(ctx, req.(*EntryRange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BulletinBoard_ServiceDesc is the grpc.ServiceDesc for BulletinBoard service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BulletinBoard_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "board.BulletinBoard",
+	HandlerType: (*BulletinBoardServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Post",
+			Handler:    _BulletinBoard_Post_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _BulletinBoard_Get_Handler,
+		},
+		{
+			MethodName: "Head",
+			Handler:    _BulletinBoard_Head_Handler,
+		},
+		{
+			MethodName: "Find",
+			Handler:    _BulletinBoard_Find_Handler,
+		},
+		{
+			MethodName: "GetRange",
+			Handler:    _BulletinBoard_GetRange_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bulletin.proto",
+}
@@ -0,0 +1 @@
+protoc -I=. --go_out=. --go-grpc_out=. bulletin.proto
@@ -0,0 +1,106 @@
+package bulletin
+
+import (
+	"context"
+
+	board "github.com/simonlangowski/lightning1/bulletin/messages"
+	"github.com/simonlangowski/lightning1/errors"
+	"google.golang.org/grpc"
+)
+
+// Serves a bulletin board over grpc, for reading only
+// entries are posted by the members of the board's group, from the submissions they receive
+type Server struct {
+	board.UnimplementedBulletinBoardServer
+	b BulletinBoard
+}
+
+func NewServer(b BulletinBoard) *Server {
+	return &Server{b: b}
+}
+
+// add the board to a grpc server, such as the one returned by network.StartServer
+func (s *Server) Register(g *grpc.Server) {
+	board.RegisterBulletinBoardServer(g, s)
+}
+
+func (s *Server) Get(_ context.Context, r *board.EntryRequest) (*board.Entry, error) {
+	return s.b.Get(r.Index)
+}
+
+func (s *Server) Head(_ context.Context, _ *board.Empty) (*board.BoardHead, error) {
+	return s.b.Head()
+}
+
+func (s *Server) Find(_ context.Context, h *board.DataHash) (*board.EntryRequest, error) {
+	index, err := s.b.Find(h.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return &board.EntryRequest{Index: index}, nil
+}
+
+func (s *Server) GetRange(_ context.Context, r *board.EntryRange) (*board.Entries, error) {
+	entries, err := s.b.GetRange(r.From, r.To)
+	if err != nil {
+		return nil, err
+	}
+	return &board.Entries{Entries: entries}, nil
+}
+
+// A bulletin board held by another party
+// Entries are checked against their hashes, but inclusion
+// should be checked against a trusted head with VerifyInclusion
+type RemoteBoard struct {
+	client board.BulletinBoardClient
+}
+
+func NewRemoteBoard(cc grpc.ClientConnInterface) *RemoteBoard {
+	return &RemoteBoard{client: board.NewBulletinBoardClient(cc)}
+}
+
+// the board only takes posts from the members of its group
+func (r *RemoteBoard) Post(data []byte) (*board.Entry, error) {
+	return nil, errors.Rejected("remote post")
+}
+
+func (r *RemoteBoard) Get(index int64) (*board.Entry, error) {
+	e, err := r.client.Get(context.Background(), &board.EntryRequest{Index: index})
+	if err != nil {
+		return nil, errors.NetworkError(err)
+	}
+	if !VerifyEntry(e, index, e.Prev) {
+		return nil, errors.ChainInvalid()
+	}
+	return e, nil
+}
+
+func (r *RemoteBoard) Head() (*board.BoardHead, error) {
+	h, err := r.client.Head(context.Background(), &board.Empty{})
+	if err != nil {
+		return nil, errors.NetworkError(err)
+	}
+	return h, nil
+}
+
+func (r *RemoteBoard) Find(dataHash []byte) (int64, error) {
+	i, err := r.client.Find(context.Background(), &board.DataHash{Hash: dataHash})
+	if err != nil {
+		return -1, errors.NetworkError(err)
+	}
+	return i.Index, nil
+}
+
+// the entries are checked against their hashes, and their chain by the caller
+func (r *RemoteBoard) GetRange(from, to int64) ([]*board.Entry, error) {
+	entries, err := r.client.GetRange(context.Background(), &board.EntryRange{From: from, To: to})
+	if err != nil {
+		return nil, errors.NetworkError(err)
+	}
+	for i, e := range entries.Entries {
+		if !VerifyEntry(e, from+int64(i), e.Prev) {
+			return nil, errors.ChainInvalid()
+		}
+	}
+	return entries.Entries, nil
+}
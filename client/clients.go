@@ -55,16 +55,25 @@ func (c *ClientRunner) Connect() error {
 }
 
 func (c *ClientRunner) KeySet(_ context.Context, m *coord.KeyInformation) (*coord.KeyInformation, error) {
-	c.C.CombinedKey = &token.TokenPublicKey{}
-	err := c.C.CombinedKey.InterpretFrom(m.TokenPublicKey)
+	err := setKeys(c.C, m)
 	if err != nil {
 		return nil, err
 	}
-	err = c.C.GroupPublicKey.InterpretFrom(m.GroupKey)
+	return &coord.KeyInformation{}, nil
+}
+
+// the public keys of the servers and anytrust groups agreed on in the key exchange
+func setKeys(st *common.CommonState, m *coord.KeyInformation) error {
+	st.CombinedKey = &token.TokenPublicKey{}
+	err := st.CombinedKey.InterpretFrom(m.TokenPublicKey)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &coord.KeyInformation{}, nil
+	err = st.GroupPublicKey.InterpretFrom(m.GroupKey)
+	if err != nil {
+		return err
+	}
+	return st.SetPublicGroupKeys(m.TokenShareKeys)
 }
 
 func (c *ClientRunner) AddClient(id int64) error {
@@ -86,6 +95,7 @@ func (c *ClientRunner) ClientStart(_ context.Context, i *coord.RoundInfo) (*coor
 	c.C.NumLayers = int(i.NumLayers)
 	c.C.Round = int(i.Round)
 	c.C.BoomerangLimit = int(i.BoomerangLimit)
+	c.C.DropServers(i.DroppedServers)
 	for id := i.StartId; id < i.EndId; id++ {
 		if c.Clients[id] == nil {
 			c.AddClient(id)
@@ -100,9 +110,14 @@ func (c *ClientRunner) ClientStart(_ context.Context, i *coord.RoundInfo) (*coor
 			go func(id int64) {
 				c.Acquire()
 				defer c.Release()
+				if !i.PathEstablishment && i.SlotsPerUser > 0 {
+					done <- c.sendFragmentedMessage(i, id)
+					return
+				}
 				cli := c.Clients[id]
 				cli.Common.Round = int(i.Round)
 				cli.Common.NumLayers = int(i.NumLayers)
+				cli.Common.CopyDropped(c.C)
 				if i.PathEstablishment {
 					err := cli.RegisterClient(c.Caller)
 					if err != nil {
@@ -125,7 +140,13 @@ func (c *ClientRunner) ClientStart(_ context.Context, i *coord.RoundInfo) (*coor
 					}
 					m := make([]byte, i.MessageSize)
 					binary.LittleEndian.PutUint64(m, uint64(id))
-					done <- cli.SendLightningMessage(c.Caller, cli.PathKeys, m)
+					if len(cli.PathKeys) == 0 {
+						// the path was revoked after a complaint, so the client waits for the next path establishment
+						log.Printf("Client %d: no path to send round %d", id, i.Round)
+						done <- nil
+					} else {
+						done <- pathRevoked(id, i.Round, cli.SendLightningMessage(c.Caller, cli.PathKeys, m))
+					}
 				}
 			}(id)
 		}
@@ -145,6 +166,46 @@ func (c *ClientRunner) ClientStart(_ context.Context, i *coord.RoundInfo) (*coor
 	return &coord.Empty{}, nil
 }
 
+// Consecutive clients are the slots of one user, and the first sends the user's message across all of them
+func (c *ClientRunner) sendFragmentedMessage(i *coord.RoundInfo, id int64) error {
+	if (id-i.StartId)%i.SlotsPerUser != 0 {
+		return nil
+	}
+	slots := make([]*prepareMessages.Client, 0, i.SlotsPerUser)
+	for sid := id; sid < id+i.SlotsPerUser && sid < i.EndId; sid++ {
+		cli := c.Clients[sid]
+		cli.Common.Round = int(i.Round)
+		cli.Common.NumLayers = int(i.NumLayers)
+		cli.Common.CopyDropped(c.C)
+		if i.SkipPathGen {
+			cli.SkipPathGen(c.Caller, i)
+		}
+		if len(cli.PathKeys) == 0 {
+			log.Printf("Client %d: no path to send round %d", sid, i.Round)
+			return nil
+		}
+		slots = append(slots, cli)
+	}
+	// the user number, with a length that varies between users
+	user := id / i.SlotsPerUser
+	capacity := prepareMessages.FragmentCapacity(len(slots), int(i.MessageSize))
+	if capacity < 8 {
+		return errors.LengthInvalidError()
+	}
+	m := make([]byte, 8+int(user)%(capacity-7))
+	binary.LittleEndian.PutUint64(m, uint64(user))
+	return pathRevoked(id, i.Round, prepareMessages.SendFragmentedMessage(c.Caller, slots, m, int(i.MessageSize)))
+}
+
+// a path revoked after an overflow does not fail the round, the client waits for the next path establishment
+func pathRevoked(id, round int64, err error) error {
+	if _, ok := err.(*errors.PathRevokedError); ok {
+		log.Printf("Client %d: path revoked in round %d", id, round)
+		return nil
+	}
+	return err
+}
+
 func (c *ClientRunner) CheckReceipt(_ context.Context, i *coord.RoundInfo) (*coord.Empty, error) {
 	done := make(chan error)
 	go func() {
@@ -154,11 +215,19 @@ func (c *ClientRunner) CheckReceipt(_ context.Context, i *coord.RoundInfo) (*coo
 				cli.Common.Round = int(i.Round)
 				cli.Common.NumLayers = int(i.NumLayers)
 				if i.PathEstablishment {
-					// for receiptLayer > 0 the receipt is not retrieved
+					var err error
 					if i.ReceiptLayer > 0 {
-						panic("Retrieving receipt breaks anonymity")
+						// the server holding the receipt must not learn it is on the client's path
+						err = cli.CheckPrivateReceipt(c.Caller, int(i.Round))
+					} else {
+						err = cli.CheckReceipt(c.Caller, int(i.Round))
+					}
+					if err != nil {
+						// have the group repair the path, the round still failed for this client
+						if cerr := cli.Complain(c.Caller, int(i.Round)); cerr != nil {
+							log.Printf("Client %d: complaint about round %d: %v", id, i.Round, cerr)
+						}
 					}
-					err := cli.CheckReceipt(c.Caller, int(i.Round))
 					done <- err
 				}
 			}(id)
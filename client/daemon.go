@@ -0,0 +1,407 @@
+package client
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/simonlangowski/lightning1/bulletin"
+	api "github.com/simonlangowski/lightning1/client/messages"
+	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/prepareMessages"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+/*
+A long running client for a single user (the ClientRunner instead simulates many clients)
+
+- It registers and establishes its paths when the coordinator starts a path establishment round,
+  and checks its receipts after each of the following path establishment rounds
+- The path keys are kept in a state file encrypted under a passphrase, so the paths survive a restart
+- It sends exactly one message every lightning round: the next queued message, or an empty one
+- The application queues messages and fetches the broadcast output through a local api on a unix socket
+- Lightning rounds are started by the coordinator, or follow the schedule of the servers (see RunSchedule)
+*/
+
+type Daemon struct {
+	coord.UnimplementedCoordinatorHandlerServer
+	api.UnimplementedClientDaemonServer
+	C          *common.CommonState
+	Caller     *network.Caller
+	Client     *prepareMessages.Client
+	stateFile  string
+	passphrase []byte
+	lastRound  int // the last round the client took part in
+
+	mu          sync.Mutex
+	queue       [][]byte
+	nextRound   int
+	messageSize int
+	// results of the receipt checks of the current paths, by round
+	receipts     map[int]error
+	receiptsDone *sync.Cond
+
+	boardLock sync.Mutex
+	boards    []bulletin.BulletinBoard
+	scanned   []int64
+	// final messages found on the boards, by round and group
+	outputs map[int]map[int][][]byte
+}
+
+// Load the client from the state file, or create a new one if there is none
+func NewDaemon(servers map[int64]*config.Server, groups map[int64]*config.Group, id int64, stateFile string, passphrase []byte) (*Daemon, error) {
+	c := common.NewCommonState(servers, 0, &config.Groups{Groups: groups})
+	c.MyId = int(id)
+	d := &Daemon{
+		C:          c,
+		stateFile:  stateFile,
+		passphrase: passphrase,
+		lastRound:  -1,
+		receipts:   make(map[int]error),
+		outputs:    make(map[int]map[int][][]byte),
+	}
+	d.receiptsDone = sync.NewCond(&d.mu)
+	state, err := prepareMessages.ReadClientState(stateFile, passphrase)
+	if os.IsNotExist(err) {
+		d.Client, err = prepareMessages.NewClient(c, id, int(id)%c.NumGroups)
+		return d, err
+	} else if err != nil {
+		return nil, err
+	}
+	if state.ID != id {
+		return nil, errors.WrongState(id, state.ID)
+	}
+	d.Client, err = state.Client(c)
+	if err != nil {
+		return nil, err
+	}
+	d.lastRound = state.Round
+	return d, nil
+}
+
+func (d *Daemon) Connect() error {
+	var err error
+	d.Caller, err = network.NewCaller(d.C.Configs)
+	if err != nil {
+		return err
+	}
+	d.Caller.SetGroups(d.C.GroupConfigs.Groups)
+	conn, err := network.GetConnections(d.C.Configs)
+	if err != nil {
+		return err
+	}
+	boards := make([]bulletin.BulletinBoard, len(conn))
+	for id, cc := range conn {
+		boards[id] = bulletin.NewRemoteBoard(cc)
+	}
+	d.SetBoards(boards)
+	return nil
+}
+
+// The boards the broadcast output is fetched from
+// each group posts to the boards of its members
+func (d *Daemon) SetBoards(boards []bulletin.BulletinBoard) {
+	d.boardLock.Lock()
+	defer d.boardLock.Unlock()
+	d.boards = boards
+	d.scanned = make([]int64, len(boards))
+}
+
+// Serve the local api on a unix socket until the listener is closed
+func (d *Daemon) Serve(socket string) (*grpc.Server, net.Listener, error) {
+	// a socket left by a previous run
+	os.Remove(socket)
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := grpc.NewServer()
+	api.RegisterClientDaemonServer(g, d)
+	go func() {
+		err := g.Serve(lis)
+		if err != nil && err != grpc.ErrServerStopped {
+			log.Printf("Client api stopped: %v", err)
+		}
+	}()
+	return g, lis, nil
+}
+
+// the state is saved after every round, so a restarted daemon does not send twice in a round
+func (d *Daemon) save() error {
+	d.lastRound = d.C.Round
+	return prepareMessages.WriteClientState(d.stateFile, d.passphrase, d.Client.State())
+}
+
+func (d *Daemon) KeySet(_ context.Context, m *coord.KeyInformation) (*coord.KeyInformation, error) {
+	err := setKeys(d.C, m)
+	if err != nil {
+		return nil, err
+	}
+	return &coord.KeyInformation{}, nil
+}
+
+// The coordinator starts rounds for a range of clients, and the daemon only takes part as its own client
+func (d *Daemon) ClientStart(_ context.Context, i *coord.RoundInfo) (*coord.Empty, error) {
+	if d.Client.ID < i.StartId || d.Client.ID >= i.EndId {
+		return &coord.Empty{}, nil
+	}
+	if i.PathEstablishment {
+		return &coord.Empty{}, d.establishPaths(i)
+	}
+	return &coord.Empty{}, d.sendRound(i)
+}
+
+func (d *Daemon) establishPaths(i *coord.RoundInfo) error {
+	d.C.NumLayers = int(i.NumLayers)
+	d.C.Round = int(i.Round)
+	d.C.BoomerangLimit = int(i.BoomerangLimit)
+	d.C.DropServers(i.DroppedServers)
+	cli := d.Client
+	// the keys may have been set after the client was loaded
+	cli.CombinedKey = d.C.CombinedKey
+	cli.GroupPublicKey = d.C.GroupPublicKey
+	err := cli.RegisterClient(d.Caller)
+	if err != nil {
+		return err
+	}
+	message, _, err := cli.MakeOptimizedPathEstablishmentMessage(d.Caller, d.C.NumLayers, d.C.BoomerangLimit)
+	if err != nil {
+		return err
+	}
+	err = cli.SubmitPathEstablishmentMessage(d.Caller, message)
+	if err != nil {
+		return err
+	}
+	err = d.save()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.receipts = make(map[int]error)
+	d.mu.Unlock()
+	go d.checkReceipts(int(i.NumLayers), int(i.BoomerangLimit))
+	return nil
+}
+
+// Check the receipt of each path establishment round as soon as it completes
+// only the receipts of rounds up to the boomerang limit come back to the first server of the path,
+// the later receipts are fetched in their buckets from every server
+func (d *Daemon) checkReceipts(numLayers, boomerangLimit int) {
+	// a copy, so that the rounds of the check do not change the state used to send
+	st := &common.CommonState{}
+	*st = *d.C
+	checker := *d.Client
+	checker.Common = st
+	for round := 1; round < numLayers; round++ {
+		st.Round = round
+		// the servers answer once the round has completed
+		var err error
+		if round <= boomerangLimit {
+			err = checker.CheckReceipt(d.Caller, round)
+		} else {
+			err = checker.CheckPrivateReceipt(d.Caller, round)
+		}
+		if err != nil {
+			log.Printf("Client %d: receipt of round %d: %v", d.Client.ID, round, err)
+			// the group repairs the path before any lightning round is sent over it
+			if cerr := checker.Complain(d.Caller, round); cerr != nil {
+				log.Printf("Client %d: complaint about round %d: %v", d.Client.ID, round, cerr)
+			} else {
+				d.mu.Lock()
+				d.Client.PathKeys = nil
+				d.mu.Unlock()
+			}
+		}
+		d.mu.Lock()
+		d.receipts[round] = err
+		d.receiptsDone.Broadcast()
+		d.mu.Unlock()
+	}
+}
+
+// The result of the daemon's own receipt check of the round
+func (d *Daemon) CheckReceipt(_ context.Context, i *coord.RoundInfo) (*coord.Empty, error) {
+	if d.Client.ID < i.StartId || d.Client.ID >= i.EndId || !i.PathEstablishment {
+		return &coord.Empty{}, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for {
+		err, checked := d.receipts[int(i.Round)]
+		if checked {
+			return &coord.Empty{}, err
+		}
+		d.receiptsDone.Wait()
+	}
+}
+
+// Send the next queued message, or an empty message so that the client takes part in every round
+func (d *Daemon) sendRound(i *coord.RoundInfo) error {
+	if len(d.Client.PathKeys) == 0 {
+		log.Printf("Client %d: no paths to send round %d", d.Client.ID, i.Round)
+		return nil
+	}
+	if int(i.Round) <= d.lastRound {
+		log.Printf("Client %d: already took part in round %d", d.Client.ID, i.Round)
+		return nil
+	}
+	d.C.NumLayers = int(i.NumLayers)
+	d.C.Round = int(i.Round)
+	d.C.DropServers(i.DroppedServers)
+	message := d.next(int(i.Round), int(i.MessageSize))
+	err := d.Client.SendLightningMessage(d.Caller, d.Client.PathKeys, message)
+	if _, ok := err.(*errors.PathRevokedError); ok {
+		// the message was not delivered, so it is sent again once the daemon has a new path
+		log.Printf("Client %d: path revoked in round %d", d.Client.ID, i.Round)
+		d.requeue(message)
+		return d.save()
+	} else if err != nil {
+		return err
+	}
+	return d.save()
+}
+
+// the round the next queued message is sent in
+func (d *Daemon) setNextRound(round, messageSize int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextRound = round
+	d.messageSize = messageSize
+}
+
+func (d *Daemon) next(round, messageSize int) []byte {
+	d.setNextRound(round+1, messageSize)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	message := make([]byte, messageSize)
+	for len(d.queue) > 0 {
+		m := d.queue[0]
+		d.queue = d.queue[1:]
+		if len(m) <= messageSize {
+			copy(message, m)
+			break
+		}
+		// the message size of the rounds changed after the message was queued
+		log.Printf("Client %d: dropped a message of %d bytes in round %d", d.Client.ID, len(m), round)
+	}
+	return message
+}
+
+func (d *Daemon) requeue(message []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queue = append([][]byte{message}, d.queue...)
+}
+
+// Send the daemon's message in every round of the schedule the servers run (see Server.RunSchedule)
+// A message that is not accepted is logged, and the daemon continues with the next round
+func (d *Daemon) RunSchedule(schedule *coord.Schedule) error {
+	round := config.NextOpenRound(schedule, time.Now())
+	for n := int64(0); schedule.NumRounds == 0 || n < schedule.NumRounds; n++ {
+		d.setNextRound(round, int(schedule.Round.MessageSize))
+		// a submission sent before the servers set up the round waits for it
+		time.Sleep(time.Until(config.RoundStartTime(schedule, round)))
+		info := proto.Clone(schedule.Round).(*coord.RoundInfo)
+		info.Round = int64(round)
+		info.PathEstablishment = false
+		err := d.sendRound(info)
+		if err != nil {
+			log.Printf("Client %d: round %d: %v", d.Client.ID, round, err)
+		}
+		round++
+	}
+	return nil
+}
+
+// Queue a message for the next round that does not already have a message
+func (d *Daemon) Queue(_ context.Context, m *api.Message) (*api.Queued, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.messageSize > 0 && len(m.Data) > d.messageSize {
+		return nil, errors.TooLong(len(m.Data), d.messageSize)
+	}
+	d.queue = append(d.queue, append([]byte{}, m.Data...))
+	return &api.Queued{Round: int64(d.nextRound + len(d.queue) - 1)}, nil
+}
+
+// The messages the anytrust groups posted for the round so far
+func (d *Daemon) Fetch(_ context.Context, r *api.OutputRequest) (*api.Output, error) {
+	d.boardLock.Lock()
+	defer d.boardLock.Unlock()
+	d.scanBoards()
+	output := &api.Output{
+		Round:    r.Round,
+		Messages: make([][]byte, 0),
+	}
+	byGroup := d.outputs[int(r.Round)]
+	groups := make([]int, 0, len(byGroup))
+	for gid := range byGroup {
+		groups = append(groups, gid)
+	}
+	sort.Ints(groups)
+	for _, gid := range groups {
+		output.Messages = append(output.Messages, byGroup[gid]...)
+	}
+	output.Groups = int64(len(groups))
+	return output, nil
+}
+
+// read the entries posted since the last scan
+func (d *Daemon) scanBoards() {
+	for i, b := range d.boards {
+		head, err := b.Head()
+		if err != nil {
+			// the other members of the groups post the same messages to their boards
+			continue
+		}
+		for d.scanned[i] < head.Length {
+			entries, err := b.GetRange(d.scanned[i], head.Length)
+			if err != nil || len(entries) == 0 {
+				break
+			}
+			for _, e := range entries {
+				d.readFinalMessages(e.Data)
+				d.scanned[i]++
+			}
+		}
+	}
+}
+
+// keep the first valid posting of each group's messages
+func (d *Daemon) readFinalMessages(data []byte) {
+	if len(data) == 0 || data[0] != bulletin.FINAL_MESSAGES {
+		return
+	}
+	f := &bulletin.FinalMessages{}
+	if f.InterpretFrom(data) != nil || !d.isMember(f.Poster, f.Group) || !f.Verify(d.C.VerificationKeys[f.Poster]) {
+		return
+	}
+	if d.outputs[f.Round] == nil {
+		d.outputs[f.Round] = make(map[int][][]byte)
+	}
+	if _, found := d.outputs[f.Round][f.Group]; !found {
+		d.outputs[f.Round][f.Group] = f.Messages
+	}
+}
+
+func (d *Daemon) isMember(server, group int) bool {
+	g := d.C.GroupConfigs.Groups[int64(group)]
+	if g == nil || server < 0 || server >= len(d.C.VerificationKeys) {
+		return false
+	}
+	for _, sid := range g.Servers {
+		if sid == int64(server) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ClientDaemonClient is the client API for ClientDaemon service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ClientDaemonClient interface {
+	// Queue a message to be sent in the next round without a message
+	Queue(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Queued, error)
+	// Get the broadcast output of a round from the bulletin boards
+	Fetch(ctx context.Context, in *OutputRequest, opts ...grpc.CallOption) (*Output, error)
+}
+
+type clientDaemonClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClientDaemonClient(cc grpc.ClientConnInterface) ClientDaemonClient {
+	return &clientDaemonClient{cc}
+}
+
+func (c *clientDaemonClient) Queue(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Queued, error) {
+	out := new(Queued)
+	err := c.cc.Invoke(ctx, "/api.ClientDaemon/Queue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clientDaemonClient) Fetch(ctx context.Context, in *OutputRequest, opts ...grpc.CallOption) (*Output, error) {
+	out := new(Output)
+	err := c.cc.Invoke(ctx, "/api.ClientDaemon/Fetch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClientDaemonServer is the server API for ClientDaemon service.
+// All implementations must embed UnimplementedClientDaemonServer
+// for forward compatibility
+type ClientDaemonServer interface {
+	// Queue a message to be sent in the next round without a message
+	Queue(context.Context, *Message) (*Queued, error)
+	// Get the broadcast output of a round from the bulletin boards
+	Fetch(context.Context, *OutputRequest) (*Output, error)
+	mustEmbedUnimplementedClientDaemonServer()
+}
+
+// UnimplementedClientDaemonServer must be embedded to have forward compatible implementations.
+type UnimplementedClientDaemonServer struct {
+}
+
+func (UnimplementedClientDaemonServer) Queue(context.Context, *Message) (*Queued, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Queue not implemented")
+}
+func (UnimplementedClientDaemonServer) Fetch(context.Context, *OutputRequest) (*Output, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Fetch not implemented")
+}
+func (UnimplementedClientDaemonServer) mustEmbedUnimplementedClientDaemonServer() {}
+
+// UnsafeClientDaemonServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ClientDaemonServer will
+// result in compilation errors.
+type UnsafeClientDaemonServer interface {
+	mustEmbedUnimplementedClientDaemonServer()
+}
+
+func RegisterClientDaemonServer(s grpc.ServiceRegistrar, srv ClientDaemonServer) {
+	s.RegisterService(&ClientDaemon_ServiceDesc, srv)
+}
+
+func _ClientDaemon_Queue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Message)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClientDaemonServer).Queue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.ClientDaemon/Queue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClientDaemonServer).Queue(ctx, req.(*Message))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClientDaemon_Fetch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OutputRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClientDaemonServer).Fetch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.ClientDaemon/Fetch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClientDaemonServer).Fetch(ctx, req.(*OutputRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ClientDaemon_ServiceDesc is the grpc.ServiceDesc for ClientDaemon service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ClientDaemon_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "api.ClientDaemon",
+	HandlerType: (*ClientDaemonServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Queue",
+			Handler:    _ClientDaemon_Queue_Handler,
+		},
+		{
+			MethodName: "Fetch",
+			Handler:    _ClientDaemon_Fetch_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "client.proto",
+}
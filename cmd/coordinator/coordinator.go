@@ -12,6 +12,9 @@ import (
 	"github.com/alexflint/go-arg"
 	"github.com/simonlangowski/lightning1/config"
 	"github.com/simonlangowski/lightning1/coordinator"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/metrics"
+	"github.com/simonlangowski/lightning1/server/beacon"
 	"github.com/simonlangowski/lightning1/server/prepareMessages"
 )
 
@@ -23,6 +26,8 @@ var args struct {
 	NumUsers    int     `default:"0"`
 	NumServers  int     `default:"0"`
 	MessageSize int     `default:"1024"`
+	// users send messages fragmented across this many slots
+	SlotsPerUser int `default:"0"`
 
 	NumGroups int `default:"0"`
 	GroupSize int `default:"0"`
@@ -46,6 +51,21 @@ var args struct {
 	Notes            string `default:""`
 	OutFile          string `default:"res.json"`
 	NoDummies        bool   `default:"True"`
+	// the servers run the randomness beacon after the experiment to choose the groups of the next epoch
+	Epoch         int    `default:"0"`
+	NextGroupFile string `default:""`
+	BeaconFile    string `default:"beacon.json"`
+	// clients revoked after their paths are established
+	Revoke []int64
+	// the servers run lightning rounds on this schedule, starting after the path rounds
+	ScheduleFile     string `default:"schedule.json"`
+	ScheduleDelay    int    `default:"60"` // seconds until the first round
+	RoundInterval    int    `default:"60"` // seconds
+	SubmissionWindow int    `default:"20"` // seconds
+	NumRounds        int    `default:"0"`
+
+	// serve metrics on /metrics at this address if set
+	Metrics string `default:""`
 
 	Latency   int `default:"0"`
 	Bandwidth int `default:"0"`
@@ -59,6 +79,12 @@ func main() {
 		p.WriteHelp(os.Stdout)
 		return
 	}
+	if args.Metrics != "" {
+		_, err := metrics.Serve(args.Metrics)
+		if err != nil {
+			log.Fatalf("Could not serve metrics: %v", err)
+		}
+	}
 	if args.GroupSize == 0 {
 		if args.F != 0 {
 			if args.NumGroups != 0 {
@@ -152,6 +178,7 @@ func main() {
 			}
 		}
 		ids = ids[:args.NumServers]
+		// the groups of the first epoch are chosen before the servers can run the beacon
 		groups := config.CreateSeparateGroupsWithSize(args.NumGroups, args.GroupSize, ids)
 		if args.NumClientServers > 0 {
 			err := config.MarshalServersToFile(args.ClientFile, clients)
@@ -216,6 +243,52 @@ func main() {
 		if err != nil {
 			log.Fatalf("Could not write servers file %s", args.ServerFile)
 		}
+		// the shares of the group keys stay with the servers, in the same directories as cmd/server
+		err = net.SetKeyDirectories("keys%d")
+		if err != nil {
+			log.Fatalf("Could not set key directories: %v", err)
+		}
+	} else if args.RunType == 6 {
+		// check that the groups file was chosen by the beacon transcript
+		servers, err := config.UnmarshalServersFromFile(args.ServerFile)
+		if err != nil {
+			log.Fatalf("Could not read servers file %s", args.ServerFile)
+		}
+		groups, err := config.UnmarshalGroupsFromFile(args.GroupFile)
+		if err != nil {
+			log.Fatalf("Could not read group file %s", args.GroupFile)
+		}
+		transcript := &coord.BeaconTranscript{}
+		err = config.Unmarshal(args.BeaconFile, transcript)
+		if err != nil {
+			log.Fatalf("Could not read beacon file %s", args.BeaconFile)
+		}
+		// the groups in use are those of args.Epoch
+		err = beacon.VerifyGroups(transcript, int64(args.Epoch), servers, groups)
+		if err != nil {
+			log.Fatalf("Groups do not match the beacon: %v", err)
+		}
+		log.Printf("Groups of epoch %d match the beacon", transcript.Epoch)
+		return
+	} else if args.RunType == 7 {
+		// write the schedule for servers to run lightning rounds without the coordinator
+		info := &coord.RoundInfo{
+			NumLayers:   int64(args.NumLayers),
+			BinSize:     int64(args.BinSize),
+			MessageSize: int64(args.MessageSize),
+		}
+		start := time.Now().Add(time.Duration(args.ScheduleDelay) * time.Second)
+		firstRound := args.NumLayers
+		if args.SkipPathGen {
+			firstRound = 1
+		}
+		schedule := config.NewSchedule(start, time.Duration(args.RoundInterval)*time.Second, time.Duration(args.SubmissionWindow)*time.Second, firstRound, args.NumRounds, info)
+		err := config.Marshal(args.ScheduleFile, schedule)
+		if err != nil {
+			log.Fatalf("Could not write schedule file %s", args.ScheduleFile)
+		}
+		log.Printf("Round %d starts at %v", firstRound, start)
+		return
 	}
 	numLayers := args.NumLayers
 	numServers := args.NumServers
@@ -272,11 +345,18 @@ func main() {
 		}
 		l = numLayers
 	}
+	if len(args.Revoke) > 0 {
+		err := c.RevokeClients(args.Revoke)
+		if err != nil {
+			log.Fatalf("Could not revoke clients: %v", err)
+		}
+	}
 	for i := l; i < l+numLightning; i++ {
 		log.Printf("Round %v", i)
 		exp := c.NewExperiment(i, numLayers, numServers, numMessages, args)
 		exp.Info.PathEstablishment = false
 		exp.Info.MessageSize = int64(args.MessageSize)
+		exp.Info.SlotsPerUser = int64(args.SlotsPerUser)
 		exp.Info.Check = !args.NoCheck
 		if args.BinSize > 0 {
 			exp.Info.BinSize = int64(args.BinSize)
@@ -296,6 +376,12 @@ func main() {
 		RecordToCsv(args.OutFile+".csv", exp)
 	}
 	l += numLightning
+	if args.NextGroupFile != "" {
+		err := c.FormGroups(args.Epoch+1, args.NumGroups, args.GroupSize, args.NextGroupFile, args.BeaconFile)
+		if err != nil {
+			log.Fatalf("Could not form groups: %v", err)
+		}
+	}
 }
 
 func ReadCsv(fn string) []string {
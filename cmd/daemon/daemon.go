@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alexflint/go-arg"
+	"github.com/simonlangowski/lightning1/client"
+	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network"
+)
+
+// run the client of a single user, with a local api on a unix socket
+
+var args struct {
+	Id         int64  `arg:"required"`
+	ServerFile string `default:"servers.json"`
+	GroupFile  string `default:"groups.json"`
+	ClientFile string `default:"clients.json"`
+	// the address in the clients file the coordinator starts rounds on, or empty to only follow the schedule
+	Addr         string `default:""`
+	StateFile    string `default:""`
+	Socket       string `default:""`
+	ScheduleFile string `default:""`
+	// the state is encrypted under the passphrase in this file, or in $CLIENT_PASSPHRASE
+	PassphraseFile string `default:""`
+}
+
+func main() {
+	arg.MustParse(&args)
+	if args.StateFile == "" {
+		args.StateFile = fmt.Sprintf("client%d.state", args.Id)
+	}
+	if args.Socket == "" {
+		args.Socket = fmt.Sprintf("client%d.sock", args.Id)
+	}
+	errors.Addr = args.Socket
+	servers, err := config.UnmarshalServersFromFile(args.ServerFile)
+	if err != nil {
+		log.Fatalf("Could not read servers file %s", args.ServerFile)
+	}
+	groups, err := config.UnmarshalGroupsFromFile(args.GroupFile)
+	if err != nil {
+		log.Fatalf("Could not read group file %s", args.GroupFile)
+	}
+	passphrase := []byte(os.Getenv("CLIENT_PASSPHRASE"))
+	if args.PassphraseFile != "" {
+		passphrase, err = ioutil.ReadFile(args.PassphraseFile)
+		if err != nil {
+			log.Fatalf("Could not read passphrase file %s", args.PassphraseFile)
+		}
+		passphrase = bytes.TrimRight(passphrase, "\r\n")
+	}
+	if len(passphrase) == 0 {
+		log.Fatalf("Set a passphrase to encrypt the client state")
+	}
+	daemon, err := client.NewDaemon(servers, groups, args.Id, args.StateFile, passphrase)
+	if err != nil {
+		log.Fatalf("Could not load client state %s: %v", args.StateFile, err)
+	}
+	err = daemon.Connect()
+	if err != nil {
+		log.Fatalf("Could not connect to servers %v", err)
+	}
+	api, _, err := daemon.Serve(args.Socket)
+	if err != nil {
+		log.Fatalf("Could not listen on %s: %v", args.Socket, err)
+	}
+	defer api.Stop()
+	if args.ScheduleFile != "" {
+		schedule := &coord.Schedule{}
+		err = config.Unmarshal(args.ScheduleFile, schedule)
+		if err != nil {
+			log.Fatalf("Could not read schedule file %s", args.ScheduleFile)
+		}
+		go daemon.RunSchedule(schedule)
+	}
+	if args.Addr != "" {
+		clients, err := config.UnmarshalServersFromFile(args.ClientFile)
+		if err != nil {
+			log.Fatalf("Could not read clients file %s", args.ClientFile)
+		}
+		network.RunServer(nil, daemon, clients, args.Addr)
+		return
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+}
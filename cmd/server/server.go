@@ -1,17 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 
+	"github.com/simonlangowski/lightning1/bulletin"
 	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
 	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/metrics"
 	"github.com/simonlangowski/lightning1/network"
 	"github.com/simonlangowski/lightning1/server"
 )
 
 func main() {
 	// read configuration files
+	// an optional schedule file before the address runs rounds without the coordinator
 	serversFile := os.Args[1]
 	groupsFile := os.Args[2]
 	addr := os.Args[len(os.Args)-1]
@@ -28,6 +33,28 @@ func main() {
 	// will start in blocked state
 	h := server.NewHandler()
 	server := server.NewServer(&config.Servers{Servers: servers}, &config.Groups{Groups: groups}, h, addr)
+	// reload keys from a previous run, so paths survive a restart
+	err = server.SetKeyDirectory(fmt.Sprintf("keys%d", server.CommonState.MyId))
+	if err != nil {
+		log.Fatalf("Could not load keys: %v", err)
+	}
+	err = server.SetAuditLog(fmt.Sprintf("audit%d", server.CommonState.MyId), config.AuditSegmentSize, config.AuditRetention)
+	if err != nil {
+		log.Fatalf("Could not create audit log: %v", err)
+	}
+	// submissions and final messages of this server's groups are posted here and served to clients and auditors
+	board, err := bulletin.NewFileBoard(fmt.Sprintf("bulletin%d.board", server.CommonState.MyId))
+	if err != nil {
+		log.Fatalf("Could not open bulletin board: %v", err)
+	}
+	server.SetBulletinBoard(board)
+	// metrics are served on /metrics at this address if it is set
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		_, err := metrics.Serve(metricsAddr)
+		if err != nil {
+			log.Fatalf("Could not serve metrics: %v", err)
+		}
+	}
 	// f, err := os.Create("path.pprof")
 	// if err != nil {
 	// 	log.Fatal(err)
@@ -35,6 +62,19 @@ func main() {
 	// pprof.StartCPUProfile(f)
 	// defer pprof.StopCPUProfile()
 	server.TcpConnections.LaunchAccepts()
-	network.RunServer(h, server, servers, addr)
+	if len(os.Args) > 4 {
+		schedule := &coord.Schedule{}
+		err = config.Unmarshal(os.Args[3], schedule)
+		if err != nil {
+			log.Fatalf("Could not read schedule file %s", os.Args[3])
+		}
+		go func() {
+			err := server.RunSchedule(schedule)
+			if err != nil {
+				log.Fatalf("Schedule stopped: %v", err)
+			}
+		}()
+	}
+	network.RunServer(h, server, servers, addr, bulletin.NewServer(board).Register)
 	config.Flush()
 }
@@ -75,12 +75,15 @@ func CreateRandomGroups(nGroups int, f float64, serverIds []int64) map[int64]*Gr
 	return CreateRandomGroupsWithSize(nGroups, size, serverIds)
 }
 
+// seeded for determinstic tests; real groups use the output of the randomness beacon
 func CreateRandomGroupsWithSize(nGroups, size int, serverIds []int64) map[int64]*Group {
+	return CreateRandomGroupsFromShuffler(nGroups, size, serverIds, SeededShuffler())
+}
+
+func CreateRandomGroupsFromShuffler(nGroups, size int, serverIds []int64, r *Shuffler) map[int64]*Group {
 	log.Printf("Warning: fix group distribution")
 	n := len(serverIds)
 	groups := make(map[int64]*Group)
-	// seeding for determinstic tests; a public unbiased source of randomness should be used
-	r := SeededShuffler()
 	for i := 0; i < nGroups; i++ {
 		indices := r.SelectRandom(n, size)
 		servers := make([]int64, size)
@@ -92,10 +95,14 @@ func CreateRandomGroupsWithSize(nGroups, size int, serverIds []int64) map[int64]
 	return groups
 }
 
+// seeded for determinstic tests
 func CreateSeparateGroupsWithSize(nGroups, size int, serverIds []int64) map[int64]*Group {
+	return CreateSeparateGroupsFromShuffler(nGroups, size, serverIds, SeededShuffler())
+}
+
+func CreateSeparateGroupsFromShuffler(nGroups, size int, serverIds []int64, s *Shuffler) map[int64]*Group {
 	n := len(serverIds)
 	// Select from disjoint sets of a random permutation
-	s := SeededShuffler()
 	permutation := s.Perm(n)
 	groups := make(map[int64]*Group)
 	index := 0
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // choice of algorithm to compute layers
 const LayerAlgorithm = 0
 
@@ -24,11 +26,29 @@ const BatchSize = 64
 
 // To estimate timeouts
 const Bandwidth = 1000 // mega bits per second
+// A layer times out after this many times as long as its messages take to arrive at the bandwidth
+const LayerTimeoutFactor = 10
+
+// plus this much time for processing
+const MinLayerTimeout = 30 * time.Second
+
 // the minimum amount of bytes per read system call
 const TCPReadSize = 1460
 
 const MASTER_GROUP = 0
 
+// Tokens are issued as long as at most this many members of an anytrust group are offline
+// but then fewer than all members of a group can sign tokens together
+const TokenOfflineTolerance = 1
+
+// number of partial signatures needed to make a token
+func TokenThreshold(groupSize int) int {
+	if groupSize-TokenOfflineTolerance < 1 {
+		return 1
+	}
+	return groupSize - TokenOfflineTolerance
+}
+
 // INSECURE: just for computing messages faster to test other parts of the system
 const SkipToken = true
 
@@ -36,4 +56,18 @@ const LogTimes = false
 
 const PreExpandKeys = false
 
-const NoDummies = true
+// links whose bins would take more bytes than this are buffered in a temporary file
+const MaxMemoryBuffer = 256 * 1024 * 1024
+
+// where the buffer files are created, or the default directory for temporary files if empty
+const BufferDirectory = ""
+
+// links are padded to the bin size with cover traffic
+const NoDummies = false
+
+// Audit log of received batches: maximum bytes stored per round, and number of rounds kept
+const AuditSegmentSize = 8 * 1024 * 1024 * 1024
+const AuditRetention = 4
+
+// receipts that stop beyond the first layer are published in this many buckets by each server
+const ReceiptBuckets = 64
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"time"
+
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+)
+
+// A round starts every interval after the start of the first round
+// and takes submissions until the end of its window
+func NewSchedule(start time.Time, interval, window time.Duration, firstRound, numRounds int, round *coord.RoundInfo) *coord.Schedule {
+	return &coord.Schedule{
+		Start:      start.UnixNano(),
+		Interval:   int64(interval),
+		Window:     int64(window),
+		FirstRound: int64(firstRound),
+		NumRounds:  int64(numRounds),
+		Round:      round,
+	}
+}
+
+func RoundStartTime(s *coord.Schedule, round int) time.Time {
+	return time.Unix(0, s.Start).Add(time.Duration(int64(round)-s.FirstRound) * time.Duration(s.Interval))
+}
+
+// when the servers start processing the submissions of the round
+func SubmissionDeadline(s *coord.Schedule, round int) time.Time {
+	return RoundStartTime(s, round).Add(time.Duration(s.Window))
+}
+
+// the first round still taking submissions at t
+func NextOpenRound(s *coord.Schedule, t time.Time) int {
+	first := SubmissionDeadline(s, int(s.FirstRound))
+	if t.Before(first) {
+		return int(s.FirstRound)
+	}
+	return int(s.FirstRound) + int(t.Sub(first)/time.Duration(s.Interval)) + 1
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Unix(1000, 0)
+	s := NewSchedule(start, 10*time.Second, 3*time.Second, 5, 0, nil)
+	if !RoundStartTime(s, 7).Equal(start.Add(20*time.Second)) || !SubmissionDeadline(s, 7).Equal(start.Add(23*time.Second)) {
+		t.Fatalf("Wrong round times")
+	}
+	cases := map[time.Duration]int{
+		-time.Minute:     5,
+		2 * time.Second:  5,
+		3 * time.Second:  6,
+		12 * time.Second: 6,
+		13 * time.Second: 7,
+	}
+	for offset, round := range cases {
+		if r := NextOpenRound(s, start.Add(offset)); r != round {
+			t.Fatalf("Round %d open at %v, expected %d", r, offset, round)
+		}
+	}
+}
@@ -8,33 +8,36 @@ import (
 	"log"
 	"os"
 	"runtime/pprof"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/simonlangowski/lightning1/config"
 	coord "github.com/simonlangowski/lightning1/coordinator/messages"
-	"github.com/simonlangowski/lightning1/crypto"
-	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
-	"github.com/simonlangowski/lightning1/crypto/token"
 	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/metrics"
+	"github.com/simonlangowski/lightning1/server/beacon"
 	"github.com/simonlangowski/lightning1/server/prepareMessages"
 )
 
 // The coordinator simulates the glocal clock time when the round begins, the time when receipts should have been received by, etc.
 // It also allows the experimenter to set parameters and measure the time things take
+// Once paths are established, servers can instead keep the clock themselves with a schedule (see Server.RunSchedule)
+// and the coordinator is only needed for monitoring
 
-type Coordinator struct {
-	privateKeys     []map[int64]*coord.KeyInformation
-	groupSecretKeys groupSecretKeys
-	publicKeys      *coord.KeyInformation
-	Net             *CoordinatorNetwork
-	mu              sync.Mutex
-}
+var (
+	roundSeconds       = metrics.NewHistogram("lightning_round_seconds", "Time from the start of a round until its messages were delivered", metrics.LatencyBuckets, "type")
+	roundsChecked      = metrics.NewCounter("lightning_rounds_total", "Rounds run by the coordinator", "passed")
+	serversDropped     = metrics.NewCounter("lightning_servers_dropped_total", "Servers dropped for missing a deadline")
+	messagesOverflowed = metrics.NewCounter("lightning_messages_overflowed_total", "Messages dropped because their links overflowed")
+)
 
-type groupSecretKeys struct {
-	TokenSecretKey []byte
-	Ssk            crypto.DHPrivateKey
-	GroupKey       []byte
+type Coordinator struct {
+	// the coordinator only learns the public keys of the anytrust groups
+	publicKeys *coord.KeyInformation
+	Net        *CoordinatorNetwork
+	revoked    map[int64]bool // clients whose messages are no longer delivered
+	mu         sync.Mutex
 }
 
 type Experiment struct {
@@ -54,22 +57,11 @@ type Experiment struct {
 }
 
 func NewCoordinator(net *CoordinatorNetwork) *Coordinator {
-	cfgs := net.ServerConfigs
-	groups := net.GroupConfigs
-	c := &Coordinator{
-		privateKeys: make([]map[int64]*coord.KeyInformation, len(cfgs)),
-		publicKeys:  &coord.KeyInformation{},
-		Net:         net,
-	}
-	for i := range c.privateKeys {
-		c.privateKeys[i] = make(map[int64]*coord.KeyInformation)
-	}
-	for gid, group := range groups {
-		for _, sid := range group.Servers {
-			c.privateKeys[sid][gid] = &coord.KeyInformation{}
-		}
+	return &Coordinator{
+		publicKeys: &coord.KeyInformation{},
+		Net:        net,
+		revoked:    make(map[int64]bool),
 	}
-	return c
 }
 
 func (c *Coordinator) NewExperiment(round, numLayers, numServers, numMessages int, notes interface{}) *Experiment {
@@ -105,17 +97,22 @@ func (c *Coordinator) DoAction(exp *Experiment) error {
 	exp.ExperimentStartTime = time.Now()
 
 	if exp.KeyGen {
-		c.KeyGenToken()
-		c.GenDHKeys()
+		err := c.KeyGen()
+		if err != nil {
+			log.Printf("Key exchange")
+			return err
+		}
 	}
 	if exp.KeyGen || exp.LoadKeys {
-		err := c.Net.SendKeys(c.privateKeys, c.publicKeys)
+		err := c.Net.SendKeys(c.publicKeys)
 		if err != nil {
 			log.Printf("Key gen")
 			return err
 		}
 	}
 	keyGenTime := time.Now()
+	// servers and clients route around the servers that were dropped
+	exp.Info.DroppedServers = c.Net.DroppedServers()
 	if exp.DoRound {
 		if !exp.Info.PathEstablishment || exp.Info.Round == 0 {
 			err := c.Net.SendRoundSetup(exp.Info)
@@ -146,33 +143,27 @@ func (c *Coordinator) DoAction(exp *Experiment) error {
 			}
 		}
 		if exp.Info.PathEstablishment {
-			if exp.Info.ReceiptLayer == 0 && exp.Info.Round != 0 {
+			if exp.Info.Round != 0 {
+				// beyond the first layer the clients fetch their receipts privately
 				err := c.Net.CheckClientReceipt(exp.Info, exp.NumMessages)
 				if err != nil {
 					log.Printf("Client receipts")
 					return err
 				}
-				exp.Passed = true
-			} else if exp.Info.ReceiptLayer > 0 {
-				// these receipts are only checked for test purposes
-				// they could not be checked without breaking anonymity
+			}
+			exp.Passed = true
+			if exp.Info.ReceiptLayer > 0 && c.Net.clientNetType == inprocess && exp.Info.Check {
+				// in process, also check that exactly the receipts of the clients arrived
 				messages, err := c.Net.GetMessages(exp.Info)
 				if err != nil {
 					log.Printf("Get messages")
 					return err
 				}
-				if c.Net.clientNetType == inprocess && exp.Info.Check {
-					exp.Passed = c.CheckReceipts(messages, int(exp.Info.Round), c.Net.clients.Clients)
-					if !exp.Passed {
-						log.Printf("Client receipts in process")
-						return errors.WrongReceipt()
-					}
-				} else {
-					// skip check - however getMessages ensures the round has completed which is still important
-					exp.Passed = true
+				exp.Passed = c.CheckReceipts(messages, int(exp.Info.Round), c.Net.clients.Clients)
+				if !exp.Passed {
+					log.Printf("Client receipts in process")
+					return errors.WrongReceipt()
 				}
-			} else {
-				exp.Passed = true
 			}
 		} else {
 			messages, err := c.Net.GetMessages(exp.Info)
@@ -180,10 +171,36 @@ func (c *Coordinator) DoAction(exp *Experiment) error {
 				log.Printf("Get messages")
 				return err
 			}
-			exp.Passed = c.Check(messages, exp.NumMessages)
+			if exp.Info.SlotsPerUser > 0 {
+				// fragments that reached different groups are reassembled together
+				messages = prepareMessages.CompleteMessages(prepareMessages.Reassemble(messages))
+				exp.Passed = c.Check(messages, exp.NumMessages/int(exp.Info.SlotsPerUser))
+			} else {
+				exp.Passed = c.Check(messages, exp.NumMessages)
+			}
 		}
 		endTime := time.Now()
 		exp.ServerRoundTime = endTime.Sub(roundStartTime)
+		roundType := "lightning"
+		if exp.Info.PathEstablishment {
+			roundType = "path"
+		}
+		roundSeconds.Observe(exp.ServerRoundTime.Seconds(), roundType)
+		roundsChecked.Inc(strconv.FormatBool(exp.Passed))
+		dropped, overflowed, err := c.Net.GetChurn(exp.Info)
+		if err != nil {
+			log.Printf("Churn")
+			return err
+		}
+		serversDropped.Add(float64(len(dropped)))
+		messagesOverflowed.Add(float64(overflowed))
+		if len(dropped) > 0 {
+			log.Printf("Servers %v were dropped in round %d", dropped, exp.Info.Round)
+		}
+		if overflowed > 0 {
+			// the paths of these messages were revoked, and the first servers tell their clients when they next send over them
+			log.Printf("%d messages overflowed their links in round %d", overflowed, exp.Info.Round)
+		}
 	}
 
 	exp.KeyGenTime = keyGenTime.Sub(exp.ExperimentStartTime)
@@ -192,18 +209,37 @@ func (c *Coordinator) DoAction(exp *Experiment) error {
 
 func (c *Coordinator) Check(messages [][]byte, numExpected int) bool {
 	seen := make(map[uint64]bool)
-	// test messages are consecutive integers up to numExpected
+	// test messages are consecutive integers up to numExpected, except those of revoked clients
 	for _, m := range messages {
-		c := binary.LittleEndian.Uint64(m)
-		if c < uint64(numExpected) {
-			seen[c] = true
+		id := binary.LittleEndian.Uint64(m)
+		if id < uint64(numExpected) && !c.revoked[int64(id)] {
+			seen[id] = true
 		} else {
 			return false
 		}
 	}
+	for id := range c.revoked {
+		if id < int64(numExpected) {
+			numExpected--
+		}
+	}
 	return len(seen) == numExpected
 }
 
+// the clients get no more tokens, and their messages are skipped at every layer of their paths
+func (c *Coordinator) RevokeClients(ids []int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.Net.Revoke(&coord.Revocation{Clients: ids})
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		c.revoked[id] = true
+	}
+	return nil
+}
+
 func (c *Coordinator) CheckReceipts(receipts [][]byte, round int, clients map[int64]*prepareMessages.Client) bool {
 	// receipts are not sorted
 	if len(receipts) != len(clients) {
@@ -228,57 +264,28 @@ func (c *Coordinator) CheckReceipts(receipts [][]byte, round int, clients map[in
 	return ok
 }
 
-// return keys to send to each server
-func (c *Coordinator) KeyGenToken() {
-	tokenSecretKey := mcl.Fr{}
-	tokenSecretKey.Random()
-	c.keyGenToken(&tokenSecretKey)
-	c.groupSecretKeys.TokenSecretKey = tokenSecretKey.Serialize()
-}
-
-func (c *Coordinator) keyGenToken(tokenSecretKey *mcl.Fr) {
-	if config.SkipToken {
-		log.Print("Warning: Using fixed token key is insecure")
-		tokenSecretKey = &token.SecretKey.Share
-	}
-	for gid, group := range c.Net.GroupConfigs {
-		shares, pk, _ := token.MockKeyGen(len(group.Servers), tokenSecretKey)
-		for i, sid := range group.Servers {
-			k := c.privateKeys[sid][gid]
-			k.GroupId = gid
-			k.TokenPublicKey = make([]byte, pk.Len())
-			pk.PackTo(k.TokenPublicKey)
-			k.TokenKeyShare = make([]byte, shares[i].Share.Len())
-			shares[i].Share.PackTo(k.TokenKeyShare)
-		}
+// the servers run a distributed key generation for the anytrust groups
+func (c *Coordinator) KeyGen() error {
+	publicKeys, err := c.Net.ExchangeKeys()
+	if err != nil {
+		return err
 	}
-	tokenPublicKey := token.TokenPublicKey{}
-	tokenPublicKey.X = token.NewTokenSigningKey(tokenSecretKey).X
-	c.publicKeys.TokenPublicKey = make([]byte, tokenPublicKey.Len())
-	tokenPublicKey.PackTo(c.publicKeys.TokenPublicKey)
-}
-
-func (c *Coordinator) GenDHKeys() {
-	ssk, spk := crypto.NewDHKeyPair()
-	groupKey := make([]byte, spk.Len())
-	spk.PackTo(groupKey)
-	c.groupSecretKeys.Ssk = ssk
-	c.groupSecretKeys.GroupKey = groupKey
-	c.genDHKeys(ssk, groupKey)
+	c.publicKeys = publicKeys
+	return nil
 }
 
-func (c *Coordinator) genDHKeys(ssk crypto.DHPrivateKey, groupKey []byte) {
-	for gid, group := range c.Net.GroupConfigs {
-		shares := crypto.AdditiveShares(&ssk, len(group.Servers))
-		for idx, sid := range group.Servers {
-			b := shares[idx].Bytes()
-			k := c.privateKeys[sid][gid]
-			k.GroupId = gid
-			k.GroupShare = b
-			k.GroupKey = groupKey
-		}
+// the servers run the randomness beacon to choose the groups of the next epoch
+// the transcript is written with the groups, so anyone can check them with beacon.VerifyGroups
+func (c *Coordinator) FormGroups(epoch, numGroups, groupSize int, groupFile, beaconFile string) error {
+	transcript, err := c.Net.RunBeacon(epoch, numGroups, groupSize)
+	if err != nil {
+		return err
 	}
-	c.publicKeys.GroupKey = groupKey
+	err = config.Marshal(beaconFile, transcript)
+	if err != nil {
+		return err
+	}
+	return config.MarshalGroupsToFile(groupFile, beacon.Groups(transcript))
 }
 
 func (e *Experiment) RecordToFile(fn string) {
@@ -300,11 +307,13 @@ func (e *Experiment) RecordToFile(fn string) {
 
 func (c *Coordinator) WriteKeys(fn string) {
 	// note that server keys are in the server config file
-	// these are the keys for the anytrust groups
-	// write group secret token key and dh key
-	c.KeyGenToken()
-	c.GenDHKeys()
-	err := c.Net.SendKeys(c.privateKeys, c.publicKeys)
+	// the shares of the anytrust group keys are kept by the servers in their key directories
+	// write the group public keys
+	err := c.KeyGen()
+	if err != nil {
+		panic(err)
+	}
+	err = c.Net.SendKeys(c.publicKeys)
 	if err != nil {
 		panic(err)
 	}
@@ -313,7 +322,7 @@ func (c *Coordinator) WriteKeys(fn string) {
 	if err != nil {
 		panic(err)
 	}
-	b, err := json.Marshal(c.groupSecretKeys)
+	b, err := json.Marshal(c.publicKeys)
 	if err != nil {
 		panic(err)
 	}
@@ -328,16 +337,13 @@ func (c *Coordinator) LoadKeys(fn string) {
 	if err != nil {
 		panic(err)
 	}
-	// read group secret token key and dh key
-	err = json.Unmarshal(b, &c.groupSecretKeys)
+	// read group public keys
+	// the servers load their shares from their key directories, so the groups must not have changed
+	c.publicKeys = &coord.KeyInformation{}
+	err = json.Unmarshal(b, c.publicKeys)
 	if err != nil {
 		panic(err)
 	}
-	// regenerate shares for the current group configuration
-	tokenSecretKey := mcl.Fr{}
-	tokenSecretKey.Deserialize(c.groupSecretKeys.TokenSecretKey)
-	c.keyGenToken(&tokenSecretKey)
-	c.genDHKeys(c.groupSecretKeys.Ssk, c.groupSecretKeys.GroupKey)
 }
 
 // record the messages to the designated file instead of submitting to the servers
@@ -1,12 +1,26 @@
 package coordinator
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"testing"
+	"time"
+
+	"github.com/simonlangowski/lightning1/bulletin"
+	"github.com/simonlangowski/lightning1/client"
+	api "github.com/simonlangowski/lightning1/client/messages"
+	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/server"
+	"github.com/simonlangowski/lightning1/server/beacon"
+	"google.golang.org/protobuf/proto"
 )
 
 func memProfile(name string) {
@@ -219,3 +233,434 @@ func TestInprocessPathAndLightning2(t *testing.T) {
 		}
 	}
 }
+
+func TestInprocessBeacon(t *testing.T) {
+	numServers := 10
+	numGroups := 3
+	groupSize := 3
+	net := NewInProcessNetwork(numServers, numGroups, groupSize)
+	c := NewCoordinator(net)
+	dir := t.TempDir()
+	groupFile := filepath.Join(dir, "groups.json")
+	beaconFile := filepath.Join(dir, "beacon.json")
+	err := c.FormGroups(1, numGroups, groupSize, groupFile, beaconFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// recompute the groups from the files
+	groups, err := config.UnmarshalGroupsFromFile(groupFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript := &coord.BeaconTranscript{}
+	err = config.Unmarshal(beaconFile, transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = beacon.VerifyGroups(transcript, 0, net.ServerConfigs, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInprocessRevocation(t *testing.T) {
+	numServers := 10
+	numGroups := 3
+	groupSize := 3
+	numLayers := 5
+	numMessages := 50
+	net := NewInProcessNetwork(numServers, numGroups, groupSize)
+	c := NewCoordinator(net)
+	for i := 0; i < numLayers; i++ {
+		exp := c.NewExperiment(i, numLayers, numServers, numMessages, "")
+		exp.KeyGen = (i == 0)
+		exp.Info.PathEstablishment = true
+		exp.Info.BoomerangLimit = int64(numLayers)
+		exp.Info.NextLayer = int64(i)
+		exp.Info.LastLayer = (i == numLayers-1)
+		err := c.DoAction(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	revoked := []int64{0, 7}
+	err := c.RevokeClients(revoked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the paths are revoked at every layer before the next round
+	for layer := 0; layer < numLayers; layer++ {
+		numKeys := 0
+		for _, s := range net.servers {
+			numKeys += s.Keys[layer].NumKeys()
+		}
+		if numKeys != numMessages-len(revoked) {
+			t.Fatalf("%d keys left in layer %d", numKeys, layer)
+		}
+	}
+	// the revoked clients still submit, but their messages are skipped in every layer
+	for i := numLayers; i < numLayers+2; i++ {
+		exp := c.NewExperiment(i, numLayers, numServers, numMessages, "")
+		exp.Info.PathEstablishment = false
+		err := c.DoAction(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exp.Passed {
+			t.Fatalf("Messages of revoked clients delivered in round %d", i)
+		}
+	}
+	// no server waited for the skipped messages
+	for _, s := range net.servers {
+		if len(s.Accusations()) != 0 {
+			t.Fatalf("Server %d blamed a link for revoked messages", s.CommonState.MyId)
+		}
+	}
+}
+
+func TestInprocessComplaint(t *testing.T) {
+	numServers := 10
+	numGroups := 3
+	groupSize := 3
+	numLayers := 5
+	numMessages := 50
+	net := NewInProcessNetwork(numServers, numGroups, groupSize)
+	c := NewCoordinator(net)
+	for i := 0; i < numLayers; i++ {
+		exp := c.NewExperiment(i, numLayers, numServers, numMessages, "")
+		exp.KeyGen = (i == 0)
+		exp.Info.PathEstablishment = true
+		exp.Info.BoomerangLimit = int64(numLayers)
+		exp.Info.NextLayer = int64(i)
+		exp.Info.LastLayer = (i == numLayers-1)
+		err := c.DoAction(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the client disputes its last receipt, although the path is well formed
+	cli := net.clients.Clients[3]
+	err := cli.CheckReceipt(net.clients.Caller, numLayers-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cli.Complain(net.clients.Caller, numLayers-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cli.PathKeys) != 0 {
+		t.Fatalf("Client kept the path it complained about")
+	}
+	// each member of the client's group traced the path
+	numVerdicts := 0
+	for _, s := range net.servers {
+		for _, v := range s.Verdicts() {
+			if !v.WellFormed() {
+				t.Fatalf("Well formed path was blamed at layer %d", v.Layer)
+			}
+			if v.Receipt == nil {
+				t.Fatalf("Receipt the client showed is not in the verdict")
+			}
+			numVerdicts++
+		}
+	}
+	if numVerdicts != groupSize {
+		t.Fatalf("%d members traced the path", numVerdicts)
+	}
+	// and the path is revoked at every layer
+	for layer := 0; layer < numLayers; layer++ {
+		numKeys := 0
+		for _, s := range net.servers {
+			numKeys += s.Keys[layer].NumKeys()
+		}
+		if numKeys != numMessages-1 {
+			t.Fatalf("%d keys left in layer %d", numKeys, layer)
+		}
+	}
+}
+
+func TestInprocessOverflow(t *testing.T) {
+	numServers := 10
+	numGroups := 3
+	groupSize := 3
+	numLayers := 5
+	numMessages := 50
+	net := NewInProcessNetwork(numServers, numGroups, groupSize)
+	c := NewCoordinator(net)
+	exp := c.NewExperiment(0, numLayers, numServers, numMessages, "")
+	exp.Info.SkipPathGen = true
+	exp.KeyGen = true
+	exp.Info.PathEstablishment = false
+	// bins far too small for the load, so that links overflow
+	binSize := exp.Info.BinSize
+	exp.Info.BinSize = 1
+	err := c.DoAction(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the paths of the dropped messages are revoked at every layer
+	numKeys := 0
+	for layer := 0; layer < numLayers; layer++ {
+		n := 0
+		for _, s := range net.servers {
+			n += s.Keys[layer].NumKeys()
+		}
+		if layer == 0 {
+			numKeys = n
+		} else if n != numKeys {
+			t.Fatalf("%d keys left in layer %d, but %d in layer 0", n, layer, numKeys)
+		}
+	}
+	if numKeys == numMessages {
+		t.Fatalf("No links overflowed")
+	}
+	if messagesOverflowed.Value() != float64(numMessages-numKeys) {
+		t.Fatalf("%v overflowed messages reported for %d revoked paths", messagesOverflowed.Value(), numMessages-numKeys)
+	}
+	// the other paths still deliver their messages
+	exp = c.NewExperiment(1, numLayers, numServers, numMessages, "")
+	exp.Info.PathEstablishment = false
+	exp.Info.BinSize = binSize
+	err = c.DoAction(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := c.Net.GetMessages(exp.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each member of a group has the messages of the group
+	delivered := make(map[uint64]bool)
+	for _, m := range messages {
+		delivered[binary.LittleEndian.Uint64(m)] = true
+	}
+	if len(delivered) != numKeys {
+		t.Fatalf("%d messages delivered by %d paths", len(delivered), numKeys)
+	}
+	// the clients of the revoked paths were told, and wait to establish new paths
+	noPath := 0
+	for _, cli := range net.clients.Clients {
+		if len(cli.PathKeys) == 0 {
+			noPath++
+		}
+	}
+	if noPath != numMessages-numKeys {
+		t.Fatalf("%d clients without a path for %d revoked paths", noPath, numMessages-numKeys)
+	}
+	// no server waited for the dropped messages
+	for _, s := range net.servers {
+		if len(s.Accusations()) != 0 {
+			t.Fatalf("Server %d blamed a link for dropped messages", s.CommonState.MyId)
+		}
+	}
+}
+
+func TestInprocessSchedule(t *testing.T) {
+	numServers := 10
+	numGroups := 3
+	groupSize := 3
+	numLayers := 5
+	numMessages := 50
+	numRounds := 3
+	net := NewInProcessNetwork(numServers, numGroups, groupSize)
+	c := NewCoordinator(net)
+	exp := c.NewExperiment(0, numLayers, numServers, numMessages, "")
+	exp.Info.SkipPathGen = true
+	exp.KeyGen = true
+	exp.Info.PathEstablishment = false
+	err := c.DoAction(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the servers run the next rounds on their own
+	template := proto.Clone(exp.Info).(*coord.RoundInfo)
+	template.SkipPathGen = false
+	schedule := config.NewSchedule(time.Now().Add(time.Second), 2*time.Second, time.Second, 1, numRounds, template)
+	done := make(chan error)
+	for _, s := range net.servers {
+		go func(s *server.Server) {
+			done <- s.RunSchedule(schedule)
+		}(s)
+	}
+	// clients submit in each window
+	for round := 1; round <= numRounds; round++ {
+		time.Sleep(time.Until(config.RoundStartTime(schedule, round)))
+		info := proto.Clone(template).(*coord.RoundInfo)
+		info.Round = int64(round)
+		err = net.SendClientStart(info, numMessages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Now().After(config.SubmissionDeadline(schedule, round)) {
+			t.Fatalf("Clients did not submit in the window of round %d", round)
+		}
+	}
+	for range net.servers {
+		err := <-done
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	info := proto.Clone(template).(*coord.RoundInfo)
+	info.Round = int64(numRounds)
+	messages, err := net.GetMessages(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Check(messages, numMessages) {
+		t.Fatalf("Messages of the last round not delivered")
+	}
+}
+
+func TestInprocessFragmented(t *testing.T) {
+	numServers := 10
+	numGroups := 3
+	groupSize := 3
+	numLayers := 5
+	numMessages := 60
+	net := NewInProcessNetwork(numServers, numGroups, groupSize)
+	c := NewCoordinator(net)
+	for i := 0; i < 2; i++ {
+		exp := c.NewExperiment(i, numLayers, numServers, numMessages, "")
+		exp.Info.SkipPathGen = (i == 0)
+		exp.KeyGen = (i == 0)
+		exp.Info.PathEstablishment = false
+		// each user sends a message longer than one slot across 3 slots
+		exp.Info.SlotsPerUser = 3
+		exp.Info.MessageSize = 64
+		err := c.DoAction(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exp.Passed {
+			t.Fatalf("Fragmented messages not reassembled in round %d", i)
+		}
+	}
+}
+
+func TestInprocessDaemon(t *testing.T) {
+	numServers := 10
+	numGroups := 3
+	groupSize := 3
+	numLayers := 4
+	numMessages := 30
+	numRounds := 2
+	net := NewInProcessNetwork(numServers, numGroups, groupSize)
+	c := NewCoordinator(net)
+	dir := t.TempDir()
+	boards := make([]bulletin.BulletinBoard, numServers)
+	for i, s := range net.servers {
+		b, err := bulletin.NewFileBoard(filepath.Join(dir, fmt.Sprintf("bulletin%d.board", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetBulletinBoard(b)
+		boards[i] = b
+	}
+	// the user of the daemon comes after the simulated clients
+	id := int64(numMessages)
+	stateFile := filepath.Join(dir, "client.state")
+	passphrase := []byte("passphrase")
+	d, err := client.NewDaemon(net.ServerConfigs, net.GroupConfigs, id, stateFile, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Caller = net.clients.Caller
+	ctx := context.Background()
+	for i := 0; i < numLayers; i++ {
+		exp := c.NewExperiment(i, numLayers, numServers, numMessages, "")
+		exp.KeyGen = (i == 0)
+		exp.Info.PathEstablishment = true
+		exp.Info.BoomerangLimit = int64(numLayers)
+		exp.Info.NextLayer = int64(i)
+		exp.Info.LastLayer = (i == numLayers-1)
+		err := c.DoAction(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := proto.Clone(exp.Info).(*coord.RoundInfo)
+		info.StartId = id
+		info.EndId = id + 1
+		if i == 0 {
+			_, err = d.KeySet(ctx, c.publicKeys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// still taking submissions until the next round starts
+			_, err = d.ClientStart(ctx, info)
+		} else {
+			_, err = d.CheckReceipt(ctx, info)
+		}
+		if err != nil {
+			t.Fatalf("Daemon in round %d: %v", i, err)
+		}
+	}
+	// the state file is only loaded for its own client
+	_, err = client.NewDaemon(net.ServerConfigs, net.GroupConfigs, id+1, stateFile, passphrase)
+	if _, ok := err.(*errors.WrongStateError); !ok {
+		t.Fatalf("State file loaded for another client: %v", err)
+	}
+	// the paths are reloaded from the state file
+	d, err = client.NewDaemon(net.ServerConfigs, net.GroupConfigs, id, stateFile, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Caller = net.clients.Caller
+	d.SetBoards(boards)
+	m := make([]byte, 8)
+	binary.LittleEndian.PutUint64(m, uint64(id))
+	_, err = d.Queue(ctx, &api.Message{Data: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := c.NewExperiment(numLayers, numLayers, numServers, numMessages, "").Info
+	template.PathEstablishment = false
+	schedule := config.NewSchedule(time.Now().Add(time.Second), 2*time.Second, time.Second, numLayers, numRounds, template)
+	done := make(chan error)
+	for _, s := range net.servers {
+		go func(s *server.Server) {
+			done <- s.RunSchedule(schedule)
+		}(s)
+	}
+	go func() {
+		done <- d.RunSchedule(schedule)
+	}()
+	for round := numLayers; round < numLayers+numRounds; round++ {
+		time.Sleep(time.Until(config.RoundStartTime(schedule, round)))
+		info := proto.Clone(template).(*coord.RoundInfo)
+		info.Round = int64(round)
+		err = net.SendClientStart(info, numMessages)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < len(net.servers)+1; i++ {
+		err := <-done
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	info := proto.Clone(template).(*coord.RoundInfo)
+	info.Round = int64(numLayers + numRounds - 1)
+	_, err = net.GetMessages(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the queued message is sent in the first round, and an empty message after it
+	for round, expected := range []bool{true, false} {
+		output, err := d.Fetch(ctx, &api.OutputRequest{Round: int64(numLayers + round)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(output.Messages) != numMessages+1 {
+			t.Fatalf("%d messages from %d groups in round %d", len(output.Messages), output.Groups, output.Round)
+		}
+		found := false
+		for _, m := range output.Messages {
+			found = found || binary.LittleEndian.Uint64(m) == uint64(id)
+		}
+		if found != expected {
+			t.Fatalf("Queued message found %v in round %d", found, output.Round)
+		}
+	}
+}
@@ -30,6 +30,12 @@ type CoordinatorHandlerClient interface {
 	CheckReceipt(ctx context.Context, in *RoundInfo, opts ...grpc.CallOption) (*Empty, error)
 	// Check that the final output messages are correct; used to time end of round
 	GetMessages(ctx context.Context, in *RoundInfo, opts ...grpc.CallOption) (*ServerMessages, error)
+	// Collect the servers that were dropped, after a round
+	GetChurn(ctx context.Context, in *RoundInfo, opts ...grpc.CallOption) (*ChurnReport, error)
+	// Signal servers to run the randomness beacon for the groups of an epoch
+	RunBeacon(ctx context.Context, in *BeaconTranscript, opts ...grpc.CallOption) (*BeaconTranscript, error)
+	// Revoke clients or keys, between rounds
+	Revoke(ctx context.Context, in *Revocation, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type coordinatorHandlerClient struct {
@@ -94,6 +100,33 @@ func (c *coordinatorHandlerClient) GetMessages(ctx context.Context, in *RoundInf
 	return out, nil
 }
 
+func (c *coordinatorHandlerClient) GetChurn(ctx context.Context, in *RoundInfo, opts ...grpc.CallOption) (*ChurnReport, error) {
+	out := new(ChurnReport)
+	err := c.cc.Invoke(ctx, "/coord.CoordinatorHandler/GetChurn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorHandlerClient) RunBeacon(ctx context.Context, in *BeaconTranscript, opts ...grpc.CallOption) (*BeaconTranscript, error) {
+	out := new(BeaconTranscript)
+	err := c.cc.Invoke(ctx, "/coord.CoordinatorHandler/RunBeacon", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorHandlerClient) Revoke(ctx context.Context, in *Revocation, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/coord.CoordinatorHandler/Revoke", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CoordinatorHandlerServer is the server API for CoordinatorHandler service.
 // All implementations must embed UnimplementedCoordinatorHandlerServer
 // for forward compatibility
@@ -110,6 +143,12 @@ type CoordinatorHandlerServer interface {
 	CheckReceipt(context.Context, *RoundInfo) (*Empty, error)
 	// Check that the final output messages are correct; used to time end of round
 	GetMessages(context.Context, *RoundInfo) (*ServerMessages, error)
+	// Collect the servers that were dropped, after a round
+	GetChurn(context.Context, *RoundInfo) (*ChurnReport, error)
+	// Signal servers to run the randomness beacon for the groups of an epoch
+	RunBeacon(context.Context, *BeaconTranscript) (*BeaconTranscript, error)
+	// Revoke clients or keys, between rounds
+	Revoke(context.Context, *Revocation) (*Empty, error)
 	mustEmbedUnimplementedCoordinatorHandlerServer()
 }
 
@@ -135,6 +174,15 @@ func (UnimplementedCoordinatorHandlerServer) CheckReceipt(context.Context, *Roun
 func (UnimplementedCoordinatorHandlerServer) GetMessages(context.Context, *RoundInfo) (*ServerMessages, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMessages not implemented")
 }
+func (UnimplementedCoordinatorHandlerServer) GetChurn(context.Context, *RoundInfo) (*ChurnReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChurn not implemented")
+}
+func (UnimplementedCoordinatorHandlerServer) RunBeacon(context.Context, *BeaconTranscript) (*BeaconTranscript, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunBeacon not implemented")
+}
+func (UnimplementedCoordinatorHandlerServer) Revoke(context.Context, *Revocation) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Revoke not implemented")
+}
 func (UnimplementedCoordinatorHandlerServer) mustEmbedUnimplementedCoordinatorHandlerServer() {}
 
 // UnsafeCoordinatorHandlerServer may be embedded to opt out of forward compatibility for this service.
@@ -256,6 +304,60 @@ func _CoordinatorHandler_GetMessages_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CoordinatorHandler_GetChurn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoundInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorHandlerServer).GetChurn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coord.CoordinatorHandler/GetChurn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorHandlerServer).GetChurn(ctx, req.(*RoundInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorHandler_RunBeacon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeaconTranscript)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorHandlerServer).RunBeacon(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coord.CoordinatorHandler/RunBeacon",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorHandlerServer).RunBeacon(ctx, req.(*BeaconTranscript))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorHandler_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Revocation)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorHandlerServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coord.CoordinatorHandler/Revoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorHandlerServer).Revoke(ctx, req.(*Revocation))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CoordinatorHandler_ServiceDesc is the grpc.ServiceDesc for CoordinatorHandler service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -287,6 +389,18 @@ var CoordinatorHandler_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMessages",
 			Handler:    _CoordinatorHandler_GetMessages_Handler,
 		},
+		{
+			MethodName: "GetChurn",
+			Handler:    _CoordinatorHandler_GetChurn_Handler,
+		},
+		{
+			MethodName: "RunBeacon",
+			Handler:    _CoordinatorHandler_RunBeacon_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _CoordinatorHandler_Revoke_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "coordinator.proto",
@@ -1,6 +1,7 @@
 package coordinator
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
@@ -14,9 +15,12 @@ import (
 	"github.com/simonlangowski/lightning1/client"
 	"github.com/simonlangowski/lightning1/config"
 	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/errors"
 	"github.com/simonlangowski/lightning1/network"
 	"github.com/simonlangowski/lightning1/network/messages"
 	"github.com/simonlangowski/lightning1/server"
+	"github.com/simonlangowski/lightning1/server/beacon"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -41,6 +45,8 @@ type CoordinatorNetwork struct {
 	remoteServers []coord.CoordinatorHandlerClient
 	remoteClients []coord.CoordinatorHandlerClient
 	processes     []*exec.Cmd
+	dropped       map[int64]bool // servers that missed a deadline are no longer contacted
+	droppedLock   sync.Mutex
 }
 
 func NewRemoteNetwork(serverFile, groupFile, clientsFile string) *CoordinatorNetwork {
@@ -219,6 +225,18 @@ func (c *CoordinatorNetwork) SetupInProcess(numServers int) {
 	}
 }
 
+// Keep the keys of in process servers in a directory for each server
+// (remote servers choose their own directories)
+func (c *CoordinatorNetwork) SetKeyDirectories(format string) error {
+	for i, s := range c.servers {
+		err := s.SetKeyDirectory(fmt.Sprintf(format, i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 /*
 	for id := i.StartId; id < i.EndId; id++ {
 		c.clients.AddClient(id)
@@ -241,7 +259,7 @@ func (c *CoordinatorNetwork) SetupInProcess(numServers int) {
 	for _, s := range c.servers {
 		s.Keys = make([]*processMessages.KeyLookupTable, i.NumLayers)
 		for layer := 0; layer < int(i.NumLayers); layer++ {
-			s.Keys[layer] = processMessages.NewKeyLookupTable(s.CommonState)
+			s.Keys[layer] = processMessages.NewKeyLookupTable(s.CommonState, layer)
 		}
 	}
 
@@ -273,43 +291,100 @@ func (c *CoordinatorNetwork) SetupInProcess(numServers int) {
 
 const retries = 3
 
-func (c *CoordinatorNetwork) SendKeys(keyMessages []map[int64]*coord.KeyInformation, publicKeys *coord.KeyInformation) error {
+// Signal the servers to exchange keys with each other
+// returns the public keys, which all servers must agree on
+func (c *CoordinatorNetwork) ExchangeKeys() (*coord.KeyInformation, error) {
+	type result struct {
+		keys *coord.KeyInformation
+		err  error
+	}
+	done := make(chan result)
+	for sid := range c.ServerConfigs {
+		go func(sid int64) {
+			ctx := context.Background()
+			var keys *coord.KeyInformation
+			var err error
+			if c.serverNetType == inprocess {
+				keys, err = c.servers[sid].KeySet(ctx, &coord.KeyInformation{})
+			} else {
+				keys, err = c.remoteServers[sid].KeySet(ctx, &coord.KeyInformation{})
+			}
+			if err != nil {
+				log.Printf("Key exchange, sid %d: %v", sid, err)
+			}
+			done <- result{keys, err}
+		}(sid)
+	}
+	var publicKeys *coord.KeyInformation
+	var err error
+	for range c.ServerConfigs {
+		r := <-done
+		if r.err != nil {
+			err = r.err
+			continue
+		}
+		// servers in no groups do not learn the keys
+		if len(r.keys.GetTokenPublicKey()) == 0 {
+			continue
+		}
+		if publicKeys == nil {
+			publicKeys = &coord.KeyInformation{
+				TokenPublicKey: r.keys.TokenPublicKey,
+				GroupKey:       r.keys.GroupKey,
+				TokenShareKeys: make(map[int64]*coord.ShareKeys),
+			}
+		} else if !bytes.Equal(publicKeys.TokenPublicKey, r.keys.TokenPublicKey) || !bytes.Equal(publicKeys.GroupKey, r.keys.GroupKey) {
+			err = errors.GroupAgreementError()
+		}
+		// each server only has the share keys of its groups
+		for gid, k := range r.keys.TokenShareKeys {
+			if publicKeys.TokenShareKeys[gid] == nil {
+				publicKeys.TokenShareKeys[gid] = k
+			} else if !proto.Equal(publicKeys.TokenShareKeys[gid], k) {
+				err = errors.GroupAgreementError()
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if publicKeys == nil || len(publicKeys.TokenShareKeys) != len(c.GroupConfigs) {
+		return nil, errors.KeyNotFound()
+	}
+	return publicKeys, nil
+}
+
+// Send the public keys from the key exchange to all servers and clients
+func (c *CoordinatorNetwork) SendKeys(publicKeys *coord.KeyInformation) error {
 	done := make(chan error)
-	for sid, keys := range keyMessages {
-		go func(sid int, keys map[int64]*coord.KeyInformation) {
+	for sid := range c.ServerConfigs {
+		go func(sid int64) {
+			var err error
 			for i := 0; i < retries; i++ {
 				ctx := context.Background()
-				if len(keys) == 0 {
-					// send public keys
-					keys[0] = publicKeys
-				}
-				ok := true
-				for _, k := range keys {
-					var err error
-					if c.serverNetType == inprocess {
-						_, err = c.servers[sid].KeySet(ctx, k)
-					} else {
-						_, err = c.remoteServers[sid].KeySet(ctx, k)
-					}
-					if err != nil {
-						log.Printf("Attempt %d, sid %d: %v", i, sid, err)
-						ok = false
-						break
-					}
+				if c.serverNetType == inprocess {
+					_, err = c.servers[sid].KeySet(ctx, publicKeys)
+				} else {
+					_, err = c.remoteServers[sid].KeySet(ctx, publicKeys)
 				}
-				if ok {
+				if err == nil {
 					break
 				}
+				log.Printf("Attempt %d, sid %d: %v", i, sid, err)
 			}
-			done <- nil
-		}(int(sid), keys)
+			done <- err
+		}(sid)
 	}
+	var err error
 	for range c.ServerConfigs {
-		err := <-done
-		if err != nil {
-			return err
+		e := <-done
+		if e != nil {
+			err = e
 		}
 	}
+	if err != nil {
+		return err
+	}
 	if c.clientNetType == inprocess {
 		ctx := context.Background()
 		_, err := c.clients.KeySet(ctx, publicKeys)
@@ -334,6 +409,48 @@ func (c *CoordinatorNetwork) SendKeys(keyMessages []map[int64]*coord.KeyInformat
 	return nil
 }
 
+// Run the randomness beacon on all servers, and check that they output the same transcript
+func (c *CoordinatorNetwork) RunBeacon(epoch, numGroups, groupSize int) (*coord.BeaconTranscript, error) {
+	request := &coord.BeaconTranscript{Epoch: int64(epoch), NumGroups: int64(numGroups), GroupSize: int64(groupSize)}
+	type result struct {
+		transcript *coord.BeaconTranscript
+		err        error
+	}
+	done := make(chan result)
+	for sid := range c.ServerConfigs {
+		go func(sid int64) {
+			ctx := context.Background()
+			var t *coord.BeaconTranscript
+			var err error
+			if c.serverNetType == inprocess {
+				t, err = c.servers[sid].RunBeacon(ctx, request)
+			} else {
+				t, err = c.remoteServers[sid].RunBeacon(ctx, request)
+			}
+			if err != nil {
+				log.Printf("Beacon, sid %d: %v", sid, err)
+			}
+			done <- result{t, err}
+		}(sid)
+	}
+	var transcript *coord.BeaconTranscript
+	var err error
+	for range c.ServerConfigs {
+		r := <-done
+		if r.err != nil {
+			err = r.err
+		} else if transcript == nil {
+			transcript = r.transcript
+		} else if !proto.Equal(transcript, r.transcript) {
+			err = errors.GroupAgreementError()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transcript, beacon.Verify(transcript, c.ServerConfigs)
+}
+
 func (c *CoordinatorNetwork) SendClientStart(i *coord.RoundInfo, numMessages int) error {
 	if c.clientNetType == inprocess {
 		ctx := context.Background()
@@ -414,7 +531,8 @@ func (c *CoordinatorNetwork) CheckClientReceipt(i *coord.RoundInfo, numMessages
 
 func (c *CoordinatorNetwork) SendRoundSetup(i *coord.RoundInfo) error {
 	done := make(chan error)
-	for idx := range c.ServerConfigs {
+	live := c.liveServers()
+	for _, idx := range live {
 		go func(idx int) {
 			ctx := context.Background()
 			var err error
@@ -426,7 +544,7 @@ func (c *CoordinatorNetwork) SendRoundSetup(i *coord.RoundInfo) error {
 			done <- err
 		}(int(idx))
 	}
-	for range c.ServerConfigs {
+	for range live {
 		err := <-done
 		if err != nil {
 			return err
@@ -437,7 +555,8 @@ func (c *CoordinatorNetwork) SendRoundSetup(i *coord.RoundInfo) error {
 
 func (c *CoordinatorNetwork) SendRoundStart(i *coord.RoundInfo) error {
 	done := make(chan error)
-	for idx := range c.ServerConfigs {
+	live := c.liveServers()
+	for _, idx := range live {
 		go func(idx int) {
 			var err error
 			ctx := context.Background()
@@ -449,7 +568,7 @@ func (c *CoordinatorNetwork) SendRoundStart(i *coord.RoundInfo) error {
 			done <- err
 		}(int(idx))
 	}
-	for range c.ServerConfigs {
+	for range live {
 		err := <-done
 		if err != nil {
 			return err
@@ -458,11 +577,38 @@ func (c *CoordinatorNetwork) SendRoundStart(i *coord.RoundInfo) error {
 	return nil
 }
 
+// Revoke clients or keys, between rounds
+func (c *CoordinatorNetwork) Revoke(r *coord.Revocation) error {
+	done := make(chan error)
+	live := c.liveServers()
+	for _, idx := range live {
+		go func(idx int) {
+			var err error
+			ctx := context.Background()
+			if c.serverNetType == inprocess {
+				_, err = c.servers[idx].Revoke(ctx, r)
+			} else {
+				_, err = c.remoteServers[idx].Revoke(ctx, r)
+			}
+			done <- err
+		}(int(idx))
+	}
+	var err error
+	for range live {
+		e := <-done
+		if e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
 func (c *CoordinatorNetwork) GetMessages(i *coord.RoundInfo) ([][]byte, error) {
 	responses := make([][]byte, 0)
 	mu := sync.Mutex{}
 	done := make(chan error)
-	for idx := range c.ServerConfigs {
+	live := c.liveServers()
+	for _, idx := range live {
 		go func(idx int) {
 			ctx := context.Background()
 			var messages *coord.ServerMessages
@@ -484,7 +630,7 @@ func (c *CoordinatorNetwork) GetMessages(i *coord.RoundInfo) ([][]byte, error) {
 			}
 		}(int(idx))
 	}
-	for range c.ServerConfigs {
+	for range live {
 		err := <-done
 		if err != nil {
 			return nil, err
@@ -493,6 +639,82 @@ func (c *CoordinatorNetwork) GetMessages(i *coord.RoundInfo) ([][]byte, error) {
 	return responses, nil
 }
 
+// Collect the servers that were dropped by any server, and stop contacting them
+// returns the servers that were newly dropped, and the number of messages dropped because their links overflowed
+func (c *CoordinatorNetwork) GetChurn(i *coord.RoundInfo) ([]int64, int64, error) {
+	type result struct {
+		report *coord.ChurnReport
+		err    error
+	}
+	done := make(chan result)
+	live := c.liveServers()
+	for _, idx := range live {
+		go func(idx int64) {
+			ctx := context.Background()
+			var r *coord.ChurnReport
+			var err error
+			if c.serverNetType == inprocess {
+				r, err = c.servers[idx].GetChurn(ctx, i)
+			} else {
+				r, err = c.remoteServers[idx].GetChurn(ctx, i)
+			}
+			done <- result{r, err}
+		}(idx)
+	}
+	silent := make([]int64, 0)
+	overflowed := int64(0)
+	var err error
+	for range live {
+		r := <-done
+		if r.err != nil {
+			err = r.err
+			continue
+		}
+		silent = append(silent, r.report.Silent...)
+		overflowed += r.report.Overflowed
+	}
+	return c.DropServers(silent), overflowed, err
+}
+
+// returns the servers that were not already dropped
+func (c *CoordinatorNetwork) DropServers(ids []int64) []int64 {
+	c.droppedLock.Lock()
+	defer c.droppedLock.Unlock()
+	if c.dropped == nil {
+		c.dropped = make(map[int64]bool)
+	}
+	newlyDropped := make([]int64, 0)
+	for _, sid := range ids {
+		if !c.dropped[sid] {
+			c.dropped[sid] = true
+			newlyDropped = append(newlyDropped, sid)
+		}
+	}
+	return newlyDropped
+}
+
+func (c *CoordinatorNetwork) DroppedServers() []int64 {
+	c.droppedLock.Lock()
+	defer c.droppedLock.Unlock()
+	ids := make([]int64, 0, len(c.dropped))
+	for sid := range c.dropped {
+		ids = append(ids, sid)
+	}
+	return ids
+}
+
+func (c *CoordinatorNetwork) liveServers() []int64 {
+	c.droppedLock.Lock()
+	defer c.droppedLock.Unlock()
+	live := make([]int64, 0, len(c.ServerConfigs))
+	for sid := range c.ServerConfigs {
+		if !c.dropped[sid] {
+			live = append(live, sid)
+		}
+	}
+	return live
+}
+
 func (c *CoordinatorNetwork) Connect(cfgs map[int64]*config.Server) []coord.CoordinatorHandlerClient {
 	conn, err := network.GetConnections(cfgs)
 	if err != nil {
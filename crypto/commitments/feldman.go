@@ -0,0 +1,94 @@
+package commitments
+
+import (
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/pairing"
+	"github.com/simonlangowski/lightning1/crypto/pairing/kyber_wrap"
+	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
+)
+
+/*
+Feldman commitments to shares of a secret
+For additive shares, the dealer publishes g^s_i for each share s_i, so that
+each share holder can check its own share, and anyone
+can check that the shares add up to the public key g^s
+For Shamir shares, the dealer publishes g^a_j for each coefficient a_j of the polynomial,
+so each share holder can check its share is on the polynomial, and g^a_0 is the public key
+
+Together with a hash commitment to the public key made before any
+public keys are revealed (Pedersen's DKG), no dealer can choose its
+key based on the keys of the other dealers
+*/
+
+// Commitments to shares of a group (diffie hellman) key
+type DHShareCommitments struct {
+	Shares []crypto.DHPublicKey
+}
+
+// Commitments to the polynomial of Shamir shares of a token (BLS) key
+type TokenShareCommitments struct {
+	Coefficients []mcl.G2
+}
+
+func CommitDHShares(shares []*crypto.DHPrivateKey) *DHShareCommitments {
+	f := &DHShareCommitments{
+		Shares: make([]crypto.DHPublicKey, len(shares)),
+	}
+	for i := range shares {
+		f.Shares[i] = *shares[i].PublicKey()
+	}
+	return f
+}
+
+func (f *DHShareCommitments) VerifyShare(i int, share *crypto.DHPrivateKey) bool {
+	if i < 0 || i >= len(f.Shares) {
+		return false
+	}
+	return share.PublicKey().Equals(&f.Shares[i])
+}
+
+// the public key the shares add up to
+func (f *DHShareCommitments) PublicKey() *crypto.DHPublicKey {
+	p := crypto.ZeroPoint()
+	for i := range f.Shares {
+		p.Accumulate(&f.Shares[i])
+	}
+	return p
+}
+
+func CommitTokenPolynomial(coefficients []mcl.Fr) *TokenShareCommitments {
+	f := &TokenShareCommitments{
+		Coefficients: make([]mcl.G2, len(coefficients)),
+	}
+	for i := range coefficients {
+		mcl.G2Mul(&f.Coefficients[i], &kyber_wrap.G2Generator, &coefficients[i])
+	}
+	return f
+}
+
+func (f *TokenShareCommitments) VerifyShare(i int, share *mcl.Fr) bool {
+	if i < 0 || len(f.Coefficients) == 0 {
+		return false
+	}
+	var p mcl.G2
+	mcl.G2Mul(&p, &kyber_wrap.G2Generator, share)
+	return p.IsEqual(f.ShareKey(i))
+}
+
+// the public key of share i
+func (f *TokenShareCommitments) ShareKey(i int) *mcl.G2 {
+	p := &mcl.G2{}
+	x := pairing.ShareIndex(i)
+	mcl.G2EvaluatePolynomial(p, f.Coefficients, &x)
+	return p
+}
+
+func (f *TokenShareCommitments) PublicKey() *mcl.G2 {
+	p := &mcl.G2{}
+	if len(f.Coefficients) == 0 {
+		p.Clear()
+	} else {
+		*p = f.Coefficients[0]
+	}
+	return p
+}
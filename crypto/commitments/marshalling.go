@@ -1,6 +1,10 @@
 package commitments
 
-import "github.com/simonlangowski/lightning1/errors"
+import (
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
+	"github.com/simonlangowski/lightning1/errors"
+)
 
 func (c *Commitment) Len() int {
 	return COMMIT_SIZE
@@ -39,3 +43,58 @@ func (o *CommitmentOpening) InterpretFrom(b []byte) error {
 	copy(o.nonce[:], b)
 	return nil
 }
+
+func (f *DHShareCommitments) Len() int {
+	return len(f.Shares) * crypto.POINT_SIZE
+}
+
+func (f *DHShareCommitments) PackTo(b []byte) {
+	if len(b) != f.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	for i := range f.Shares {
+		f.Shares[i].PackTo(b[i*crypto.POINT_SIZE : (i+1)*crypto.POINT_SIZE])
+	}
+}
+
+// the number of shares is determined by the length
+func (f *DHShareCommitments) InterpretFrom(b []byte) error {
+	if len(b)%crypto.POINT_SIZE != 0 {
+		return errors.LengthInvalidError()
+	}
+	f.Shares = make([]crypto.DHPublicKey, len(b)/crypto.POINT_SIZE)
+	for i := range f.Shares {
+		err := f.Shares[i].InterpretFrom(b[i*crypto.POINT_SIZE : (i+1)*crypto.POINT_SIZE])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *TokenShareCommitments) Len() int {
+	return len(f.Coefficients) * mcl.G2_LEN
+}
+
+func (f *TokenShareCommitments) PackTo(b []byte) {
+	if len(b) != f.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	for i := range f.Coefficients {
+		f.Coefficients[i].PackTo(b[i*mcl.G2_LEN : (i+1)*mcl.G2_LEN])
+	}
+}
+
+func (f *TokenShareCommitments) InterpretFrom(b []byte) error {
+	if len(b)%mcl.G2_LEN != 0 {
+		return errors.LengthInvalidError()
+	}
+	f.Coefficients = make([]mcl.G2, len(b)/mcl.G2_LEN)
+	for i := range f.Coefficients {
+		err := f.Coefficients[i].InterpretFrom(b[i*mcl.G2_LEN : (i+1)*mcl.G2_LEN])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
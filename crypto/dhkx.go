@@ -11,6 +11,7 @@ const KEY_SIZE = VERIFICATION_KEY_SIZE
 const POINT_SIZE = 32
 const SCALAR_SIZE = 32
 const PK_SIZE = POINT_SIZE
+const HASH_SIZE = 32 // sha256
 
 type DHPublicKey struct {
 	*edwards25519.Point
@@ -98,6 +99,12 @@ func (d *DHPublicKey) Accumulate(o *DHPublicKey) *DHPublicKey {
 	return d
 }
 
+func ZeroScalar() *DHPrivateKey {
+	return &DHPrivateKey{
+		Scalar: edwards25519.NewScalar(),
+	}
+}
+
 func ZeroPoint() *DHPublicKey {
 	return &DHPublicKey{
 		Point: edwards25519.NewIdentityPoint(),
@@ -40,15 +40,33 @@ import (
 // }
 
 func PreHashSign(m []byte, key SigningKey) Signature {
+	return SignDigest(BatchDigest(m), key)
+}
+
+// The digest a batch between servers is signed under
+// the receiver keeps the digest and signature as evidence of what the sender sent
+func BatchDigest(m []byte) []byte {
 	h := sha512.Sum512(m)
-	s, err := ed25519.PrivateKey(key).Sign(rand.Reader, h[:], crypto.SHA512)
+	return h[:]
+}
+
+func SignDigest(digest []byte, key SigningKey) Signature {
+	s, err := ed25519.PrivateKey(key).Sign(rand.Reader, digest, crypto.SHA512)
 	if err != nil {
 		panic(err)
 	}
-	errors.DebugPrint("Signature %v on %v %v with %v", s, m[:NONCE_SIZE], h, key)
+	errors.DebugPrint("Signature %v on %v with %v", s, digest, key)
 	return s
 }
 
+// check a signature of PreHashSign, given the digest of the batch
+func VerifyDigest(vk VerificationKey, digest []byte, s Signature) bool {
+	op := &ed25519.Options{
+		Hash: crypto.SHA512,
+	}
+	return ed25519.VerifyWithOptions(ed25519.PublicKey(vk), digest, s, op)
+}
+
 func PreHashVerify(h hash.Hash, vk *ExpandedVerificationKey, s Signature) bool {
 	op := &ed25519.Options{
 		Hash: crypto.SHA512,
@@ -0,0 +1,107 @@
+package nizk
+
+import (
+	"crypto/sha512"
+
+	"filippo.io/edwards25519"
+	"github.com/simonlangowski/lightning1/crypto"
+)
+
+// The same proof of discrete logarithm equality on edwards25519, for the diffie hellman keys in crypto
+// It shows that Z = xM for the secret x of the public key H = xG, without revealing x
+// so a server can show that it derived the shared key Z of a client key M with its secret key
+// The commitments are sent instead of the challenge, so that many proofs can be checked together
+type DecryptionProof struct {
+	A crypto.DHPublicKey  // sG
+	B crypto.DHPublicKey  // sM
+	R crypto.DHPrivateKey // s - cx
+}
+
+// Decrypt the client key with the secret key, and prove it was done correctly
+// returns the proof and the shared point, whose bytes are the shared key
+func NewDecryptionProof(clientPublicKey *crypto.DHPublicKey, secretKey *crypto.DHPrivateKey) (*DecryptionProof, *crypto.DHPublicKey) {
+	Z := secretKey.Mul(clientPublicKey)
+	s := crypto.RandomCurveScalar()
+	p := &DecryptionProof{
+		A: *s.PublicKey(),
+		B: s.Mul(clientPublicKey),
+	}
+	c := decryptionChallenge(secretKey.PublicKey(), clientPublicKey, &Z, &p.A, &p.B)
+	r := edwards25519.NewScalar().Multiply(c, secretKey.Scalar)
+	p.R = crypto.DHPrivateKey{Scalar: r.Subtract(s.Scalar, r)}
+	return p, &Z
+}
+
+// Check that shared is the decryption of the client key with the secret key of the public key
+func (p *DecryptionProof) Verify(clientPublicKey, publicKey, shared *crypto.DHPublicKey) bool {
+	if p.A.Point == nil || p.B.Point == nil || p.R.Scalar == nil {
+		return false
+	}
+	c := decryptionChallenge(publicKey, clientPublicKey, shared, &p.A, &p.B)
+	// rG + cH = sG
+	A := edwards25519.NewIdentityPoint().VarTimeDoubleScalarBaseMult(c, publicKey.Point, p.R.Scalar)
+	if A.Equal(p.A.Point) != 1 {
+		return false
+	}
+	// rM + cZ = sM
+	B := edwards25519.NewIdentityPoint().ScalarMult(p.R.Scalar, clientPublicKey.Point)
+	B.Add(B, edwards25519.NewIdentityPoint().ScalarMult(c, shared.Point))
+	return B.Equal(p.B.Point) == 1
+}
+
+// c = H(H, M, Z, A, B)
+func decryptionChallenge(H, M, Z, A, B *crypto.DHPublicKey) *edwards25519.Scalar {
+	h := sha512.New()
+	for _, p := range []*crypto.DHPublicKey{H, M, Z, A, B} {
+		h.Write(p.Bytes())
+	}
+	c, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Check many decryptions with the same secret key together, e.g. all the responses of one anytrust group member
+// a random linear combination of the proofs is checked, so this fails if any one of them would
+func BatchVerifyDecryptions(publicKey *crypto.DHPublicKey, clientPublicKeys, shared []*crypto.DHPublicKey, proofs []*DecryptionProof) bool {
+	n := len(proofs)
+	if len(clientPublicKeys) != n || len(shared) != n {
+		return false
+	} else if n == 0 {
+		return true
+	}
+	// sum(w r) G + sum(w c) H - sum(w A) = 0
+	// sum(w r M) + sum(w c Z) - sum(w B) = 0
+	rSum := edwards25519.NewScalar()
+	cSum := edwards25519.NewScalar()
+	aScalars := make([]*edwards25519.Scalar, 0, n+1)
+	aPoints := make([]*edwards25519.Point, 0, n+1)
+	bScalars := make([]*edwards25519.Scalar, 0, 3*n)
+	bPoints := make([]*edwards25519.Point, 0, 3*n)
+	for i, p := range proofs {
+		if p == nil || p.A.Point == nil || p.B.Point == nil || p.R.Scalar == nil {
+			return false
+		}
+		w := crypto.RandomCurveScalar().Scalar
+		c := decryptionChallenge(publicKey, clientPublicKeys[i], shared[i], &p.A, &p.B)
+		wr := edwards25519.NewScalar().Multiply(w, p.R.Scalar)
+		wc := edwards25519.NewScalar().Multiply(w, c)
+		negW := edwards25519.NewScalar().Negate(w)
+		rSum.Add(rSum, wr)
+		cSum.Add(cSum, wc)
+		aScalars = append(aScalars, negW)
+		aPoints = append(aPoints, p.A.Point)
+		bScalars = append(bScalars, wr, wc, negW)
+		bPoints = append(bPoints, clientPublicKeys[i].Point, shared[i].Point, p.B.Point)
+	}
+	aScalars = append(aScalars, cSum)
+	aPoints = append(aPoints, publicKey.Point)
+	A := edwards25519.NewIdentityPoint().VarTimeMultiScalarMult(aScalars, aPoints)
+	A.Add(A, edwards25519.NewIdentityPoint().ScalarBaseMult(rSum))
+	if A.Equal(edwards25519.NewIdentityPoint()) != 1 {
+		return false
+	}
+	B := edwards25519.NewIdentityPoint().VarTimeMultiScalarMult(bScalars, bPoints)
+	return B.Equal(edwards25519.NewIdentityPoint()) == 1
+}
@@ -0,0 +1,70 @@
+package nizk
+
+import (
+	"testing"
+
+	"github.com/simonlangowski/lightning1/crypto"
+)
+
+func TestDecryptionProof(t *testing.T) {
+	secret, public := crypto.NewDHKeyPair()
+	_, client := crypto.NewDHKeyPair()
+	proof, shared := NewDecryptionProof(&client, &secret)
+	expected := secret.SharedKey(&client)
+	if string(shared.AsShared()) != string(expected) {
+		t.Fatalf("Wrong shared key")
+	}
+	if !proof.Verify(&client, &public, shared) {
+		t.Fatalf("Proof was invalid")
+	}
+
+	b := make([]byte, proof.Len())
+	proof.PackTo(b)
+	received := &DecryptionProof{}
+	err := received.InterpretFrom(b)
+	if err != nil || !received.Verify(&client, &public, shared) {
+		t.Fatalf("Proof did not survive marshalling")
+	}
+
+	// a different shared key, or a different server key
+	_, other := crypto.NewDHKeyPair()
+	if proof.Verify(&client, &public, &other) {
+		t.Fatalf("Validated a wrong shared key")
+	}
+	if proof.Verify(&client, &other, shared) {
+		t.Fatalf("Validated the proof of another server")
+	}
+	_, wrong := NewDecryptionProof(&client, crypto.RandomCurveScalar())
+	if proof.Verify(&client, &public, wrong) {
+		t.Fatalf("Validated a decryption with another key")
+	}
+}
+
+func TestBatchVerifyDecryptions(t *testing.T) {
+	n := 10
+	secret, public := crypto.NewDHKeyPair()
+	clients := make([]*crypto.DHPublicKey, n)
+	shared := make([]*crypto.DHPublicKey, n)
+	proofs := make([]*DecryptionProof, n)
+	for i := range proofs {
+		_, client := crypto.NewDHKeyPair()
+		clients[i] = &client
+		proofs[i], shared[i] = NewDecryptionProof(&client, &secret)
+	}
+	if !BatchVerifyDecryptions(&public, clients, shared, proofs) {
+		t.Fatalf("Valid proofs failed in batch")
+	}
+	// one wrong decryption fails the batch
+	_, wrong := NewDecryptionProof(clients[3], crypto.RandomCurveScalar())
+	correct := shared[3]
+	shared[3] = wrong
+	if BatchVerifyDecryptions(&public, clients, shared, proofs) {
+		t.Fatalf("Validated a batch with a wrong shared key")
+	}
+	shared[3] = correct
+	// as does a proof for another client key
+	proofs[3], proofs[4] = proofs[4], proofs[3]
+	if BatchVerifyDecryptions(&public, clients, shared, proofs) {
+		t.Fatalf("Validated a batch with swapped proofs")
+	}
+}
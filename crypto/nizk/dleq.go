@@ -18,7 +18,6 @@ import (
 	"errors"
 	"math/big"
 
-	"github.com/simonlangowski/lightning1/crypto"
 	"github.com/simonlangowski/lightning1/crypto/ec"
 )
 
@@ -33,11 +32,6 @@ type DLEQProof struct {
 
 }
 
-func NewDecryptionProof(clientPublicKey crypto.DHPublicKey, serverPublicKey crypto.DHPublicKey, sharedKey crypto.DHSharedKey) (*DLEQProof, error) {
-
-	return nil, nil
-}
-
 // Given g, h, m, z such that g, m are generators and h = g^x, z = m^x,
 // compute a proof that log_g(h) == log_m(z). If (g, h, m, z) are already known
 // to the verifier, then (c, r) is sufficient to check the proof.
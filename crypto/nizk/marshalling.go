@@ -9,6 +9,8 @@ import (
 var DL_SIZE = ec.CurveElementSize + ec.ScalarElementSize + crypto.HASH_SIZE
 var DLEQ_SIZE = ec.CurveElementSize + ec.ScalarElementSize + crypto.HASH_SIZE
 
+const DECRYPTION_PROOF_SIZE = 2*crypto.POINT_SIZE + crypto.SCALAR_SIZE
+
 func (d *DLEQProof) Len() int {
 	return ec.ScalarElementSize + crypto.HASH_SIZE
 }
@@ -66,3 +68,31 @@ func (d *DLProof) Marshal() []byte {
 	d.PackTo(b)
 	return b
 }
+
+func (d *DecryptionProof) Len() int {
+	return DECRYPTION_PROOF_SIZE
+}
+
+func (d *DecryptionProof) PackTo(b []byte) {
+	if len(b) != d.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	d.A.PackTo(b[:crypto.POINT_SIZE])
+	d.B.PackTo(b[crypto.POINT_SIZE : 2*crypto.POINT_SIZE])
+	copy(b[2*crypto.POINT_SIZE:], d.R.Bytes())
+}
+
+func (d *DecryptionProof) InterpretFrom(b []byte) error {
+	if len(b) != d.Len() {
+		return errors.LengthInvalidError()
+	}
+	err := d.A.InterpretFrom(b[:crypto.POINT_SIZE])
+	if err != nil {
+		return err
+	}
+	err = d.B.InterpretFrom(b[crypto.POINT_SIZE : 2*crypto.POINT_SIZE])
+	if err != nil {
+		return err
+	}
+	return d.R.InterpretFrom(b[2*crypto.POINT_SIZE:])
+}
@@ -50,3 +50,26 @@ func AdditiveShares(secret *mcl.Fr, numShares int) []mcl.Fr {
 	}
 	return signingShares
 }
+
+// Shamir shares of secret: any threshold of the shares recover it
+// returns the shares and the coefficients of the polynomial, with the secret as the constant term
+func ShamirShares(secret *mcl.Fr, numShares, threshold int) ([]mcl.Fr, []mcl.Fr) {
+	coefficients := make([]mcl.Fr, threshold)
+	coefficients[0] = *secret
+	for i := 1; i < threshold; i++ {
+		coefficients[i].Random()
+	}
+	shares := make([]mcl.Fr, numShares)
+	for i := range shares {
+		x := ShareIndex(i)
+		mcl.FrEvaluatePolynomial(&shares[i], coefficients, &x)
+	}
+	return shares, coefficients
+}
+
+// the point where share i is evaluated (the secret is at 0)
+func ShareIndex(i int) mcl.Fr {
+	var x mcl.Fr
+	x.SetInt64(int64(i + 1))
+	return x
+}
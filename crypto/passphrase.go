@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/simonlangowski/lightning1/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encryption of data at rest, such as a client's keys, under a key derived from a passphrase
+// The file is the version, the salt of the key derivation, the nonce, and the AES-GCM ciphertext
+
+const PASSPHRASE_VERSION = 1
+const PASSPHRASE_SALT_SIZE = 16
+const passphraseHeaderSize = 4 + PASSPHRASE_SALT_SIZE
+
+// scrypt parameters, so that guessing passphrases is slow
+const scryptN = 1 << 15
+const scryptR = 8
+const scryptP = 1
+
+func passphraseKey(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func PassphraseSeal(plaintext, passphrase []byte) ([]byte, error) {
+	header := make([]byte, passphraseHeaderSize)
+	binary.LittleEndian.PutUint32(header[:4], PASSPHRASE_VERSION)
+	_, err := rand.Read(header[4:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := passphraseKey(passphrase, header[4:])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	box := append(header, nonce...)
+	// the header is authenticated with the ciphertext
+	return aead.Seal(box, nonce, plaintext, header), nil
+}
+
+func PassphraseOpen(box, passphrase []byte) ([]byte, error) {
+	if len(box) < passphraseHeaderSize {
+		return nil, errors.LengthInvalidError()
+	}
+	header := box[:passphraseHeaderSize]
+	if binary.LittleEndian.Uint32(header[:4]) != PASSPHRASE_VERSION {
+		return nil, errors.VersionError()
+	}
+	aead, err := passphraseKey(passphrase, header[4:])
+	if err != nil {
+		return nil, err
+	}
+	if len(box) < passphraseHeaderSize+aead.NonceSize()+aead.Overhead() {
+		return nil, errors.LengthInvalidError()
+	}
+	nonce := box[passphraseHeaderSize : passphraseHeaderSize+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, box[passphraseHeaderSize+aead.NonceSize():], header)
+	if err != nil {
+		return nil, errors.WrongPassphrase()
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+
+	"filippo.io/edwards25519"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+/*
+Per round ratchet of the keys of an established path
+
+- A path is established once and used in every later lightning round
+- Each round the client and the server holding a key derive a scalar from their shared key and the round,
+  and the verification key is multiplied by it, so that messages are signed under a different key each round
+- The client signs with its secret scalar multiplied by the same scalar, which are still ed25519 signatures
+- Only the two of them know the scalar, so anyone else seeing the keys of two rounds cannot link them
+- The exception is the anonymous key of the last layer, which the last server proves is the ratchet of the key of the path,
+  so the trustees of the group the path ends at can link its rounds, but cannot accept keys the last server made up
+- Lookup keys are hashed with the round under the secret of their link, the key the client shares with the first server
+  or the key the two servers of a link share, so that both ends of a link find the same entry in each round,
+  and anyone else who saw the lookup key of the path cannot find it in later rounds
+- Boomerang messages return under the lookup keys of their round in the same way
+*/
+
+// The signer of the envelopes of one layer
+type Signer interface {
+	Sign(message []byte) Signature
+}
+
+func (k SigningKey) Sign(message []byte) Signature {
+	return Sign(k, message)
+}
+
+// A signing key multiplied by the scalar of a round
+type RatchetedSigningKey struct {
+	secret *edwards25519.Scalar
+	prefix []byte // for the deterministic nonces, as in ed25519
+	public VerificationKey
+}
+
+// The scalar of the round, from a shared key of the path
+func RatchetScalar(shared DHSharedKey, round int) *DHPrivateKey {
+	h := sha512.New()
+	h.Write([]byte("ratchet"))
+	h.Write(shared)
+	binary.Write(h, binary.LittleEndian, uint64(round))
+	s, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		panic(err)
+	}
+	return &DHPrivateKey{s}
+}
+
+// The lookup key of the round, which both ends of a link find from the key of the path and the secret of the link
+func RatchetLookup(link DHSharedKey, key LookupKey, round int) LookupKey {
+	h := hmac.New(sha256.New, link)
+	h.Write([]byte("lookup"))
+	h.Write(key[:])
+	binary.Write(h, binary.LittleEndian, uint64(round))
+	l := LookupKey{}
+	copy(l[:], h.Sum(nil))
+	return l
+}
+
+func (k *VerificationKey) Ratchet(r *DHPrivateKey) (VerificationKey, error) {
+	pt, err := k.ToCurvePoint()
+	if err != nil {
+		return nil, err
+	}
+	p := r.Mul(pt)
+	return VerificationKey(p.Bytes()), nil
+}
+
+func (k *SigningKey) Ratchet(r *DHPrivateKey) *RatchetedSigningKey {
+	h := sha512.Sum512((*ed25519.PrivateKey)(k).Seed())
+	s, err := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	if err != nil {
+		panic(err)
+	}
+	s.Multiply(s, r.Scalar)
+	prefix := sha512.Sum512(append(h[32:], r.Bytes()...))
+	return &RatchetedSigningKey{
+		secret: s,
+		prefix: prefix[:32],
+		public: VerificationKey(edwards25519.NewIdentityPoint().ScalarBaseMult(s).Bytes()),
+	}
+}
+
+func (k *RatchetedSigningKey) VerificationKey() VerificationKey {
+	return k.public
+}
+
+// an ed25519 signature under the ratcheted key
+func (k *RatchetedSigningKey) Sign(message []byte) Signature {
+	h := sha512.New()
+	h.Write(k.prefix)
+	h.Write(message)
+	r, _ := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	R := edwards25519.NewIdentityPoint().ScalarBaseMult(r).Bytes()
+	h.Reset()
+	h.Write(R)
+	h.Write(k.public)
+	h.Write(message)
+	c, _ := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	S := edwards25519.NewScalar().MultiplyAdd(c, k.secret, r)
+	return append(R, S.Bytes()...)
+}
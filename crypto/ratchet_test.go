@@ -0,0 +1,40 @@
+package crypto
+
+import "testing"
+
+func TestRatchet(t *testing.T) {
+	pk, sk := NewSigningKeyPair()
+	secret, _ := sk.ToScalar()
+	server, serverKey := NewDHKeyPair()
+	m := []byte("lightning")
+	keys := make(map[string]bool)
+	for round := 0; round < 3; round++ {
+		// the client and the server find the same key from their shared key
+		r := RatchetScalar(secret.SharedKey(&serverKey), round)
+		signer := sk.Ratchet(r)
+		pt, _ := pk.ToCurvePoint()
+		ratcheted, err := pk.Ratchet(RatchetScalar(server.SharedKey(pt), round))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(ratcheted) != string(signer.VerificationKey()) {
+			t.Fatalf("Keys of round %d differ", round)
+		}
+		if !Verify(ratcheted, m, signer.Sign(m)) {
+			t.Fatalf("Signature of round %d does not verify", round)
+		}
+		if Verify(pk, m, signer.Sign(m)) {
+			t.Fatalf("Signature of round %d verifies under the key of the path", round)
+		}
+		link := server.SharedKey(pt)
+		if keys[string(ratcheted)] || RatchetLookup(link, pk.LookupKey(), round) == RatchetLookup(link, pk.LookupKey(), round+1) {
+			t.Fatalf("Keys of round %d repeat", round)
+		}
+		// only the ends of the link can find the lookup key of the round
+		other, _ := NewDHKeyPair()
+		if RatchetLookup(link, pk.LookupKey(), round) == RatchetLookup(other.SharedKey(pt), pk.LookupKey(), round) {
+			t.Fatalf("Lookup key of round %d found without the link", round)
+		}
+		keys[string(ratcheted)] = true
+	}
+}
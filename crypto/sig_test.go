@@ -51,6 +51,56 @@ func BenchmarkVerify(b *testing.B) {
 	}
 }
 
+func TestVerifyBatch(t *testing.T) {
+	b := BatchVerifier(10)
+	for i := 0; i < 10; i++ {
+		spk, ssk := NewSigningKeyPair()
+		m := make([]byte, 100)
+		m[0] = byte(i)
+		s := Sign(ssk, m)
+		if i == 3 {
+			m[0] = 100
+		}
+		if i%2 == 0 {
+			epk, _ := spk.ExpandKey()
+			AddExpandedToBatch(b, epk, m, s)
+		} else {
+			AddToBatch(b, spk, m, s)
+		}
+	}
+	valid := VerifyBatch(b)
+	for i := range valid {
+		if valid[i] != (i != 3) {
+			t.Fatalf("Signature %d not checked", i)
+		}
+	}
+}
+
+// compare to BenchmarkExpandedKeys for the gain of batches of config.BatchSize
+func BenchmarkVerifyBatch(b *testing.B) {
+	batchSize := 64
+	epks := make([]*ExpandedVerificationKey, batchSize)
+	sigs := make([]Signature, batchSize)
+	m := make([]byte, 1000)
+	for i := range epks {
+		spk, ssk := NewSigningKeyPair()
+		epks[i], _ = spk.ExpandKey()
+		sigs[i] = Sign(ssk, m)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i += batchSize {
+		v := BatchVerifier(batchSize)
+		for j := range epks {
+			AddExpandedToBatch(v, epks[j], m, sigs[j])
+		}
+		for _, ok := range VerifyBatch(v) {
+			if !ok {
+				b.FailNow()
+			}
+		}
+	}
+}
+
 func BenchmarkAES(b *testing.B) {
 	sk, pk := NewDHKeyPair()
 	shk := sk.SharedKey(&pk)
@@ -43,7 +43,7 @@ func PackSignedData(round, layer, server int, raw []byte, offset int) []byte {
 
 // AES encryption with the symmetric key
 // Encrypt-then-sign non repudiable encryption
-func SignedSecretSeal(message []byte, nonce *[NONCE_SIZE]byte, key DHSharedKey, signingKey SigningKey) []byte {
+func SignedSecretSeal(message []byte, nonce *[NONCE_SIZE]byte, key DHSharedKey, signingKey Signer) []byte {
 	// keys := hkdf.New(sha256.New, key, (*nonce)[:], nil)
 	// aesKey := make([]byte, SymmetricKeySize)
 
@@ -68,7 +68,7 @@ func SignedSecretSeal(message []byte, nonce *[NONCE_SIZE]byte, key DHSharedKey,
 
 	// Sign nonce and ciphertext!
 	copy(out[:NONCE_SIZE], nonce[:])
-	s := Sign(signingKey, out[:len(out)-Overhead])
+	s := signingKey.Sign(out[:len(out)-Overhead])
 	copy(out[len(out)-Overhead:], s)
 	// Nonce here can be filled in by the verifying machine, and not included
 	errors.DebugPrint("Encrypted %v %v: %v to %v", nonce, key, message, out[NONCE_SIZE:len(out)-Overhead])
@@ -17,6 +17,7 @@ type Signature []byte
 
 const SIGNATURE_SIZE = ed25519.SignatureSize
 const VERIFICATION_KEY_SIZE = ed25519.PublicKeySize
+const SIGNING_KEY_SIZE = ed25519.PrivateKeySize
 
 /*
 We use ed25519 for signing
@@ -48,6 +49,21 @@ func BatchVerifier(size int) *ed25519.BatchVerifier {
 	return ed25519.NewBatchVerifierWithCapacity(size)
 }
 
+func AddToBatch(b *ed25519.BatchVerifier, k VerificationKey, m []byte, s Signature) {
+	b.Add(ed25519.PublicKey(k), m, s)
+}
+
+func AddExpandedToBatch(b *ed25519.BatchVerifier, v *ExpandedVerificationKey, m []byte, s Signature) {
+	b.AddExpanded((*ed25519.ExpandedPublicKey)(v), m, s)
+}
+
+// whether each signature in the batch is valid
+// the signatures are only checked one at a time if the batch fails
+func VerifyBatch(b *ed25519.BatchVerifier) []bool {
+	_, valid := b.Verify(rand.Reader)
+	return valid
+}
+
 func Verify(k VerificationKey, m []byte, s Signature) bool {
 	return ed25519.Verify(ed25519.PublicKey(k), m, s)
 }
@@ -112,6 +128,10 @@ func (p *SigningKey) PublicKey() crypto.PublicKey {
 	return privateKey.Public()
 }
 
+func (p *SigningKey) VerificationKey() VerificationKey {
+	return VerificationKey((*ed25519.PrivateKey)(p).Public().(ed25519.PublicKey))
+}
+
 func (p *VerificationKey) ToCurvePoint() (*DHPublicKey, error) {
 	pk := (*ed25519.PublicKey)(p)
 	pt, err := edwards25519.NewIdentityPoint().SetBytes(*pk)
@@ -0,0 +1,78 @@
+package token
+
+import (
+	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
+)
+
+/*
+Batch verification of tokens signed under the same key
+For random r_i, the tokens are all valid (with high probability) if
+	e(sum r_i * token_i, Q) = e(sum r_i * H(m_i), X)
+which is one pairing check instead of one for each token
+(https://eprint.iacr.org/2007/172.pdf)
+*/
+
+type BatchVerifier struct {
+	key    *TokenPublicKey
+	tokens []mcl.G1
+	hashes []mcl.G1
+}
+
+func NewBatchVerifier(key *TokenPublicKey, size int) *BatchVerifier {
+	return &BatchVerifier{
+		key:    key,
+		tokens: make([]mcl.G1, 0, size),
+		hashes: make([]mcl.G1, 0, size),
+	}
+}
+
+// returns the index of the token in the batch
+func (b *BatchVerifier) Add(token *SignedToken, message []byte) int {
+	var hash mcl.G1
+	b.key.hashToCurvePoint(message, &hash)
+	b.tokens = append(b.tokens, token.T)
+	b.hashes = append(b.hashes, hash)
+	return len(b.tokens) - 1
+}
+
+func (b *BatchVerifier) Len() int {
+	return len(b.tokens)
+}
+
+// whether each token in the batch is valid
+// if the batch does not verify, each token is checked separately
+func (b *BatchVerifier) Verify() []bool {
+	valid := make([]bool, len(b.tokens))
+	if len(b.tokens) == 0 {
+		return valid
+	}
+	ok := true
+	for i := range b.tokens {
+		// the random combination only checks tokens in the right group
+		if !b.tokens[i].IsValidOrder() {
+			ok = false
+			break
+		}
+	}
+	if ok && b.verifyCombination() {
+		for i := range valid {
+			valid[i] = true
+		}
+		return valid
+	}
+	for i := range valid {
+		valid[i] = b.key.verify(&b.tokens[i], &b.hashes[i])
+	}
+	return valid
+}
+
+func (b *BatchVerifier) verifyCombination() bool {
+	coefficients := make([]mcl.Fr, len(b.tokens))
+	for i := range coefficients {
+		coefficients[i].Random()
+	}
+	var token, hash mcl.G1
+	mcl.G1MulVec(&token, b.tokens, coefficients)
+	mcl.G1MulVec(&hash, b.hashes, coefficients)
+	return b.key.precompute.PrecomputedPairingCheck(&token, &hash)
+}
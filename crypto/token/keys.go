@@ -37,9 +37,21 @@ func KeyGenShares(numShares int) ([]*TokenSigningKey, *TokenPublicKey, *TokenSig
 	return MockKeyGen(numShares, s)
 }
 
+// any threshold of the partial signing keys can make tokens
+func KeyGenThresholdShares(numShares, threshold int) ([]*TokenSigningKey, *TokenPublicKey, *TokenSigningKey) {
+	s := &mcl.Fr{}
+	s.Random()
+	return ThresholdKeyGen(numShares, threshold, s)
+}
+
 func MockKeyGen(numShares int, secret *mcl.Fr) ([]*TokenSigningKey, *TokenPublicKey, *TokenSigningKey) {
+	return ThresholdKeyGen(numShares, numShares, secret)
+}
+
+// Shamir shares of the secret; the public key of each share is X of its partial signing key
+func ThresholdKeyGen(numShares, threshold int, secret *mcl.Fr) ([]*TokenSigningKey, *TokenPublicKey, *TokenSigningKey) {
 	masterSigningKey := NewTokenSigningKey(secret)
-	shares := pairing.AdditiveShares(secret, numShares)
+	shares, _ := pairing.ShamirShares(secret, numShares, threshold)
 	partialSigningKeys := make([]*TokenSigningKey, numShares)
 	publicKey := NewTokenPublicKey(&masterSigningKey.X)
 	for i := range partialSigningKeys {
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 
 	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/crypto/pairing"
 	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
 	"github.com/simonlangowski/lightning1/errors"
 )
@@ -15,9 +16,10 @@ type SignedToken struct {
 }
 
 type TokenIssuanceInformation struct {
-	key      *TokenPublicKey
-	hash     mcl.G1
-	blinding mcl.Fr
+	key         *TokenPublicKey
+	hash        mcl.G1
+	blindedHash mcl.G1
+	blinding    mcl.Fr
 }
 
 func (t *TokenPublicKey) Prepare(message []byte) (*mcl.G1, *TokenIssuanceInformation) {
@@ -26,6 +28,7 @@ func (t *TokenPublicKey) Prepare(message []byte) (*mcl.G1, *TokenIssuanceInforma
 	t.hashToCurvePoint(message, &info.hash)
 	info.blinding.Random()
 	t.blind(blindedHash, &info.hash, &info.blinding)
+	info.blindedHash = *blindedHash
 	return blindedHash, info
 }
 
@@ -42,18 +45,50 @@ func (t *TokenSigningKey) BlindSign(out *mcl.G1, blindedHash *mcl.G1) error {
 	return nil
 }
 
-func (info *TokenIssuanceInformation) Create(partials []mcl.G1) (*SignedToken, error) {
+// partials are signed by the members of the group at positions
+func (info *TokenIssuanceInformation) Create(partials []mcl.G1, positions []int) (*SignedToken, error) {
+	token, ok := info.TryCreate(partials, positions)
+	if !ok {
+		// BadPartials finds the servers to blame
+		return nil, errors.TokenInvalid()
+	}
+	return token, nil
+}
+
+// Create a token if the partials are enough, otherwise more partials can be tried
+func (info *TokenIssuanceInformation) TryCreate(partials []mcl.G1, positions []int) (*SignedToken, bool) {
+	if info.key == nil {
+		// already created
+		return nil, false
+	}
 	token := &SignedToken{}
 	t := info.key
-	t.combine(partials, &token.T)
-	t.unblind(&token.T, &info.blinding)
+	if !t.combine(partials, positions, &token.T) {
+		return nil, false
+	}
+	blinding := info.blinding
+	t.unblind(&token.T, &blinding)
 	if !t.verify(&token.T, &info.hash) {
-		// Can check pairings of each message with public key shares to blame server
-		return nil, errors.TokenInvalid()
-	} else {
-		info.key = nil
-		return token, nil
+		return nil, false
+	}
+	info.key = nil
+	return token, true
+}
+
+// check a partial signature with the public key of the share that made it
+func (info *TokenIssuanceInformation) VerifyPartial(shareKey *TokenPublicKey, partial *mcl.G1) bool {
+	return shareKey.verify(partial, &info.blindedHash)
+}
+
+// the indices of the partials that do not verify with the share keys at their positions
+func (info *TokenIssuanceInformation) BadPartials(partials []mcl.G1, positions []int, shareKeys []*TokenPublicKey) []int {
+	bad := make([]int, 0)
+	for i := range partials {
+		if positions[i] < 0 || positions[i] >= len(shareKeys) || !info.VerifyPartial(shareKeys[positions[i]], &partials[i]) {
+			bad = append(bad, i)
+		}
 	}
+	return bad
 }
 
 func (t *TokenPublicKey) VerifyMessage(token *SignedToken, message []byte) bool {
@@ -70,11 +105,16 @@ func (t *TokenPublicKey) blind(out *mcl.G1, m *mcl.G1, r *mcl.Fr) {
 	mcl.G1Mul(out, m, r)
 }
 
-func (t *TokenPublicKey) combine(partials []mcl.G1, final *mcl.G1) {
-	final.Clear()
-	for i := range partials {
-		mcl.G1Add(final, final, &partials[i])
+// interpolate the partials of Shamir shares at 0
+func (t *TokenPublicKey) combine(partials []mcl.G1, positions []int, final *mcl.G1) bool {
+	if len(partials) != len(positions) {
+		return false
+	}
+	xs := make([]mcl.Fr, len(positions))
+	for i := range positions {
+		xs[i] = pairing.ShareIndex(positions[i])
 	}
+	return mcl.G1LagrangeInterpolation(final, xs, partials) == nil
 }
 
 func (t *TokenPublicKey) unblind(m *mcl.G1, r *mcl.Fr) {
@@ -22,7 +22,7 @@ func TestToken(t *testing.T) {
 		}
 	}
 
-	token, err := info.Create(blindedHashes)
+	token, err := info.Create(blindedHashes, positions(numSigners))
 	if err != nil {
 		t.Logf("%v", err)
 		t.FailNow()
@@ -33,6 +33,55 @@ func TestToken(t *testing.T) {
 	}
 }
 
+func positions(n int) []int {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	return p
+}
+
+func TestThresholdToken(t *testing.T) {
+	numSigners := 5
+	threshold := 3
+	partialSigningKeys, publicKey, _ := KeyGenThresholdShares(numSigners, threshold)
+	message := []byte("Hi")
+	blindedHash, info := publicKey.Prepare(message)
+	partials := make([]mcl.G1, numSigners)
+	for i := range partialSigningKeys {
+		partialSigningKeys[i].BlindSign(&partials[i], blindedHash)
+	}
+	// a bad partial is found with the public key of its share
+	partials[1].Random()
+	for i := range partials {
+		if info.VerifyPartial(NewTokenPublicKey(&partialSigningKeys[i].X), &partials[i]) != (i != 1) {
+			t.Fatalf("Partial %d not checked", i)
+		}
+	}
+	shareKeys := make([]*TokenPublicKey, numSigners)
+	for i := range shareKeys {
+		shareKeys[i] = NewTokenPublicKey(&partialSigningKeys[i].X)
+	}
+	bad := info.BadPartials(partials[1:4], []int{1, 2, 3}, shareKeys)
+	if len(bad) != 1 || bad[0] != 0 {
+		t.Fatalf("Bad partials %v", bad)
+	}
+	// too few partials
+	_, ok := info.TryCreate([]mcl.G1{partials[0], partials[2]}, []int{0, 2})
+	if ok {
+		t.Fatalf("Token created from too few partials")
+	}
+	_, ok = info.TryCreate(partials[:3], []int{0, 1, 2})
+	if ok {
+		t.Fatalf("Token created with a bad partial")
+	}
+	// any threshold of the good partials
+	token, ok := info.TryCreate([]mcl.G1{partials[4], partials[0], partials[3]}, []int{4, 0, 3})
+	if !ok || !publicKey.VerifyMessage(token, message) {
+		t.Fatalf("Token not created")
+	}
+}
+
 func TestProfile(t *testing.T) {
 	f, _ := os.Create("token.pprof")
 	pprof.StartCPUProfile(f)
@@ -52,7 +101,7 @@ func TestProfile(t *testing.T) {
 			}
 		}
 
-		token, err := info.Create(blindedHashes)
+		token, err := info.Create(blindedHashes, positions(numSigners))
 		if err != nil {
 			t.Logf("%v", err)
 			t.FailNow()
@@ -92,7 +141,7 @@ func BenchmarkTokenVerify(b *testing.B) {
 	blindedHash, info := publicKey.Prepare(message)
 	signingKey.BlindSign(blindedHash, blindedHash)
 	blindedHashes := []mcl.G1{*blindedHash}
-	token, err := info.Create(blindedHashes)
+	token, err := info.Create(blindedHashes, positions(1))
 	if err != nil {
 		b.FailNow()
 	}
@@ -107,3 +156,47 @@ func BenchmarkKeyGen(b *testing.B) {
 		KeyGenShares(64)
 	}
 }
+
+func TestBatchVerify(t *testing.T) {
+	_, publicKey, signingKey := KeyGenShares(1)
+	batch := NewBatchVerifier(publicKey, 10)
+	for i := 0; i < 10; i++ {
+		message := []byte{byte(i)}
+		blindedHash, info := publicKey.Prepare(message)
+		signingKey.BlindSign(blindedHash, blindedHash)
+		token, err := info.Create([]mcl.G1{*blindedHash}, []int{0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 3 {
+			// signed for a different message
+			message = []byte("Hi")
+		}
+		batch.Add(token, message)
+	}
+	valid := batch.Verify()
+	for i := range valid {
+		if valid[i] != (i != 3) {
+			t.Fatalf("Token %d not checked", i)
+		}
+	}
+}
+
+func BenchmarkBatchVerify(b *testing.B) {
+	_, publicKey, signingKey := KeyGenShares(1)
+	message := []byte("Hi")
+	blindedHash, info := publicKey.Prepare(message)
+	signingKey.BlindSign(blindedHash, blindedHash)
+	token, err := info.Create([]mcl.G1{*blindedHash}, []int{0})
+	if err != nil {
+		b.FailNow()
+	}
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		batch := NewBatchVerifier(publicKey, 64)
+		for i := 0; i < 64; i++ {
+			batch.Add(token, message)
+		}
+		batch.Verify()
+	}
+}
@@ -56,6 +56,187 @@ func DecryptionFailure() error    { return err("Unable to decrypt message") }
 func ProofFailure() error         { return err("Proof failure") }
 func TokenInvalid() error         { return err("Token invalid") }
 func CommitFailure() error        { return err("Commitment invalid") }
-func WrongReceipt() error         { return err("Receipt incorrect") }
-func LinkOverflow() error         { return err("Link overflow") }
 func SynchronizationError() error { return err("Multiple messages from same server") }
+
+// The errors below are typed, so callers can tell them apart
+// they are outcomes the process expects and recovers from, such as a peer, client or user doing something wrong,
+// so they are not logged: LogError only lets the first error through and blocks every later one
+
+// A member of an anytrust group signed a partial token that does not verify with the public key of its share
+type BadPartialError struct {
+	Servers []int
+}
+
+func (e *BadPartialError) Error() string {
+	return fmt.Sprintf("Bad partial token signature from servers %v", e.Servers)
+}
+
+func BadPartial(servers []int) error { return &BadPartialError{Servers: servers} }
+
+// Servers did not send their messages for a layer before its deadline
+// not logged, since the layer continues without them
+type TimeoutError struct {
+	Layer   int
+	Servers []int
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("Servers %v missed the deadline for layer %d", e.Servers, e.Layer)
+}
+
+func Timeout(layer int, servers []int) error { return &TimeoutError{Layer: layer, Servers: servers} }
+
+// A client submitted after the submission window of its round closed
+// not logged, since the client can submit in a later round
+type LateError struct {
+	Round int
+}
+
+func (e *LateError) Error() string {
+	return fmt.Sprintf("Submission window of round %d closed", e.Round)
+}
+
+func Late(round int) error { return &LateError{Round: round} }
+
+// A message queued by the local application is longer than the messages of a round
+// not logged, since the application can send a shorter message
+type TooLongError struct {
+	Length int
+	Max    int
+}
+
+func (e *TooLongError) Error() string {
+	return fmt.Sprintf("Message of %d bytes is longer than %d bytes", e.Length, e.Max)
+}
+
+func TooLong(length, max int) error { return &TooLongError{Length: length, Max: max} }
+
+// Data at rest could not be decrypted with the passphrase (or was changed)
+// not logged, since the user can retry with the right passphrase
+type PassphraseError struct{}
+
+func (e *PassphraseError) Error() string {
+	return "Wrong passphrase or corrupted data"
+}
+
+func WrongPassphrase() error { return &PassphraseError{} }
+
+// A bin of a link is full, which happens with small probability for the chosen bin size
+// not logged, since the message is dropped and its path is revoked instead
+type OverflowError struct{}
+
+func (e *OverflowError) Error() string {
+	return "Link overflow"
+}
+
+func LinkOverflow() error { return &OverflowError{} }
+
+// Members of an anytrust group sent partial decryptions whose proofs do not verify with the public keys of their shares
+// not logged, since the messages of the group are dropped and their paths are revoked instead
+type BadDecryptionError struct {
+	Group   int
+	Servers []int
+}
+
+func (e *BadDecryptionError) Error() string {
+	return fmt.Sprintf("Bad partial decryptions from servers %v of group %d", e.Servers, e.Group)
+}
+
+func BadDecryption(group int, servers []int) error {
+	return &BadDecryptionError{Group: group, Servers: servers}
+}
+
+// A client complained about a path that the traceback showed the client did not form correctly
+// not logged, since the complaint is only rejected
+type RejectedComplaintError struct {
+	Client int
+	Layer  int
+}
+
+func (e *RejectedComplaintError) Error() string {
+	return fmt.Sprintf("Complaint of client %d rejected, its path was malformed at layer %d", e.Client, e.Layer)
+}
+
+func RejectedComplaint(client, layer int) error {
+	return &RejectedComplaintError{Client: client, Layer: layer}
+}
+
+// A revoked client tried to register again
+type RevokedError struct{}
+
+func (e *RevokedError) Error() string {
+	return "Client revoked"
+}
+
+func ClientRevokedError() error { return &RevokedError{} }
+
+// The first server of a path answered a submission with a notice that the path was revoked
+type PathRevokedError struct{}
+
+func (e *PathRevokedError) Error() string {
+	return "Path revoked"
+}
+
+func PathRevoked() error { return &PathRevokedError{} }
+
+// Stored state or a key file was written by an unsupported version
+type UnsupportedVersionError struct{}
+
+func (e *UnsupportedVersionError) Error() string {
+	return "Unsupported version"
+}
+
+func VersionError() error { return &UnsupportedVersionError{} }
+
+// A state file holds the state of another client than the one it was loaded for
+type WrongStateError struct {
+	Want  int64
+	Found int64
+}
+
+func (e *WrongStateError) Error() string {
+	return fmt.Sprintf("State file of client %d loaded for client %d", e.Found, e.Want)
+}
+
+func WrongState(want, found int64) error { return &WrongStateError{Want: want, Found: found} }
+
+// The entries of a bulletin board do not form a hash chain
+type ChainError struct{}
+
+func (e *ChainError) Error() string {
+	return "Bulletin board chain invalid"
+}
+
+func ChainInvalid() error { return &ChainError{} }
+
+// A bulletin board entry was requested past the head of the board
+type EntryError struct {
+	Index int
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("Bulletin board entry %d not found", e.Index)
+}
+
+func EntryNotFound(index int) error { return &EntryError{Index: index} }
+
+// The receipt of a path establishment round was wrong or missing
+type ReceiptError struct{}
+
+func (e *ReceiptError) Error() string {
+	return "Receipt incorrect"
+}
+
+func WrongReceipt() error { return &ReceiptError{} }
+
+// A message from another server or a client, or a transcript of such messages, was rejected, since its sender, signature or contents do not check out
+// the message is only dropped, so a faulty peer cannot stop the process by sending bad messages
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "Message rejected: " + e.Reason
+}
+
+func Rejected(reason string) error { return &RejectedError{Reason: reason} }
@@ -8,3 +8,14 @@ import (
 func TestErrorPrinting(t *testing.T) {
 	log.Print(UnimplementedError())
 }
+
+// typed errors must not go through LogError, which blocks after the first error
+func TestTypedErrorsNotLogged(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		for _, e := range []error{BadPartial([]int{i}), ClientRevokedError(), PathRevoked(), VersionError(), WrongState(int64(i), 0), ChainInvalid(), EntryNotFound(i), LinkOverflow(), Late(i), WrongReceipt(), Rejected("signature")} {
+			if e.Error() == "" {
+				t.Fatalf("Empty error message")
+			}
+		}
+	}
+}
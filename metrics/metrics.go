@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Counters, gauges and histograms of a process, served in the Prometheus text exposition format
+// Metrics are created once as package variables, and each value is a series chosen by its label values
+
+// bucket upper bounds for latencies in seconds
+var LatencyBuckets = []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30, 60, 300}
+
+// bucket upper bounds for fractions
+var RatioBuckets = []float64{.1, .2, .3, .4, .5, .6, .7, .8, .9, 1}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+var registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func register(m metric) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.metrics = append(registry.metrics, m)
+}
+
+// The name, help text and label names shared by every kind of metric
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// the labels of a series, formatted as in the exposition format
+func (d *desc) series(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s has labels %v", d.name, d.labels))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(values))
+	for i := range values {
+		pairs[i] = d.labels[i] + "=" + strconv.Quote(values[i])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (d *desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, d.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// the series of a metric, in a fixed order
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func braces(series string) string {
+	if series == "" {
+		return ""
+	}
+	return "{" + series + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// A value that only increases, such as a number of messages
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		values: make(map[string]float64),
+	}
+	register(c)
+	return c
+}
+
+func (c *Counter) Add(v float64, labelValues ...string) {
+	s := c.series(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[s] += v
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Value(labelValues ...string) float64 {
+	s := c.series(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[s]
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	for _, s := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, braces(s), formatFloat(c.values[s]))
+	}
+}
+
+// A value that is set, such as the current round
+type Gauge struct {
+	Counter
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{Counter{
+		desc:   desc{name: name, help: help, kind: "gauge", labels: labels},
+		values: make(map[string]float64),
+	}}
+	register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	s := g.series(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[s] = v
+}
+
+// The distribution of observations, such as latencies, counted in buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	s := h.desc.series(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hs := h.series[s]
+	if hs == nil {
+		hs = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[s] = hs
+	}
+	// buckets are cumulative
+	for i, b := range h.buckets {
+		if v <= b {
+			hs.counts[i]++
+		}
+	}
+	hs.sum += v
+	hs.count++
+}
+
+// observe the seconds since start
+func (h *Histogram) Since(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// the number of observations
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	s := h.desc.series(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hs := h.series[s]; hs != nil {
+		return hs.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, s := range keys {
+		hs := h.series[s]
+		sep := ""
+		if s != "" {
+			sep = ","
+		}
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s%sle=%q} %d\n", h.name, s, sep, formatFloat(b), hs.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", h.name, s, sep, hs.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, braces(s), formatFloat(hs.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, braces(s), hs.count)
+	}
+}
+
+// Write every metric in the text exposition format
+func WriteTo(w io.Writer) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	b := bufio.NewWriter(w)
+	for _, m := range registry.metrics {
+		m.write(b)
+	}
+	b.Flush()
+}
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WriteTo(w)
+	})
+}
+
+// Serve the metrics on /metrics at addr until the listener is closed
+func Serve(addr string) (net.Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	go http.Serve(l, mux)
+	return l, nil
+}
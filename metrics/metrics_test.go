@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	c := NewCounter("test_messages_total", "Test messages", "layer", "reason")
+	c.Inc("1", "skipped")
+	c.Add(2, "1", "skipped")
+	c.Inc("0", "unknown_key")
+	g := NewGauge("test_round", "Test round")
+	g.Set(7)
+	h := NewHistogram("test_seconds", "Test latency", []float64{1, 10})
+	h.Observe(0.5)
+	h.Observe(5)
+	h.Observe(50)
+	if c.Value("1", "skipped") != 3 || h.Count() != 3 {
+		t.Fatalf("Wrong values")
+	}
+
+	b := new(bytes.Buffer)
+	WriteTo(b)
+	out := b.String()
+	expected := []string{
+		"# TYPE test_messages_total counter\n",
+		"test_messages_total{layer=\"0\",reason=\"unknown_key\"} 1\n",
+		"test_messages_total{layer=\"1\",reason=\"skipped\"} 3\n",
+		"# TYPE test_round gauge\ntest_round 7\n",
+		"test_seconds_bucket{le=\"1\"} 1\ntest_seconds_bucket{le=\"10\"} 2\ntest_seconds_bucket{le=\"+Inf\"} 3\ntest_seconds_sum 55.5\ntest_seconds_count 3\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Fatalf("Missing %q in\n%s", e, out)
+		}
+	}
+
+	l, err := Serve("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	resp, err := http.Get("http://" + l.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || !strings.Contains(string(body), expected[1]) {
+		t.Fatalf("Metrics not served: %v", err)
+	}
+}
@@ -105,7 +105,7 @@ func (c *ConnectionManager) Send(b []byte, dest int) (chan error, error) {
 	return nil, err
 }
 
-func (c *ConnectionManager) SendShuffleMessages(Messages map[int]*buffers.MemReadWriter, common *common.CommonState, layer int, t messages.NetworkMessage_MessageType) error {
+func (c *ConnectionManager) SendShuffleMessages(Messages map[int]buffers.Buffer, common *common.CommonState, layer int, t messages.NetworkMessage_MessageType) error {
 	m := messages.Metadata{
 		Type:        t,
 		Round:       common.Round,
@@ -118,8 +118,10 @@ func (c *ConnectionManager) SendShuffleMessages(Messages map[int]*buffers.MemRea
 	return err
 }
 
-func (c *ConnectionManager) SendSignedMessageChunks(m *messages.Metadata, Messages map[int]*buffers.MemReadWriter, common *common.CommonState) ([]chan error, error) {
+func (c *ConnectionManager) SendSignedMessageChunks(m *messages.Metadata, Messages map[int]buffers.Buffer, common *common.CommonState) ([]chan error, error) {
 	// done := make(chan error)
+	// each buffer is copied into its message, including those that are skipped or not reached after an error
+	defer buffers.CloseAll(Messages)
 	jobs := c.caller.GetJobs()
 	// timeout := BandwidthTimeout(Messages[0].Len())
 	inProgress := make([]chan error, len(Messages))
@@ -130,15 +132,25 @@ func (c *ConnectionManager) SendSignedMessageChunks(m *messages.Metadata, Messag
 		if !ok {
 			break
 		}
+		if common.IsDropped(sid) {
+			// the users of a dropped server are routed to a replacement in the next round
+			continue
+		}
 		f := Messages[sid]
-		f.Shuffle(!config.NoDummies)
+		if config.NoDummies {
+			f.Shuffle(nil)
+		} else {
+			f.Shuffle(NewCover(common, sid, m))
+		}
 		sm := messages.NewSignedMessage(f.Len(), m.Round, m.Layer, m.Sender, 0, sid, f.NumMessages(), m.Type)
 		r, err := f.ReadNextChunk(sm.Data)
 		if err != nil {
 			return inProgress, err //done <- err
 		}
 		sm.Data = sm.Data[:r]
-		PreHashSign(c.MyCfg.SignatureKey, sm)
+		digest := crypto.BatchDigest(sm.GetSignedData())
+		sm.Signature = crypto.SignDigest(digest, c.MyCfg.SignatureKey)
+		c.recordSent(m.Round, m.Layer, sid, digest)
 		inProgress[sid], err = c.Send(sm.AsArray(), sid)
 		if err != nil {
 			return inProgress, err //done <- err
@@ -156,6 +168,25 @@ func (c *ConnectionManager) SendSignedMessageChunks(m *messages.Metadata, Messag
 	return inProgress, nil
 }
 
+// keep the digest of the batch sent to dest, which this server answers blame requests with
+func (c *ConnectionManager) recordSent(round, layer, dest int, digest []byte) {
+	c.sentLock.Lock()
+	defer c.sentLock.Unlock()
+	for b := range c.sent {
+		if b.round < round-1 {
+			delete(c.sent, b)
+		}
+	}
+	c.sent[sentBatch{round, layer, dest}] = digest
+}
+
+// The digest of the batch sent to dest for the layer, or nil if none was sent
+func (c *ConnectionManager) SentDigest(round, layer, dest int) []byte {
+	c.sentLock.Lock()
+	defer c.sentLock.Unlock()
+	return c.sent[sentBatch{round, layer, dest}]
+}
+
 // ensure all writes have finished
 func (c *ConnectionManager) FinishSends(inProgress []chan error) error {
 	for _, c := range inProgress {
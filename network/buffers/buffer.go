@@ -0,0 +1,169 @@
+package buffers
+
+import (
+	"log"
+	"sync"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/metrics"
+)
+
+var binFill = metrics.NewHistogram("lightning_bin_fill_ratio", "Fraction of the bin of a link filled by real messages", metrics.RatioBuckets)
+
+// The elements sent on a link, read back in a shuffled order
+type Buffer interface {
+	Write(b []byte) error
+	Shuffle(dummies DummyGenerator)
+	NumMessages() int
+	ReadNextChunk(b []byte) (int, error)
+	ReadElement(b []byte) error
+	Len() int
+	// free the buffer, whether or not its elements were read
+	Close()
+}
+
+// Fills the elements that pad a buffer to its size
+type DummyGenerator interface {
+	Dummy(b []byte)
+}
+
+// Buffers whose elements would take more than config.MaxMemoryBuffer bytes are kept in a file
+func NewBuffer(elementLength, numElements int, shuf *config.Shuffler) Buffer {
+	if elementLength*numElements > config.MaxMemoryBuffer {
+		f, err := NewFileReadWriter(elementLength, numElements, shuf)
+		if err == nil {
+			return f
+		}
+		log.Printf("Could not create buffer file, buffering in memory: %v", err)
+	}
+	return NewMemReadWriter(elementLength, numElements, shuf)
+}
+
+// Close the buffers of every link, once they are sent or are not going to be
+func CloseAll(bufs map[int]Buffer) {
+	for _, b := range bufs {
+		b.Close()
+	}
+}
+
+// Where the elements of a buffer are kept until they are read
+type storage interface {
+	put(index int, b []byte) error
+	get(index int, b []byte) error
+	// called once every element has been read, or the buffer is closed, and may be called again
+	release()
+}
+
+// The shuffling and chunking shared by the buffers
+type shuffledBuffer struct {
+	store         storage
+	permutation   []int
+	position      int
+	elementLength int
+	elementCount  int
+	offset        int
+	element       []byte
+	dummies       DummyGenerator
+	capacity      int
+	numElements   int
+	shuf          *config.Shuffler
+	mu            sync.Mutex
+}
+
+func newShuffledBuffer(store storage, elementLength, numElements int, shuf *config.Shuffler) shuffledBuffer {
+	return shuffledBuffer{
+		store:         store,
+		offset:        elementLength,
+		element:       make([]byte, elementLength),
+		elementLength: elementLength,
+		capacity:      numElements,
+		numElements:   numElements,
+		shuf:          shuf,
+	}
+}
+
+// Shuffle the elements, padded to the size of the buffer with dummies unless dummies is nil
+func (m *shuffledBuffer) Shuffle(dummies DummyGenerator) {
+	if m.capacity > 0 {
+		binFill.Observe(float64(m.elementCount) / float64(m.capacity))
+	}
+	m.dummies = dummies
+	if dummies == nil {
+		m.numElements = m.elementCount
+	}
+	m.permutation = m.shuf.Perm(m.numElements)
+	if m.numElements == 0 {
+		m.store.release()
+	}
+}
+
+// the number of elements read after shuffling, including the dummies
+func (m *shuffledBuffer) NumMessages() int {
+	return m.numElements
+}
+
+func (m *shuffledBuffer) Write(b []byte) error {
+	m.mu.Lock()
+	if m.elementCount >= m.capacity {
+		m.mu.Unlock()
+		return errors.LinkOverflow()
+	}
+	index := m.elementCount
+	m.elementCount += 1
+	m.mu.Unlock()
+	// elements are only read after all of them are written
+	return m.store.put(index, b)
+}
+
+func (r *shuffledBuffer) ReadNextChunk(b []byte) (int, error) {
+	size := len(b)
+	written := 0
+	var err error = nil
+	// write any remainder from previous call
+	if r.elementLength-r.offset > 0 {
+		// this could fill the entire buffer for very large elements...
+		written = copy(b, r.element[r.offset:])
+		r.offset += written
+	}
+	// read next element as applicable
+	for written < size && r.position < r.numElements && err == nil {
+		if written+r.elementLength > size {
+			// partial read into buffer
+			err = r.ReadElement(r.element)
+			// copy part into buffer, leave remainder for next time
+			r.offset = copy(b[written:], r.element)
+			written += r.offset
+		} else {
+			// full read
+			err = r.ReadElement(b[written : written+r.elementLength])
+			written += r.elementLength
+		}
+	}
+	// if the last part is short, it will need to be truncated to written
+	return written, err
+}
+
+func (r *shuffledBuffer) ReadElement(b []byte) error {
+	elementIndex := r.permutation[r.position]
+	r.position++
+	var err error
+	if elementIndex >= r.elementCount {
+		r.dummies.Dummy(b)
+	} else {
+		// each element is only read once
+		err = r.store.get(elementIndex, b)
+	}
+	if r.position == r.numElements {
+		r.store.release()
+	}
+	return err
+}
+
+func (r *shuffledBuffer) Close() {
+	r.store.release()
+}
+
+func (r *shuffledBuffer) Len() int {
+	return r.numElements * r.elementLength
+}
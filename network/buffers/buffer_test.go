@@ -0,0 +1,131 @@
+package buffers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+type markedDummies struct{}
+
+func (markedDummies) Dummy(b []byte) {
+	for i := range b {
+		b[i] = 0xff
+	}
+}
+
+func testBuffer(t *testing.T, b Buffer, elementLength, numWritten, numElements int) {
+	for i := 0; i < numWritten; i++ {
+		e := make([]byte, elementLength)
+		binary.LittleEndian.PutUint64(e, uint64(i))
+		err := b.Write(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if numWritten == numElements {
+		if _, ok := b.Write(make([]byte, elementLength)).(*errors.OverflowError); !ok {
+			t.Fatalf("Full buffer did not overflow")
+		}
+	}
+	b.Shuffle(markedDummies{})
+	if b.NumMessages() != numElements || b.Len() != numElements*elementLength {
+		t.Fatalf("Buffer not padded to its size")
+	}
+	// chunks that split elements
+	out := make([]byte, 0, b.Len())
+	chunk := make([]byte, elementLength+elementLength/2)
+	for len(out) < b.Len() {
+		n, err := b.ReadNextChunk(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, chunk[:n]...)
+	}
+	seen := make(map[uint64]bool)
+	dummy := bytes.Repeat([]byte{0xff}, elementLength)
+	inOrder := true
+	for i := 0; i < numElements; i++ {
+		e := out[i*elementLength : (i+1)*elementLength]
+		if bytes.Equal(e, dummy) {
+			continue
+		}
+		id := binary.LittleEndian.Uint64(e)
+		if id >= uint64(numWritten) || seen[id] {
+			t.Fatalf("Element %d read wrong", id)
+		}
+		seen[id] = true
+		inOrder = inOrder && id == uint64(i)
+	}
+	if len(seen) != numWritten {
+		t.Fatalf("Read %d of %d elements", len(seen), numWritten)
+	}
+	if inOrder {
+		t.Fatalf("Elements not shuffled")
+	}
+}
+
+func TestMemReadWriter(t *testing.T) {
+	elementLength := 40
+	numElements := 100
+	b := NewMemReadWriter(elementLength, numElements, config.NewPRGShuffler(rand.Reader))
+	testBuffer(t, b, elementLength, numElements/2, numElements)
+	b = NewMemReadWriter(elementLength, numElements, config.NewPRGShuffler(rand.Reader))
+	testBuffer(t, b, elementLength, numElements, numElements)
+}
+
+func TestFileReadWriter(t *testing.T) {
+	elementLength := 40
+	numElements := 100
+	b, err := NewFileReadWriter(elementLength, numElements, config.NewPRGShuffler(rand.Reader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBuffer(t, b, elementLength, numElements/2, numElements)
+	b, err = NewFileReadWriter(elementLength, numElements, config.NewPRGShuffler(rand.Reader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBuffer(t, b, elementLength, numElements, numElements)
+	// short elements read back padded
+	b, err = NewFileReadWriter(elementLength, 1, config.NewPRGShuffler(rand.Reader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Write([]byte{1})
+	b.Shuffle(nil)
+	e := make([]byte, elementLength)
+	err = b.ReadElement(e)
+	if err != nil || e[0] != 1 || !bytes.Equal(e[1:], make([]byte, elementLength-1)) {
+		t.Fatalf("Short element read wrong")
+	}
+	b.Close()
+	// a buffer that is never read still closes its file
+	b, err = NewFileReadWriter(elementLength, numElements, config.NewPRGShuffler(rand.Reader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Write(make([]byte, elementLength))
+	b.Close()
+	b.Close()
+	if _, err := b.store.(*fileStore).file.Stat(); err == nil {
+		t.Fatalf("Closed buffer kept its file open")
+	}
+}
+
+func TestNewBuffer(t *testing.T) {
+	elementLength := 1024
+	shuf := config.NewPRGShuffler(rand.Reader)
+	if _, ok := NewBuffer(elementLength, 16, shuf).(*MemReadWriter); !ok {
+		t.Fatalf("Small buffer not in memory")
+	}
+	b := NewBuffer(elementLength, config.MaxMemoryBuffer/elementLength+1, shuf)
+	if _, ok := b.(*FileReadWriter); !ok {
+		t.Fatalf("Large buffer not in a file")
+	}
+	b.Shuffle(nil)
+}
@@ -0,0 +1,58 @@
+package buffers
+
+import (
+	"os"
+	"sync"
+
+	"github.com/simonlangowski/lightning1/config"
+)
+
+// For path establishment rounds whose boomerang envelopes do not fit in memory
+// each element has a fixed slot in a temporary file, so writes can happen in parallel and reads in the shuffled order
+type FileReadWriter struct {
+	shuffledBuffer
+}
+
+type fileStore struct {
+	file          *os.File
+	elementLength int
+	closed        sync.Once
+}
+
+func NewFileReadWriter(elementLength, numElements int, shuf *config.Shuffler) (*FileReadWriter, error) {
+	f, err := os.CreateTemp(config.BufferDirectory, "buffer")
+	if err != nil {
+		return nil, err
+	}
+	// the file is deleted once it is closed
+	err = os.Remove(f.Name())
+	if err == nil {
+		// sparse, so that short elements read back padded with zeros
+		err = f.Truncate(int64(numElements) * int64(elementLength))
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &fileStore{file: f, elementLength: elementLength}
+	return &FileReadWriter{newShuffledBuffer(s, elementLength, numElements, shuf)}, nil
+}
+
+func (s *fileStore) put(index int, b []byte) error {
+	if len(b) > s.elementLength {
+		b = b[:s.elementLength]
+	}
+	_, err := s.file.WriteAt(b, int64(index)*int64(s.elementLength))
+	return err
+}
+
+func (s *fileStore) get(index int, b []byte) error {
+	_, err := s.file.ReadAt(b[:s.elementLength], int64(index)*int64(s.elementLength))
+	return err
+}
+
+func (s *fileStore) release() {
+	s.closed.Do(func() {
+		s.file.Close()
+	})
+}
@@ -0,0 +1,88 @@
+package network
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash"
+	"sync"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+/*
+Cover traffic between two servers
+
+Every link carries exactly BinSize messages, so a network observer cannot learn the load of a link
+- The sender pads its buffer for the link with dummies: a random body after a key field holding
+  a MAC of the body under the key the two servers share
+- Without the link key a dummy looks like any other envelope of the layer
+- The receiver computes the MAC of every message and compares it in constant time,
+  so real messages and dummies are handled the same way until the dummy is dropped
+*/
+
+type Cover struct {
+	mac    hash.Hash
+	header []byte
+	stream cipher.Stream
+	mu     sync.Mutex
+}
+
+// The cover traffic of a message from sender to receiver, for one end of the link
+func NewCover(c *common.CommonState, peer int, m *messages.Metadata) *Cover {
+	linkKey := c.ServerSecretKey.SharedKey(&c.ServerPublicKeys[peer])
+	header := make([]byte, 12)
+	binary.LittleEndian.PutUint32(header[:4], uint32(m.Round))
+	binary.LittleEndian.PutUint32(header[4:8], uint32(m.Layer))
+	binary.LittleEndian.PutUint32(header[8:], uint32(m.Type))
+	// the bodies only need to look random, so they are a keystream rather than read from the system
+	seed := make([]byte, 32+aes.BlockSize)
+	_, err := rand.Read(seed)
+	if err != nil {
+		panic(err)
+	}
+	block, err := aes.NewCipher(seed[:32])
+	if err != nil {
+		panic(err)
+	}
+	return &Cover{
+		mac:    hmac.New(sha256.New, linkKey),
+		header: header,
+		stream: cipher.NewCTR(block, seed[32:]),
+	}
+}
+
+func (c *Cover) tag(body []byte) []byte {
+	c.mac.Reset()
+	c.mac.Write(c.header)
+	c.mac.Write(body)
+	return c.mac.Sum(nil)[:crypto.KEY_SIZE]
+}
+
+// Fill b with a dummy
+func (c *Cover) Dummy(b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	body := b[crypto.KEY_SIZE:]
+	for i := range body {
+		body[i] = 0
+	}
+	c.stream.XORKeyStream(body, body)
+	copy(b[:crypto.KEY_SIZE], c.tag(body))
+}
+
+// Constant time in the contents of the message
+func (c *Cover) IsDummy(b []byte) bool {
+	if len(b) < crypto.KEY_SIZE {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return subtle.ConstantTimeCompare(b[:crypto.KEY_SIZE], c.tag(b[crypto.KEY_SIZE:])) == 1
+}
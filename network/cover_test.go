@@ -0,0 +1,71 @@
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/network/buffers"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+func TestCover(t *testing.T) {
+	ids := []int64{0, 1}
+	servers := make(map[int64]*config.Server)
+	for _, id := range ids {
+		servers[id] = config.CreateServerWithCertificate("localhost:9000", id, nil, nil)
+	}
+	groups := &config.Groups{Groups: config.CreateSeparateGroupsWithSize(1, 2, ids)}
+	sender := common.NewCommonState(servers, 0, groups)
+	receiver := common.NewCommonState(servers, 1, groups)
+	m := &messages.Metadata{Type: messages.NetworkMessage_ServerMessageForward, Round: 3, Layer: 2}
+
+	elementLength := 100
+	binSize := 20
+	numReal := 7
+	b := buffers.NewMemReadWriter(elementLength, binSize, config.NewPRGShuffler(rand.Reader))
+	real := make([][]byte, numReal)
+	for i := range real {
+		real[i] = make([]byte, elementLength)
+		rand.Read(real[i])
+		b.Write(real[i])
+	}
+	b.Shuffle(NewCover(sender, 1, m))
+	// the link carries the whole bin
+	if b.NumMessages() != binSize {
+		t.Fatalf("%d messages sent instead of %d", b.NumMessages(), binSize)
+	}
+	out := make([]byte, b.Len())
+	n, err := b.ReadNextChunk(out)
+	if err != nil || n != binSize*elementLength {
+		t.Fatalf("Read %d bytes: %v", n, err)
+	}
+
+	cover := NewCover(receiver, 0, m)
+	other := NewCover(receiver, 0, &messages.Metadata{Type: m.Type, Round: m.Round + 1, Layer: m.Layer})
+	numDummies := 0
+	received := 0
+	for pos := 0; pos < len(out); pos += elementLength {
+		e := out[pos : pos+elementLength]
+		if bytes.Equal(e[:16], make([]byte, 16)) {
+			t.Fatalf("Dummy is not random")
+		}
+		if other.IsDummy(e) {
+			t.Fatalf("Dummy recognised in another round")
+		}
+		if cover.IsDummy(e) {
+			numDummies++
+			continue
+		}
+		for _, r := range real {
+			if bytes.Equal(r, e) {
+				received++
+			}
+		}
+	}
+	if numDummies != binSize-numReal || received != numReal {
+		t.Fatalf("%d dummies and %d real messages received", numDummies, received)
+	}
+}
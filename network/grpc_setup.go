@@ -17,8 +17,8 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
-func RunServer(handler messages.MessageHandlersServer, coordHandler coord.CoordinatorHandlerServer, servercfgs map[int64]*config.Server, addr string) {
-	server := StartServer(handler, coordHandler, servercfgs, addr)
+func RunServer(handler messages.MessageHandlersServer, coordHandler coord.CoordinatorHandlerServer, servercfgs map[int64]*config.Server, addr string, services ...func(*grpc.Server)) {
+	server := StartServer(handler, coordHandler, servercfgs, addr, services...)
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	<-sigs
@@ -26,7 +26,8 @@ func RunServer(handler messages.MessageHandlersServer, coordHandler coord.Coordi
 	log.Printf("Server %v stopped", addr)
 }
 
-func StartServer(handler messages.MessageHandlersServer, coordHandler coord.CoordinatorHandlerServer, servercfgs map[int64]*config.Server, addr string) *grpc.Server {
+// services register any additional handlers, such as a bulletin board
+func StartServer(handler messages.MessageHandlersServer, coordHandler coord.CoordinatorHandlerServer, servercfgs map[int64]*config.Server, addr string, services ...func(*grpc.Server)) *grpc.Server {
 	id, myCfg := FindConfig(addr, servercfgs)
 	if id < 0 {
 		panic("Could not find " + addr)
@@ -44,6 +45,9 @@ func StartServer(handler messages.MessageHandlersServer, coordHandler coord.Coor
 	if coordHandler != nil {
 		coord.RegisterCoordinatorHandlerServer(grpcServer, coordHandler)
 	}
+	for _, register := range services {
+		register(grpcServer)
+	}
 	lis, err := net.Listen("tcp", config.Port(addr))
 	if err != nil {
 		log.Fatal("Could not listen:", addr, err)
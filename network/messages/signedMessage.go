@@ -78,6 +78,12 @@ func (s *Metadata) PackTo(b []byte) {
 	binary.LittleEndian.PutUint32(b[24:28], uint32(s.Group))
 }
 
+// Pack the metadata of a message that is sent without a signature
+// signing packs it already
+func (s *SignedMessage) PackMetadata() {
+	s.Metadata.PackTo(s.Raw)
+}
+
 // Get the byte array that is signed by the signature (including the metadata)
 func (s *SignedMessage) GetSignedData() []byte {
 	s.Metadata.PackTo(s.Raw)
@@ -154,6 +154,34 @@ func (c *Caller) SendToGroup(groupNumber int, message *messages.SignedMessage) (
 	return responses, nil
 }
 
+// A response from the member of a group at Position
+type GroupResponse struct {
+	Position int
+	Response *messages.SignedMessage
+	Err      error
+}
+
+// Send to each member of the group, and return the responses as they arrive
+// the channel is closed after every member has responded
+func (c *Caller) SendToGroupAsync(groupNumber int, message *messages.SignedMessage) <-chan GroupResponse {
+	group := c.Groups[groupNumber]
+	responses := make(chan GroupResponse, len(group))
+	var wg sync.WaitGroup
+	for i, dest := range group {
+		wg.Add(1)
+		go func(i int, dest int) {
+			defer wg.Done()
+			resp, err := c.SendSignedMessage(dest, message)
+			responses <- GroupResponse{Position: i, Response: resp, Err: err}
+		}(i, dest)
+	}
+	go func() {
+		wg.Wait()
+		close(responses)
+	}()
+	return responses
+}
+
 func (c *Caller) UseStream(dest int) messages.MessageHandlers_HandleSignedMessageStreamClient {
 	c.streamLocks[dest].Lock()
 	if !c.mock {
@@ -12,7 +12,7 @@ import (
 
 var numWorkers = runtime.NumCPU()
 
-func CreateGroupMessages(chunks map[int]*buffers.MemReadWriter, c *common.CommonState, layer int, t messages.NetworkMessage_MessageType) ([]int, [][]byte) {
+func CreateGroupMessages(chunks map[int]buffers.Buffer, c *common.CommonState, layer int, t messages.NetworkMessage_MessageType) ([]int, [][]byte) {
 	wg := sync.WaitGroup{}
 	signedMessages := make([][]byte, len(chunks))
 	messageCounts := make([]int, len(chunks))
@@ -22,11 +22,12 @@ func CreateGroupMessages(chunks map[int]*buffers.MemReadWriter, c *common.Common
 			defer wg.Done()
 			for j := i; j < len(signedMessages); j += numWorkers {
 				f := chunks[j]
-				f.Shuffle(false)
+				f.Shuffle(nil)
 				messageCounts[j] = f.NumMessages()
 				lengthLeft := f.Len()
 				sm := messages.NewSignedMessage(lengthLeft, c.Round, c.Layer, c.MyId, j, 0, f.NumMessages(), t)
 				f.ReadNextChunk(sm.Data)
+				f.Close()
 				PreHashSign(c.SecretSigningKey, sm)
 				signedMessages[j] = sm.AsArray()
 			}
@@ -37,7 +38,7 @@ func CreateGroupMessages(chunks map[int]*buffers.MemReadWriter, c *common.Common
 }
 
 // no need for dummies, but do shuffle
-func (c *ConnectionManager) SendGroupShuffleMessages(chunks map[int]*buffers.MemReadWriter, common *common.CommonState, t messages.NetworkMessage_MessageType, responseSize int) ([]int, error) {
+func (c *ConnectionManager) SendGroupShuffleMessages(chunks map[int]buffers.Buffer, common *common.CommonState, t messages.NetworkMessage_MessageType, responseSize int) ([]int, error) {
 	// first compile and sign all the messages.  No dummies so no extra memory overhead
 	config.LogTime("Starting signing %d", common.Layer)
 	messageCounts, groupMessages := CreateGroupMessages(chunks, common, common.Layer, t)
@@ -1,17 +1,23 @@
 package synchronization
 
 import (
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/metrics"
 )
 
+var layerWait = metrics.NewHistogram("lightning_sync_wait_seconds", "Time from the start of a layer until every sender finished it", metrics.LatencyBuckets, "layer")
+
 // Implement syncrhonized broadcast
 // A server receives a message in layer l+1 only after all messages from layer l
 
 // Basically, hold messages in layer l+1 until those from layer l are processed
 // With correct functioning, we get one message from each server each layer, and so we know we are done
-// Otherwise, if no message is received after a timeout, then start a churn protocol (not implemented)
+// Otherwise, if a server has not finished sending by the deadline of the layer, it is dropped (churn)
+// and the layer completes without it. Dropped servers are skipped in every later layer
 
 type Synchronizer struct {
 	// current state
@@ -24,6 +30,8 @@ type Synchronizer struct {
 	processed int
 	threshold int
 	started   []bool
+	finished  []bool // guarded by countLock
+	dropped   map[int]bool
 	countLock sync.Mutex
 	markLock  sync.Mutex
 
@@ -31,6 +39,11 @@ type Synchronizer struct {
 	wait *sync.Cond
 	lock sync.RWMutex
 
+	// deadline of the current layer
+	timer      *time.Timer
+	generation int
+	layerStart time.Time
+
 	callback Callback
 }
 
@@ -41,6 +54,14 @@ type Callback interface {
 	OnThreshold(int) (int, int)
 }
 
+// Optionally implemented by the callback to set deadlines
+type Churn interface {
+	// how long to wait for the messages of a layer, or 0 to wait forever
+	Deadline(layer int) time.Duration
+	// called with the ids that did not finish (call Done) before the deadline, before the layer completes without them
+	OnTimeout(layer int, silent []int)
+}
+
 // For submissions before layer 0
 const PreRound = -1
 
@@ -52,12 +73,15 @@ const Completed = 1
 
 func NewSynchronizer(round int, layer int, threshold int, callback Callback) *Synchronizer {
 	s := &Synchronizer{
-		round:     round,
-		layer:     layer,
-		processed: 0,
-		threshold: threshold,
-		started:   make([]bool, threshold),
-		callback:  callback,
+		round:      round,
+		layer:      layer,
+		processed:  0,
+		threshold:  threshold,
+		started:    make([]bool, threshold),
+		finished:   make([]bool, threshold),
+		dropped:    make(map[int]bool),
+		callback:   callback,
+		layerStart: time.Now(),
 	}
 	s.wait = sync.NewCond(s.lock.RLocker())
 	return s
@@ -71,13 +95,23 @@ func (s *Synchronizer) Sync(layer int) {
 	}
 }
 
+func (s *Synchronizer) Layer() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.layer
+}
+
 func (s *Synchronizer) SyncOnce(layer int, id int) error {
 	s.Sync(layer)
 	s.markLock.Lock()
 	defer s.markLock.Unlock()
-	if id > len(s.started) || id < 0 {
+	if id >= len(s.started) || id < 0 {
 		return errors.BadMetadataError()
 	}
+	if s.dropped[id] {
+		// arrived after the deadline
+		return errors.Timeout(layer, []int{id})
+	}
 	if s.started[id] {
 		return errors.SynchronizationError()
 	}
@@ -85,12 +119,18 @@ func (s *Synchronizer) SyncOnce(layer int, id int) error {
 	return nil
 }
 
-func (s *Synchronizer) Done() {
+// Called once the id that passed SyncOnce for the layer is finished
+// ignored if the id was dropped meanwhile, since it was counted then
+func (s *Synchronizer) Done(layer int, id int) {
 	s.countLock.Lock()
 	defer s.countLock.Unlock()
+	if layer != s.layer || id < 0 || id >= len(s.finished) || s.finished[id] {
+		return
+	}
+	s.finished[id] = true
 	s.processed += 1
 	if s.processed == s.threshold {
-		go s.Trigger()
+		go s.trigger(s.generation)
 	} else if s.processed > s.threshold {
 		panic("More clients passed SyncOnce than threshold")
 	}
@@ -103,13 +143,35 @@ func (s *Synchronizer) Trigger() {
 	defer s.lock.Unlock()
 	s.markLock.Lock()
 	defer s.markLock.Unlock()
+	s.nextLayer()
+}
+
+// complete the layer of the generation, unless it already completed or the synchronizer was reset
+func (s *Synchronizer) trigger(generation int) {
+	s.countLock.Lock()
+	defer s.countLock.Unlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.markLock.Lock()
+	defer s.markLock.Unlock()
+	if generation != s.generation {
+		return
+	}
+	s.nextLayer()
+}
+
+// called holding all locks
+func (s *Synchronizer) nextLayer() {
+	layerWait.Since(s.layerStart, strconv.Itoa(s.layer))
 	if s.callback != nil {
 		s.threshold, s.layer = s.callback.OnThreshold(s.layer)
 	} else {
 		s.layer++
 	}
 	s.started = make([]bool, s.threshold)
+	s.finished = make([]bool, s.threshold)
 	s.processed = 0
+	s.startLayer()
 	s.wait.Broadcast()
 }
 
@@ -125,6 +187,115 @@ func (s *Synchronizer) Reset(round int, layer int, threshold int) {
 	s.processed = 0
 	s.threshold = threshold
 	s.started = make([]bool, threshold)
+	s.finished = make([]bool, threshold)
+	s.startLayer()
 
 	s.wait.Broadcast()
 }
+
+// Stop waiting for these ids in this and every later layer
+func (s *Synchronizer) Drop(ids []int) {
+	s.countLock.Lock()
+	s.markLock.Lock()
+	processed := s.processed
+	for _, id := range ids {
+		s.drop(id)
+	}
+	// otherwise Done already triggered
+	trigger := s.processed != processed && s.processed == s.threshold
+	generation := s.generation
+	s.markLock.Unlock()
+	s.countLock.Unlock()
+	if trigger {
+		go s.trigger(generation)
+	}
+}
+
+// The ids that were dropped
+func (s *Synchronizer) Dropped() []int {
+	s.markLock.Lock()
+	defer s.markLock.Unlock()
+	ids := make([]int, 0, len(s.dropped))
+	for id := range s.dropped {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// called holding the count and mark locks
+func (s *Synchronizer) drop(id int) {
+	if s.dropped[id] {
+		return
+	}
+	s.dropped[id] = true
+	if id >= 0 && id < len(s.started) && !s.finished[id] {
+		s.started[id] = true
+		s.finished[id] = true
+		s.processed++
+	}
+}
+
+// called holding all locks when the layer changes
+func (s *Synchronizer) startLayer() {
+	for id := range s.dropped {
+		if id >= 0 && id < len(s.started) {
+			s.started[id] = true
+			s.finished[id] = true
+			s.processed++
+		}
+	}
+	s.generation++
+	if s.processed == s.threshold && s.threshold > 0 {
+		// only dropped ids are left
+		go s.trigger(s.generation)
+	}
+	s.layerStart = time.Now()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	churn, ok := s.callback.(Churn)
+	if !ok {
+		return
+	}
+	deadline := churn.Deadline(s.layer)
+	if deadline <= 0 {
+		return
+	}
+	generation, layer := s.generation, s.layer
+	s.timer = time.AfterFunc(deadline, func() {
+		s.expire(generation, layer)
+	})
+}
+
+// drop the servers that did not finish sending in a layer before its deadline, and complete the layer
+// a server that started but stalled is dropped too, or it could hold up the layer forever
+func (s *Synchronizer) expire(generation, layer int) {
+	s.countLock.Lock()
+	s.markLock.Lock()
+	if generation != s.generation {
+		// the layer already completed
+		s.markLock.Unlock()
+		s.countLock.Unlock()
+		return
+	}
+	silent := make([]int, 0)
+	for id, finished := range s.finished {
+		if !finished {
+			silent = append(silent, id)
+		}
+	}
+	for _, id := range silent {
+		s.drop(id)
+	}
+	trigger := len(silent) > 0 && s.processed == s.threshold
+	s.markLock.Unlock()
+	s.countLock.Unlock()
+	if len(silent) == 0 {
+		return
+	}
+	s.callback.(Churn).OnTimeout(layer, silent)
+	if trigger {
+		s.trigger(generation)
+	}
+}
@@ -0,0 +1,104 @@
+package synchronization
+
+import (
+	"testing"
+	"time"
+
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+type mockChurn struct {
+	threshold int
+	deadline  time.Duration
+	silent    chan []int
+}
+
+func (m *mockChurn) OnThreshold(layer int) (int, int) {
+	return m.threshold, layer + 1
+}
+
+func (m *mockChurn) Deadline(layer int) time.Duration {
+	return m.deadline
+}
+
+func (m *mockChurn) OnTimeout(layer int, silent []int) {
+	m.silent <- silent
+}
+
+func TestTimeout(t *testing.T) {
+	threshold := 3
+	m := &mockChurn{threshold: threshold, deadline: 50 * time.Millisecond, silent: make(chan []int, 1)}
+	s := NewSynchronizer(0, 0, threshold, m)
+	s.Reset(0, 1, threshold)
+	for _, id := range []int{0, 1} {
+		err := s.SyncOnce(1, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Done(1, id)
+	}
+	// server 2 never sends
+	select {
+	case silent := <-m.silent:
+		if len(silent) != 1 || silent[0] != 2 {
+			t.Fatalf("Wrong silent servers %v", silent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Deadline did not expire")
+	}
+	s.Sync(2)
+
+	// the next layer does not wait for the dropped server
+	m.deadline = 0
+	for _, id := range []int{0, 1} {
+		err := s.SyncOnce(2, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Done(2, id)
+	}
+	s.Sync(3)
+	err := s.SyncOnce(3, 2)
+	if e, ok := err.(*errors.TimeoutError); !ok || e.Servers[0] != 2 {
+		t.Fatalf("Dropped server was not rejected")
+	}
+	if dropped := s.Dropped(); len(dropped) != 1 || dropped[0] != 2 {
+		t.Fatalf("Wrong dropped servers %v", dropped)
+	}
+}
+
+func TestStalled(t *testing.T) {
+	threshold := 3
+	m := &mockChurn{threshold: threshold, deadline: 50 * time.Millisecond, silent: make(chan []int, 1)}
+	s := NewSynchronizer(0, 0, threshold, m)
+	s.Reset(0, 1, threshold)
+	for _, id := range []int{0, 1, 2} {
+		err := s.SyncOnce(1, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Done(1, 0)
+	s.Done(1, 1)
+	// server 2 started sending but stalls
+	select {
+	case silent := <-m.silent:
+		if len(silent) != 1 || silent[0] != 2 {
+			t.Fatalf("Wrong silent servers %v", silent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Deadline did not expire for the stalled server")
+	}
+	s.Sync(2)
+	// it finishing late does not count towards the next layer
+	s.Done(1, 2)
+	m.deadline = 0
+	for _, id := range []int{0, 1} {
+		err := s.SyncOnce(2, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Done(2, id)
+	}
+	s.Sync(3)
+}
@@ -30,6 +30,12 @@ type ConnectionManager struct {
 	locks               []sync.Mutex
 	caller              *Caller
 	terminated          bool
+	sentLock            sync.Mutex
+	sent                map[sentBatch][]byte // digests of the batches sent in the current and last round
+}
+
+type sentBatch struct {
+	round, layer, dest int
 }
 
 func NewConnectionManager(cfgs map[int64]*config.Server, id int) *ConnectionManager {
@@ -39,6 +45,7 @@ func NewConnectionManager(cfgs map[int64]*config.Server, id int) *ConnectionMana
 		OutgoingConnections: make([]net.Conn, len(cfgs)),
 		IncomingConnections: make([]net.Conn, len(cfgs)),
 		locks:               make([]sync.Mutex, len(cfgs)),
+		sent:                make(map[sentBatch][]byte),
 	}
 	selfConnectionIn, selfConnectionOut := NewMockConnPair(id, id)
 	c.IncomingConnections[id] = selfConnectionIn
@@ -0,0 +1,408 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+)
+
+/*
+Audit log of the signed batches a server receives
+
+Each batch is stored with its metadata and the sender's stream signature
+so that a third party can later check exactly what the sender sent
+The batches for each round are written to one segment file of bounded size
+- A batch reserves its record in the segment when its first message arrives,
+  or is dropped whole if the record does not fit
+- Its messages are written to the record as they arrive
+- The count and signature are written, and the segment synced, when the batch is committed,
+  so a record without a count was never committed and is skipped when reading
+- Batches of different rounds may arrive interleaved, so each round has its own segment handle,
+  which stays open until the last batch with a record in it is committed or closed
+Only the segments for the most recent rounds are kept
+*/
+
+// messages are written to the segment once this many bytes are pending
+const flushSize = 1 << 16
+
+// the count of a record that is not committed
+const uncommitted = ^uint32(0)
+
+type Log struct {
+	dir            string
+	maxSegmentSize int64
+	retention      int // number of most recent rounds to keep
+
+	round    int              // the most recent round
+	segments map[int]*segment // the open segments, by round
+	dropped  int
+	mu       sync.Mutex
+}
+
+// An open segment file, shared by the batches of its round
+type segment struct {
+	f     *os.File
+	round int
+	size  int64
+	refs  int // batches with a record in the segment that are not committed or closed yet
+}
+
+// An incoming batch, written to the segment of its round as its messages arrive
+// and recorded once the stream signature is checked
+type Batch struct {
+	Metadata  messages.Metadata
+	raw       []byte   // metadata as signed
+	Messages  [][]byte // only for batches read from a segment
+	Signature crypto.Signature
+	log       *Log
+
+	segment       *segment
+	start         int64 // offset of the record in the segment
+	messageLength int
+	count         int
+	written       int    // bytes of messages in the segment
+	pending       []byte // messages not yet written
+	skipped       bool   // the batch is not recorded
+	err           error
+}
+
+func NewLog(dir string, maxSegmentSize int64, retention int) (*Log, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{
+		dir:            dir,
+		maxSegmentSize: maxSegmentSize,
+		retention:      retention,
+		round:          -1,
+		segments:       make(map[int]*segment),
+	}, nil
+}
+
+func (l *Log) SegmentFile(round int) string {
+	return filepath.Join(l.dir, fmt.Sprintf("round%d.audit", round))
+}
+
+// start a batch for the messages following the metadata
+// returns nil if there is no log, which records nothing
+func (l *Log) NewBatch(metadataBytes []byte) *Batch {
+	if l == nil {
+		return nil
+	}
+	b := &Batch{
+		raw: make([]byte, len(metadataBytes)),
+		log: l,
+	}
+	copy(b.raw, metadataBytes)
+	b.Metadata.InterpretFrom(b.raw)
+	return b
+}
+
+// copy the message to the segment, since processing decrypts in place
+func (b *Batch) Add(message []byte) {
+	if b == nil || b.skipped || b.err != nil {
+		return
+	}
+	if b.segment == nil {
+		b.messageLength = len(message)
+		if !b.log.reserve(b) {
+			b.skipped = true
+			return
+		}
+	}
+	if len(message) != b.messageLength || b.count == int(b.Metadata.NumMessages) {
+		// the record has no room for it
+		b.skip()
+		return
+	}
+	b.pending = append(b.pending, message...)
+	b.count++
+	if len(b.pending) >= flushSize {
+		b.flush()
+	}
+}
+
+// stop copying a batch that does not fit its record
+func (b *Batch) skip() {
+	b.skipped = true
+	b.pending = nil
+	b.log.mu.Lock()
+	b.log.dropped++
+	b.log.mu.Unlock()
+	b.Close()
+}
+
+// Release the record of the batch in its segment, once the batch is committed or will not be
+// a batch that was not committed stays unrecorded
+func (b *Batch) Close() {
+	if b == nil || b.segment == nil {
+		return
+	}
+	b.log.release(b.segment)
+	b.segment = nil
+}
+
+func (b *Batch) flush() {
+	if len(b.pending) == 0 {
+		return
+	}
+	_, b.err = b.segment.f.WriteAt(b.pending, b.start+headerSize+int64(b.written))
+	b.written += len(b.pending)
+	b.pending = b.pending[:0]
+}
+
+// record the batch with its signature, and sync the segment
+func (b *Batch) Commit(signature []byte) error {
+	if b == nil {
+		return nil
+	}
+	b.Signature = make([]byte, len(signature))
+	copy(b.Signature, signature)
+	if b.err != nil || b.skipped {
+		return b.err
+	}
+	if b.segment == nil && !b.log.reserve(b) {
+		// no messages, and no room for the record
+		return b.err
+	}
+	defer b.Close()
+	b.flush()
+	if b.err != nil {
+		return b.err
+	}
+	_, err := b.segment.f.WriteAt(b.Signature, b.start+int64(b.Len())-crypto.SIGNATURE_SIZE)
+	if err != nil {
+		return err
+	}
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, uint32(b.count))
+	_, err = b.segment.f.WriteAt(count, b.start+4+messages.Metadata_size)
+	if err != nil {
+		return err
+	}
+	return b.segment.f.Sync()
+}
+
+// check the sender's signature over the batch
+func (b *Batch) Verify(vk *crypto.ExpandedVerificationKey) bool {
+	h := sha512.New()
+	h.Write(b.raw)
+	for _, m := range b.Messages {
+		h.Write(m)
+	}
+	return crypto.PreHashVerify(h, vk, b.Signature)
+}
+
+// length, metadata, and message count and size
+const headerSize = 4 + messages.Metadata_size + 4 + 4
+
+// the length of the record, with room for every message of the metadata
+func (b *Batch) Len() int {
+	return headerSize + int(b.Metadata.NumMessages)*b.messageLength + crypto.SIGNATURE_SIZE
+}
+
+// the header of an uncommitted record
+func (b *Batch) packHeader(buf []byte) {
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(b.Len()-4))
+	pos := 4
+	pos += copy(buf[pos:pos+messages.Metadata_size], b.raw)
+	binary.LittleEndian.PutUint32(buf[pos:pos+4], uncommitted)
+	pos += 4
+	binary.LittleEndian.PutUint32(buf[pos:pos+4], uint32(b.messageLength))
+}
+
+// interpret a committed record without its length prefix
+// the messages are followed by any unused room, then the signature
+func (b *Batch) InterpretFrom(buf []byte) error {
+	if len(buf) < messages.Metadata_size+8+crypto.SIGNATURE_SIZE {
+		return errors.LengthInvalidError()
+	}
+	pos := 0
+	b.raw = buf[pos : pos+messages.Metadata_size]
+	b.Metadata.InterpretFrom(b.raw)
+	pos += messages.Metadata_size
+	numMessages := int(binary.LittleEndian.Uint32(buf[pos : pos+4]))
+	pos += 4
+	b.messageLength = int(binary.LittleEndian.Uint32(buf[pos : pos+4]))
+	pos += 4
+	if numMessages > int(b.Metadata.NumMessages) || len(buf) != headerSize-4+int(b.Metadata.NumMessages)*b.messageLength+crypto.SIGNATURE_SIZE {
+		return errors.LengthInvalidError()
+	}
+	b.Messages = make([][]byte, numMessages)
+	for i := range b.Messages {
+		b.Messages[i] = buf[pos : pos+b.messageLength]
+		pos += b.messageLength
+	}
+	b.Signature = buf[len(buf)-crypto.SIGNATURE_SIZE:]
+	return nil
+}
+
+func committed(buf []byte) bool {
+	pos := messages.Metadata_size
+	return len(buf) >= pos+4 && binary.LittleEndian.Uint32(buf[pos:pos+4]) != uncommitted
+}
+
+// reserve the record of the batch in the segment of its round
+// returns false if the segment is full
+func (l *Log) reserve(b *Batch) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.segments[b.Metadata.Round]
+	if s == nil {
+		var err error
+		s, err = l.openSegment(b.Metadata.Round)
+		if err != nil {
+			b.err = err
+			return false
+		}
+	}
+	buf := make([]byte, headerSize)
+	if s.size+int64(b.Len()) > l.maxSegmentSize {
+		// the segment is full
+		if l.dropped == 0 {
+			log.Printf("Audit segment for round %d is full", s.round)
+		}
+		l.dropped++
+		if s.refs == 0 && s.round != l.round {
+			l.closeSegment(s)
+		}
+		return false
+	}
+	b.packHeader(buf)
+	_, err := s.f.WriteAt(buf, s.size)
+	if err != nil {
+		b.err = err
+		return false
+	}
+	// the segment covers the whole record, even if the batch is never committed
+	err = s.f.Truncate(s.size + int64(b.Len()))
+	if err != nil {
+		b.err = err
+		return false
+	}
+	s.refs++
+	b.segment = s
+	b.start = s.size
+	s.size += int64(b.Len())
+	return true
+}
+
+// close the segment after the last batch of an old round, since no more batches are expected for it
+func (l *Log) release(s *segment) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s.refs--
+	if s.refs == 0 && s.round != l.round {
+		l.closeSegment(s)
+	}
+}
+
+func (l *Log) closeSegment(s *segment) error {
+	if l.segments[s.round] == s {
+		delete(l.segments, s.round)
+	}
+	return s.f.Close()
+}
+
+// open the segment of a round, and when the round is new, close the unused segments of older rounds
+// and remove the segments past retention
+// a late batch of an older round reopens its segment, and adds its record at the end
+func (l *Log) openSegment(round int) (*segment, error) {
+	f, err := os.OpenFile(l.SegmentFile(round), os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &segment{f: f, round: round, size: info.Size()}
+	l.segments[round] = s
+	if round < l.round {
+		return s, nil
+	}
+	l.round = round
+	l.dropped = 0
+	for _, old := range l.segments {
+		if old.round != round && old.refs == 0 {
+			l.closeSegment(old)
+		}
+	}
+	if l.retention <= 0 {
+		// keep everything
+		return s, nil
+	}
+	for old := round - l.retention; old >= 0; old-- {
+		err = os.Remove(l.SegmentFile(old))
+		if os.IsNotExist(err) {
+			break
+		}
+	}
+	return s, nil
+}
+
+// Number of batches that did not fit in the current segment
+func (l *Log) Dropped() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dropped
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var err error
+	for _, s := range l.segments {
+		if e := l.closeSegment(s); e != nil {
+			err = e
+		}
+	}
+	l.round = -1
+	return err
+}
+
+// Read all batches stored for a round
+func ReadSegment(fn string) ([]*Batch, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	batches := make([]*Batch, 0)
+	length := make([]byte, 4)
+	for {
+		_, err = io.ReadFull(r, length)
+		if err == io.EOF {
+			return batches, nil
+		} else if err != nil {
+			return batches, err
+		}
+		buf := make([]byte, binary.LittleEndian.Uint32(length))
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return batches, err
+		}
+		if !committed(buf) {
+			continue
+		}
+		b := &Batch{}
+		err = b.InterpretFrom(buf)
+		if err != nil {
+			return batches, err
+		}
+		batches = append(batches, b)
+	}
+}
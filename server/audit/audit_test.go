@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"crypto/rand"
+	"os"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/network/messages"
+)
+
+func signedBatch(l *Log, round int, numMessages int, key crypto.SigningKey) *Batch {
+	m := messages.Metadata{
+		Type:        messages.NetworkMessage_ServerMessageForward,
+		Round:       round,
+		Layer:       1,
+		Sender:      2,
+		NumMessages: uint32(numMessages),
+	}
+	raw := make([]byte, messages.Metadata_size)
+	m.PackTo(raw)
+	signed := append([]byte{}, raw...)
+	b := l.NewBatch(raw)
+	for i := 0; i < numMessages; i++ {
+		message := make([]byte, 100)
+		rand.Read(message)
+		b.Add(message)
+		signed = append(signed, message...)
+	}
+	b.Signature = crypto.PreHashSign(signed, key)
+	return b
+}
+
+func TestAuditLog(t *testing.T) {
+	vk, sk := crypto.NewSigningKeyPair()
+	evk, _ := vk.ExpandKey()
+	l, err := NewLog(t.TempDir(), 10000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for round := 0; round < 3; round++ {
+		b := signedBatch(l, round, 10, sk)
+		err = b.Commit(b.Signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(l.SegmentFile(0)); !os.IsNotExist(err) {
+		t.Fatalf("Segment past retention was not removed")
+	}
+	batches, err := ReadSegment(l.SegmentFile(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || len(batches[0].Messages) != 10 || batches[0].Metadata.Round != 2 {
+		t.Fatalf("Batch not read back")
+	}
+	if !batches[0].Verify(evk) {
+		t.Fatalf("Stored batch did not verify")
+	}
+	batches[0].Messages[3][0] ^= 1
+	if batches[0].Verify(evk) {
+		t.Fatalf("Modified batch verified")
+	}
+
+	// the segment is bounded
+	for i := 0; i < 10; i++ {
+		b := signedBatch(l, 2, 10, sk)
+		err = b.Commit(b.Signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.Dropped() == 0 {
+		t.Fatalf("Segment was not bounded")
+	}
+	info, _ := os.Stat(l.SegmentFile(2))
+	if info.Size() > 10000 {
+		t.Fatalf("Segment exceeds bound")
+	}
+}
+
+func TestAuditStream(t *testing.T) {
+	vk, sk := crypto.NewSigningKeyPair()
+	evk, _ := vk.ExpandKey()
+	l, err := NewLog(t.TempDir(), 100000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// a batch whose stream failed is never committed
+	signedBatch(l, 0, 10, sk)
+	b := signedBatch(l, 0, 10, sk)
+	if len(b.Messages) != 0 {
+		t.Fatalf("Messages kept in memory")
+	}
+	err = b.Commit(b.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a batch with more messages than its metadata is not recorded
+	extra := signedBatch(l, 0, 5, sk)
+	extra.Add(make([]byte, 100))
+	err = extra.Commit(extra.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batches, err := ReadSegment(l.SegmentFile(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || !batches[0].Verify(evk) {
+		t.Fatalf("Only the committed batch should be read back")
+	}
+}
+
+func TestAuditInterleavedRounds(t *testing.T) {
+	vk, sk := crypto.NewSigningKeyPair()
+	evk, _ := vk.ExpandKey()
+	l, err := NewLog(t.TempDir(), 100000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// the late batches of round 0 arrive while round 1 has started
+	batches := []*Batch{
+		signedBatch(l, 0, 10, sk),
+		signedBatch(l, 1, 10, sk),
+		signedBatch(l, 0, 10, sk),
+		signedBatch(l, 1, 10, sk),
+	}
+	// a batch whose stream failed releases its segment without being recorded
+	signedBatch(l, 0, 10, sk).Close()
+	for _, b := range batches {
+		err = b.Commit(b.Signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for round := 0; round < 2; round++ {
+		read, err := ReadSegment(l.SegmentFile(round))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(read) != 2 || !read[0].Verify(evk) || !read[1].Verify(evk) {
+			t.Fatalf("Batches of round %d not read back", round)
+		}
+	}
+	// only the segment of the most recent round is still open
+	if len(l.segments) != 1 || l.segments[1] == nil {
+		t.Fatalf("Segments of old rounds left open")
+	}
+	signedBatch(l, 2, 10, sk).Close()
+	if len(l.segments) != 1 || l.segments[2] == nil {
+		t.Fatalf("Segment of the last round left open")
+	}
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/simonlangowski/lightning1/server/audit"
+)
+
+// Record the signed batches received from other servers in dir
+// so disputed rounds can be investigated after the fact
+func (s *Server) SetAuditLog(dir string, maxSegmentSize int64, retention int) error {
+	l, err := audit.NewLog(dir, maxSegmentSize, retention)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = l
+	return nil
+}
@@ -0,0 +1,264 @@
+package beacon
+
+import (
+	"crypto/rand"
+	"log"
+	"sync"
+
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/commitments"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/network/synchronization"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+/*
+Commit-reveal randomness beacon run by all of the servers, to choose the anytrust groups of each epoch
+1. Each server picks a random value, and sends a signed hash commitment to it to every server,
+   with the epoch and the parameters of the groups, so the output cannot be reused for other groups
+2. Once a server has the commitment of every server, it sends every server its value and the opening
+3. The output is the hash of the epoch and every value, in the order of the server ids
+
+The output is unbiased if at least one server is honest, since every other value is fixed before that server reveals
+A server can still stop the beacon by not revealing, but it cannot choose between outputs, and the other servers know who it is
+The transcript holds each server's signature on its commitment, so anyone with servers.json can check it
+and recompute the groups (see transcript.go)
+A server runs each epoch once, and a verifier only accepts the epoch after the groups it has,
+so the coordinator cannot run an epoch again until it likes the groups
+*/
+
+// steps of the beacon, sent in the layer field
+const (
+	COMMIT_STEP = 0
+	REVEAL_STEP = 1
+	DONE_STEP   = 2
+)
+
+type Beacon struct {
+	c        *common.CommonState
+	runs     map[int]*run // by epoch
+	finished int          // the last epoch that was output
+	mu       sync.Mutex
+}
+
+// the state of the beacon for one epoch
+type run struct {
+	epoch      int
+	value      []byte
+	commitment *commitments.Commitment
+	opening    *commitments.CommitmentOpening
+
+	contributions []*coord.BeaconContribution // by server id
+	parameters    []BeaconCommitMessage       // the group parameters each server committed with, by server id
+	failed        []int64                     // servers whose values did not match their commitments
+	sync          *synchronization.Synchronizer
+	mu            sync.Mutex
+}
+
+func NewBeacon(c *common.CommonState) *Beacon {
+	return &Beacon{
+		c:        c,
+		runs:     make(map[int]*run),
+		finished: -1,
+	}
+}
+
+// the run of an epoch is started by whichever comes first, the coordinator or a message from another server
+func (b *Beacon) getRun(epoch int) *run {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if epoch <= b.finished {
+		return nil
+	}
+	r := b.runs[epoch]
+	if r == nil {
+		r = b.newRun(epoch)
+		b.runs[epoch] = r
+	}
+	return r
+}
+
+func (b *Beacon) newRun(epoch int) *run {
+	r := &run{
+		epoch:         epoch,
+		value:         make([]byte, VALUE_SIZE),
+		contributions: make([]*coord.BeaconContribution, b.c.NumServers),
+		parameters:    make([]BeaconCommitMessage, b.c.NumServers),
+		failed:        make([]int64, 0),
+	}
+	_, err := rand.Read(r.value)
+	if err != nil {
+		panic(err)
+	}
+	err, r.commitment, r.opening = commitments.MakeCommitment(r.value)
+	if err != nil {
+		panic(err)
+	}
+	r.sync = synchronization.NewSynchronizer(epoch, COMMIT_STEP, b.c.NumServers, b)
+	return r
+}
+
+func (b *Beacon) OnThreshold(step int) (int, int) {
+	return b.c.NumServers, step + 1
+}
+
+// Run the beacon for an epoch with the other servers
+// the request sets the epoch and the parameters of the groups, and is returned with the contributions of every server
+func (b *Beacon) Run(c *network.Caller, request *coord.BeaconTranscript) (*coord.BeaconTranscript, error) {
+	r := b.getRun(int(request.Epoch))
+	if r == nil {
+		return nil, errors.BadMetadataError()
+	}
+	commit := BeaconCommitMessage{
+		NumGroups:  int(request.NumGroups),
+		GroupSize:  int(request.GroupSize),
+		Commitment: *r.commitment,
+	}
+	m := messages.NewSignedMessage(commit.Len(), r.epoch, COMMIT_STEP, b.c.MyId, 0, 0, 1, messages.NetworkMessage_BeaconPush)
+	commit.PackTo(m.Data)
+	b.c.Sign(m)
+	err := b.broadcast(c, m)
+	if err != nil {
+		return nil, err
+	}
+	// reveal only after every server has committed
+	r.sync.Sync(REVEAL_STEP)
+	reveal := BeaconRevealMessage{Opening: *r.opening}
+	copy(reveal.Value[:], r.value)
+	m = messages.NewSignedMessage(reveal.Len(), r.epoch, REVEAL_STEP, b.c.MyId, 0, 0, 1, messages.NetworkMessage_BeaconPush)
+	reveal.PackTo(m.Data)
+	b.c.Sign(m)
+	err = b.broadcast(c, m)
+	if err != nil {
+		return nil, err
+	}
+	r.sync.Sync(DONE_STEP)
+
+	b.mu.Lock()
+	delete(b.runs, r.epoch)
+	b.finished = r.epoch
+	b.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.failed) > 0 {
+		log.Printf("%d: beacon failed, values from servers %v did not match their commitments", b.c.MyId, r.failed)
+		return nil, errors.CommitFailure()
+	}
+	for sid, p := range r.parameters {
+		if p.NumGroups != commit.NumGroups || p.GroupSize != commit.GroupSize {
+			// the coordinator asked the servers for different groups
+			log.Printf("%d: server %d committed to %d groups of %d in epoch %d", b.c.MyId, sid, p.NumGroups, p.GroupSize, r.epoch)
+			return nil, errors.GroupAgreementError()
+		}
+	}
+	t := &coord.BeaconTranscript{
+		Epoch:         request.Epoch,
+		NumGroups:     request.NumGroups,
+		GroupSize:     request.GroupSize,
+		Contributions: make(map[int64]*coord.BeaconContribution),
+	}
+	for sid, contribution := range r.contributions {
+		t.Contributions[int64(sid)] = contribution
+	}
+	return t, nil
+}
+
+// send the same message to every server, including this one
+func (b *Beacon) broadcast(c *network.Caller, m *messages.SignedMessage) error {
+	done := make(chan error)
+	for sid := 0; sid < b.c.NumServers; sid++ {
+		go func(sid int) {
+			_, err := c.SendSignedMessage(sid, m)
+			done <- err
+		}(sid)
+	}
+	var err error
+	for sid := 0; sid < b.c.NumServers; sid++ {
+		e := <-done
+		if e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (b *Beacon) ReceiveBeacon(m *messages.SignedMessage) error {
+	if m.Sender < 0 || m.Sender >= b.c.NumServers {
+		return errors.BadMetadataError()
+	}
+	if !crypto.Verify(b.c.VerificationKeys[m.Sender], m.GetSignedData(), m.Signature) {
+		return errors.SignatureError()
+	}
+	r := b.getRun(m.Round)
+	if r == nil {
+		return errors.BadMetadataError()
+	}
+	switch m.Layer {
+	case COMMIT_STEP:
+		return b.receiveCommitment(r, m)
+	case REVEAL_STEP:
+		return b.receiveReveal(r, m)
+	}
+	return errors.BadMetadataError()
+}
+
+func (b *Beacon) receiveCommitment(r *run, m *messages.SignedMessage) error {
+	err := r.sync.SyncOnce(COMMIT_STEP, m.Sender)
+	if err != nil {
+		return err
+	}
+	defer r.sync.Done(COMMIT_STEP, m.Sender)
+	commit := &BeaconCommitMessage{}
+	err = commit.InterpretFrom(m.Data)
+	if err != nil {
+		// the reveal from this server will not open
+		return err
+	}
+	contribution := &coord.BeaconContribution{
+		Commitment: make([]byte, commitments.COMMIT_SIZE),
+		Signature:  make([]byte, len(m.Signature)),
+	}
+	commit.Commitment.PackTo(contribution.Commitment)
+	copy(contribution.Signature, m.Signature)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.contributions[m.Sender] = contribution
+	r.parameters[m.Sender] = *commit
+	return nil
+}
+
+func (b *Beacon) receiveReveal(r *run, m *messages.SignedMessage) error {
+	// blocks until every commitment is received
+	err := r.sync.SyncOnce(REVEAL_STEP, m.Sender)
+	if err != nil {
+		return err
+	}
+	defer r.sync.Done(REVEAL_STEP, m.Sender)
+	reveal := &BeaconRevealMessage{}
+	err = reveal.InterpretFrom(m.Data)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	contribution := r.contributions[m.Sender]
+	if err != nil || contribution == nil || !openContribution(contribution, &reveal.Opening, reveal.Value[:]) {
+		log.Printf("%d: beacon value from %d does not match its commitment", b.c.MyId, m.Sender)
+		r.failed = append(r.failed, int64(m.Sender))
+		return err
+	}
+	contribution.Value = make([]byte, VALUE_SIZE)
+	copy(contribution.Value, reveal.Value[:])
+	contribution.Opening = make([]byte, commitments.OPENING_SIZE)
+	reveal.Opening.PackTo(contribution.Opening)
+	return nil
+}
+
+func openContribution(contribution *coord.BeaconContribution, opening *commitments.CommitmentOpening, value []byte) bool {
+	if len(contribution.Commitment) != commitments.COMMIT_SIZE {
+		return false
+	}
+	commitment := &commitments.Commitment{}
+	commitment.InterpretFrom(contribution.Commitment)
+	return commitment.Open(opening, value)
+}
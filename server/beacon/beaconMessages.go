@@ -0,0 +1,65 @@
+package beacon
+
+import (
+	"encoding/binary"
+
+	"github.com/simonlangowski/lightning1/crypto/commitments"
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+const VALUE_SIZE = 32
+
+// A server's hash commitment to its random value, with the parameters of the groups the output chooses
+type BeaconCommitMessage struct {
+	NumGroups  int
+	GroupSize  int
+	Commitment commitments.Commitment
+}
+
+// A server's random value, sent once every server has committed
+type BeaconRevealMessage struct {
+	Value   [VALUE_SIZE]byte
+	Opening commitments.CommitmentOpening
+}
+
+func (b *BeaconCommitMessage) Len() int {
+	return 4 + 4 + commitments.COMMIT_SIZE
+}
+
+func (b *BeaconCommitMessage) PackTo(d []byte) {
+	if len(d) != b.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	binary.LittleEndian.PutUint32(d[0:4], uint32(b.NumGroups))
+	binary.LittleEndian.PutUint32(d[4:8], uint32(b.GroupSize))
+	b.Commitment.PackTo(d[8:])
+}
+
+func (b *BeaconCommitMessage) InterpretFrom(d []byte) error {
+	if len(d) != b.Len() {
+		return errors.Rejected("length")
+	}
+	b.NumGroups = int(binary.LittleEndian.Uint32(d[0:4]))
+	b.GroupSize = int(binary.LittleEndian.Uint32(d[4:8]))
+	return b.Commitment.InterpretFrom(d[8:])
+}
+
+func (b *BeaconRevealMessage) Len() int {
+	return VALUE_SIZE + commitments.OPENING_SIZE
+}
+
+func (b *BeaconRevealMessage) PackTo(d []byte) {
+	if len(d) != b.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	copy(d[:VALUE_SIZE], b.Value[:])
+	b.Opening.PackTo(d[VALUE_SIZE:])
+}
+
+func (b *BeaconRevealMessage) InterpretFrom(d []byte) error {
+	if len(d) != b.Len() {
+		return errors.LengthInvalidError()
+	}
+	copy(b.Value[:], d[:VALUE_SIZE])
+	return b.Opening.InterpretFrom(d[VALUE_SIZE:])
+}
@@ -0,0 +1,121 @@
+package beacon
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/network"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+	"google.golang.org/protobuf/proto"
+)
+
+type MockBeaconHandler struct {
+	b *Beacon
+	t *testing.T
+	messages.UnimplementedMessageHandlersServer
+}
+
+func (m *MockBeaconHandler) HandleSignedMessage(_ context.Context, raw *messages.NetworkMessage) (*messages.NetworkMessage, error) {
+	message := messages.ParseSignedMessage(raw)
+	if message.Type != messages.NetworkMessage_BeaconPush {
+		m.t.FailNow()
+	}
+	return &messages.NetworkMessage{}, m.b.ReceiveBeacon(message)
+}
+
+func runBeacon(t *testing.T, beacons []*Beacon, handlers []messages.MessageHandlersServer, request *coord.BeaconTranscript) *coord.BeaconTranscript {
+	type result struct {
+		transcript *coord.BeaconTranscript
+		err        error
+	}
+	done := make(chan result)
+	for _, b := range beacons {
+		go func(b *Beacon) {
+			transcript, err := b.Run(network.NewMockCaller(handlers), request)
+			done <- result{transcript, err}
+		}(b)
+	}
+	var transcript *coord.BeaconTranscript
+	for range beacons {
+		r := <-done
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if transcript == nil {
+			transcript = r.transcript
+		} else if !proto.Equal(transcript, r.transcript) {
+			t.Fatalf("Servers output different transcripts")
+		}
+	}
+	return transcript
+}
+
+func TestBeacon(t *testing.T) {
+	numServers := 5
+	states := common.NewMockCommonStates(numServers, &common.CommonState{
+		NumServers: numServers,
+	})
+	beacons := make([]*Beacon, numServers)
+	handlers := make([]messages.MessageHandlersServer, numServers)
+	servers := make(map[int64]*config.Server)
+	for i := range beacons {
+		beacons[i] = NewBeacon(states[i])
+		handlers[i] = &MockBeaconHandler{b: beacons[i], t: t}
+		servers[int64(i)] = &config.Server{Id: int64(i), VerificationKey: states[i].VerificationKeys[i]}
+	}
+	request := &coord.BeaconTranscript{Epoch: 1, NumGroups: 2, GroupSize: 2}
+	transcript := runBeacon(t, beacons, handlers, request)
+	if len(transcript.Contributions) != numServers || transcript.NumGroups != 2 {
+		t.Fatalf("Wrong transcript")
+	}
+	err := Verify(transcript, servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// anyone can recompute the groups from the transcript
+	groups := Groups(transcript)
+	if len(groups) != 2 || len(groups[0].Servers) != 2 {
+		t.Fatalf("Wrong groups")
+	}
+	b, err := proto.Marshal(transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := &coord.BeaconTranscript{}
+	err = proto.Unmarshal(b, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyGroups(read, 0, servers, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only as the epoch after the groups in use
+	if VerifyGroups(read, 1, servers, groups) == nil {
+		t.Fatalf("Groups of an old epoch verified")
+	}
+	// the output cannot be reused for other groups
+	read.NumGroups = 1
+	read.GroupSize = 4
+	if Verify(read, servers) == nil {
+		t.Fatalf("Transcript verified with other group parameters")
+	}
+
+	// every epoch has new randomness
+	next := runBeacon(t, beacons, handlers, &coord.BeaconTranscript{Epoch: 2, NumGroups: 2, GroupSize: 2})
+	if bytes.Equal(Output(transcript), Output(next)) {
+		t.Fatalf("Same output for different epochs")
+	}
+
+	// a value that was not committed to does not verify
+	next.Contributions[3].Value[0] ^= 1
+	err = Verify(next, servers)
+	if err == nil {
+		t.Fatalf("Changed value verified")
+	}
+}
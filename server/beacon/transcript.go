@@ -0,0 +1,101 @@
+package beacon
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/commitments"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+)
+
+// The hash of the epoch and the value of every server
+// the transcript must already be verified
+func Output(t *coord.BeaconTranscript) []byte {
+	h := sha256.New()
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(t.Epoch))
+	h.Write(b)
+	for sid := 0; sid < len(t.Contributions); sid++ {
+		h.Write(t.Contributions[int64(sid)].Value)
+	}
+	return h.Sum(nil)
+}
+
+// A PRG seeded with the output, to choose the groups
+func Shuffler(output []byte) *config.Shuffler {
+	h := sha3.NewShake128()
+	h.Write(output)
+	return config.NewPRGShuffler(h)
+}
+
+// Check that every server signed its commitment with the epoch and group parameters of the transcript,
+// and that every value opens the commitment
+func Verify(t *coord.BeaconTranscript, servers map[int64]*config.Server) error {
+	if len(t.Contributions) != len(servers) {
+		return errors.Rejected("missing contribution")
+	}
+	for sid, s := range servers {
+		contribution := t.Contributions[sid]
+		if contribution == nil {
+			return errors.Rejected("missing contribution")
+		}
+		// the commitment message is the same for every destination
+		commit := BeaconCommitMessage{NumGroups: int(t.NumGroups), GroupSize: int(t.GroupSize)}
+		if len(contribution.Commitment) != commitments.COMMIT_SIZE {
+			return errors.Rejected("length")
+		}
+		commit.Commitment.InterpretFrom(contribution.Commitment)
+		m := messages.NewSignedMessage(commit.Len(), int(t.Epoch), COMMIT_STEP, int(sid), 0, 0, 1, messages.NetworkMessage_BeaconPush)
+		commit.PackTo(m.Data)
+		if !crypto.Verify(s.VerificationKey, m.GetSignedData(), contribution.Signature) {
+			return errors.Rejected("signature")
+		}
+		opening := &commitments.CommitmentOpening{}
+		if len(contribution.Opening) != commitments.OPENING_SIZE || len(contribution.Value) != VALUE_SIZE {
+			return errors.Rejected("length")
+		}
+		opening.InterpretFrom(contribution.Opening)
+		if !openContribution(contribution, opening, contribution.Value) {
+			return errors.Rejected("opening")
+		}
+	}
+	return nil
+}
+
+// The groups of the epoch, chosen with the output of the beacon
+func Groups(t *coord.BeaconTranscript) map[int64]*config.Group {
+	serverIds := make([]int64, len(t.Contributions))
+	for i := range serverIds {
+		serverIds[i] = int64(i)
+	}
+	return config.CreateSeparateGroupsFromShuffler(int(t.NumGroups), int(t.GroupSize), serverIds, Shuffler(Output(t)))
+}
+
+// Check the transcript is of the epoch after previous, the epoch of the groups in use,
+// and that the groups are the ones it chose, so that anyone can recompute groups.json
+func VerifyGroups(t *coord.BeaconTranscript, previous int64, servers map[int64]*config.Server, groups map[int64]*config.Group) error {
+	if t.Epoch != previous+1 {
+		return errors.Rejected("epoch")
+	}
+	err := Verify(t, servers)
+	if err != nil {
+		return err
+	}
+	expected := Groups(t)
+	if len(expected) != len(groups) {
+		return errors.Rejected("groups")
+	}
+	for gid, g := range expected {
+		if !proto.Equal(g, groups[gid]) {
+			return errors.Rejected("groups")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/simonlangowski/lightning1/bulletin"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/blame"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/prepareMessages"
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+// the digest and signature of a batch received from a server
+type batchEvidence struct {
+	hash      []byte
+	signature crypto.Signature
+}
+
+type receivedBatch struct {
+	layer, sender int
+}
+
+// Keep the digest and signature of a batch that arrived, as evidence of what its sender sent
+func (s *Server) recordReceived(layer, sender int, hash []byte, signature crypto.Signature) {
+	s.blameLock.Lock()
+	defer s.blameLock.Unlock()
+	// copied, since the buffer belongs to the connection reader
+	s.received[receivedBatch{layer, sender}] = batchEvidence{hash, append(crypto.Signature(nil), signature...)}
+}
+
+// Find who dropped the messages missing from this layer, continue the round without the users
+// whose messages were dropped, and record accusations once the upstream servers answer
+func (s *Server) blameMissingMessages(layer int) {
+	// boomerang messages arrive from the next server
+	reverse := s.pathRound
+	missing := s.onionParsers[layer].MissingKeys()
+	countDropped(layer, len(missing), "missing")
+	upstream := make(map[int][]crypto.LookupKey)
+	revoked := make([]*processMessages.BootstrapKey, 0, len(missing))
+	for _, k := range missing {
+		if reverse {
+			upstream[k.NextServer] = append(upstream[k.NextServer], k.OutgoingVerificationKey.LookupKey())
+		} else if layer > 0 {
+			upstream[k.PrevServer] = append(upstream[k.PrevServer], k.VerificationKey.LookupKey())
+		}
+		// in the first lightning layer the client simply did not submit
+		l := k.VerificationKey.LookupKey()
+		if b := s.Keys[layer].RevokeKey(&l, false); b != nil {
+			revoked = append(revoked, b)
+		}
+	}
+	// delivered before the batch of this layer is sent, so the later servers on the paths skip the users instead of blaming this server
+	s.propagateRevocation(layer, revoked, true, true, &s.revocations)
+	// resets the usage now that the counts match
+	s.onionParsers[layer].AllKeysAccountedFor()
+	s.checkpointKeys(layer)
+
+	// the requests are made here, since the round moves on while the upstream servers are asked
+	round := s.CommonState.Round
+	requests := make(map[int]*messages.SignedMessage)
+	batches := make(map[int]batchEvidence)
+	s.blameLock.Lock()
+	for sid, keys := range upstream {
+		// a dropped server is not asked, since it did not send anything
+		if !s.CommonState.IsDropped(sid) {
+			requests[sid] = blame.NewBlameRequest(s.CommonState, layer, sid, reverse, keys)
+		}
+		batches[sid] = s.received[receivedBatch{layer, sid}]
+	}
+	s.blameLock.Unlock()
+	// a slow upstream server must not hold up the layer
+	go s.accuse(round, layer, upstream, requests, batches)
+}
+
+// Ask each upstream server for evidence, and record an accusation against it for its missing keys
+func (s *Server) accuse(round, layer int, upstream map[int][]crypto.LookupKey, requests map[int]*messages.SignedMessage, batches map[int]batchEvidence) {
+	wg := sync.WaitGroup{}
+	for sid, keys := range upstream {
+		wg.Add(1)
+		go func(sid int, keys []crypto.LookupKey) {
+			defer wg.Done()
+			var evidence *messages.SignedMessage
+			if req := requests[sid]; req != nil {
+				var err error
+				evidence, err = s.Caller.SendSignedMessage(sid, req)
+				if err != nil {
+					evidence = nil
+				}
+			}
+			b := batches[sid]
+			a := blame.NewAccusation(s.CommonState, round, layer, sid, keys, evidence, b.hash, b.signature)
+			log.Printf("%d: server %d dropped %d messages in round %d layer %d", s.CommonState.MyId, sid, len(a.Keys), a.Round, a.Layer)
+			s.blameLock.Lock()
+			s.accusations = append(s.accusations, a)
+			s.blameLock.Unlock()
+		}(sid, keys)
+	}
+	wg.Wait()
+}
+
+// Revoke the paths of the messages that did not fit in their links in this layer,
+// so that the servers on them skip the users instead of waiting for or blaming this server
+func (s *Server) revokeOverflowed(layer int, overflowed []*processMessages.BootstrapKey, forward bool) {
+	if len(overflowed) == 0 {
+		return
+	}
+	n := s.revokeDropped(layer, overflowed, forward, "overflow")
+	log.Printf("%d: dropped %d messages that overflowed their links in round %d layer %d", s.CommonState.MyId, n, s.CommonState.Round, layer)
+	s.blameLock.Lock()
+	s.overflowed += n
+	s.blameLock.Unlock()
+}
+
+// Revoke the paths of messages this server dropped at the layer, and pass the revocation along them
+// returns the number of paths revoked
+func (s *Server) revokeDropped(layer int, dropped []*processMessages.BootstrapKey, forward bool, reason string) int {
+	revoked := make([]*processMessages.BootstrapKey, 0, len(dropped))
+	for _, k := range dropped {
+		l := k.VerificationKey.LookupKey()
+		if b := s.Keys[layer].RevokeKey(&l, false); b != nil {
+			revoked = append(revoked, b)
+		}
+	}
+	countDropped(layer, len(revoked), reason)
+	s.propagateRevocation(layer, revoked, forward, true, &s.revocations)
+	return len(revoked)
+}
+
+// Pass the revocation of keys removed from the table of layer along their paths
+// forward to the next servers and backward to the previous servers, as set
+// the keys are collected by the caller, under whatever lock it holds, and the rpcs are sent asynchronously as in accuse,
+// so no lock is held while the neighbours answer; wait on wg to know they were delivered
+func (s *Server) propagateRevocation(layer int, revoked []*processMessages.BootstrapKey, forward, backward bool, wg *sync.WaitGroup) {
+	next := make(map[int][]crypto.LookupKey)
+	prev := make(map[int][]crypto.LookupKey)
+	for _, b := range revoked {
+		if forward && layer+1 < len(s.Keys) {
+			next[b.NextServer] = append(next[b.NextServer], b.OutgoingVerificationKey.LookupKey())
+		}
+		// the previous server of the first layer is the client
+		if backward && layer > 0 {
+			prev[b.PrevServer] = append(prev[b.PrevServer], b.VerificationKey.LookupKey())
+		}
+	}
+	for sid, keys := range next {
+		wg.Add(1)
+		go func(sid int, keys []crypto.LookupKey) {
+			defer wg.Done()
+			s.sendRevocation(layer+1, sid, false, keys)
+		}(sid, keys)
+	}
+	for sid, keys := range prev {
+		wg.Add(1)
+		go func(sid int, keys []crypto.LookupKey) {
+			defer wg.Done()
+			s.sendRevocation(layer-1, sid, true, keys)
+		}(sid, keys)
+	}
+}
+
+func (s *Server) sendRevocation(layer, sid int, reverse bool, keys []crypto.LookupKey) {
+	if s.CommonState.IsDropped(sid) {
+		return
+	}
+	m := blame.NewRevocation(s.CommonState, layer, sid, reverse, keys)
+	_, err := s.Caller.SendSignedMessage(sid, m)
+	if err != nil {
+		// the server will blame this link for the messages instead
+		log.Printf("%d: could not revoke %d keys at server %d: %v", s.CommonState.MyId, len(keys), sid, err)
+	}
+}
+
+// Revoke the keys a neighbouring server revoked, and continue away from it along their paths
+func (s *Server) HandleRevocation(m *messages.SignedMessage) error {
+	reverse, revoked, err := blame.ApplyRevocation(s.CommonState, m, s.Keys)
+	if err != nil {
+		return err
+	}
+	// no lock is held, and the sender waits until the revocation has gone along the rest of the paths
+	wg := sync.WaitGroup{}
+	s.propagateRevocation(m.Layer, revoked, !reverse, reverse, &wg)
+	wg.Wait()
+	return nil
+}
+
+// Give evidence to a server that is missing messages it expected from this server
+func (s *Server) HandleBlameRequest(m *messages.SignedMessage) (*messages.SignedMessage, error) {
+	return blame.RespondToRequest(s.CommonState, m, s.Keys, s.TcpConnections.SentDigest(m.Round, m.Layer, m.Sender))
+}
+
+// Prove the decryption of an envelope of a disputed path
+func (s *Server) HandleTraceback(m *messages.SignedMessage) (*messages.SignedMessage, error) {
+	return blame.RespondToTraceback(s.CommonState, m)
+}
+
+// Trace the path a client of one of this server's groups complained about,
+// and have its first server revoke it, unless the client malformed it
+func (s *Server) HandleComplaint(m *messages.SignedMessage) error {
+	err := s.GroupAliases[m.Group].messagePreparer.VerifyClient(int64(m.Sender), m)
+	if err != nil {
+		return err
+	}
+	complaint := prepareMessages.Complaint{}
+	err = complaint.InterpretFrom(m.Data)
+	if err != nil {
+		return err
+	}
+	// the layer is the disputed path establishment round
+	if m.Layer < 0 || m.Layer >= s.CommonState.NumLayers {
+		return errors.Rejected("layer")
+	}
+	// only the client that submitted the envelope can complain about it
+	if !blame.VerifyComplaint(s.CommonState, m.Sender, int(m.Group), m.Layer, &complaint) {
+		return errors.Rejected("envelope of another client")
+	}
+	e := common.PathEstablishmentEnvelope{}
+	e.InterpretFrom(complaint.Envelope)
+	tokenHash := e.InToken.Hash()
+	first := int(s.CommonState.HashToServer(&tokenHash))
+	// the receipt the first server signed for the client in the disputed round
+	var receipt *messages.SignedMessage
+	if len(complaint.Receipt) > 0 {
+		receipt = bulletin.ParseSubmission(complaint.Receipt)
+		if receipt == nil || receipt.Type != messages.NetworkMessage_ClientGetReceipt || receipt.Sender != first ||
+			receipt.Dest != m.Sender || receipt.Round != m.Layer || !s.CommonState.Verify(receipt) {
+			return errors.Rejected("receipt")
+		}
+	}
+	// the layers up to the disputed round are established
+	v := blame.Traceback(s.CommonState, m.Sender, int(m.Group), m.Layer, &complaint, m.Layer+1, s.Caller.SendSignedMessage)
+	v.Receipt = receipt
+	s.blameLock.Lock()
+	s.verdicts = append(s.verdicts, v)
+	s.blameLock.Unlock()
+	if v.ClientBlamed() && !v.WellFormed() {
+		return errors.RejectedComplaint(m.Sender, v.Layer)
+	}
+	if !v.ClientBlamed() {
+		log.Printf("%d: server %d broke the path of client %d in round %d layer %d", s.CommonState.MyId, v.Server, m.Sender, v.Round, v.Layer)
+	}
+	r := blame.NewPathRepair(s.CommonState, int(m.Group), first, m.Sender, e.InKey.LookupKey())
+	if first == s.CommonState.MyId {
+		return s.HandlePathRepair(r)
+	} else if s.CommonState.IsDropped(first) {
+		// its paths are already gone
+		return nil
+	}
+	_, err = s.Caller.SendSignedMessage(first, r)
+	return err
+}
+
+// Revoke the path a member of the client's group asked to repair, and continue along it
+func (s *Server) HandlePathRepair(m *messages.SignedMessage) error {
+	if len(s.Keys) == 0 {
+		return errors.Rejected("no paths")
+	}
+	b, err := blame.ApplyPathRepair(s.CommonState, m, s.Keys[0], s.repairs)
+	if err != nil || b == nil {
+		return err
+	}
+	countDropped(0, 1, "complaint")
+	wg := sync.WaitGroup{}
+	s.propagateRevocation(0, []*processMessages.BootstrapKey{b}, true, false, &wg)
+	wg.Wait()
+	return nil
+}
+
+// tracebacks of complaints handled by this server
+func (s *Server) Verdicts() []*blame.Verdict {
+	s.blameLock.Lock()
+	defer s.blameLock.Unlock()
+	return s.verdicts
+}
+
+// accusations made by this server
+func (s *Server) Accusations() []*blame.Accusation {
+	s.blameLock.Lock()
+	defer s.blameLock.Unlock()
+	return s.accusations
+}
+
+// Called by the coordinator between rounds, on every server
+// Revoked clients get no more tokens, and the first server on each of their paths revokes the path
+// Revoked keys of a layer are revoked by the server holding them, and the rest of their paths
+func (s *Server) Revoke(_ context.Context, r *coord.Revocation) (*coord.Empty, error) {
+	// checked before anything is revoked, so a bad request revokes nothing
+	if len(r.Keys) > 0 && len(s.Keys) > 0 && (r.Layer < 0 || int(r.Layer) >= len(s.Keys)) {
+		return nil, errors.Rejected("layer")
+	}
+	for _, k := range r.Keys {
+		if len(k) != len(crypto.LookupKey{}) {
+			return nil, errors.Rejected("key length")
+		}
+	}
+	for _, g := range s.GroupAliases {
+		for _, id := range r.Clients {
+			g.messagePreparer.RevokeClient(id)
+		}
+	}
+	if len(s.Keys) == 0 {
+		// no paths yet
+		return &coord.Empty{}, nil
+	}
+	revoked := make([]*processMessages.BootstrapKey, 0)
+	for _, id := range r.Clients {
+		for _, l := range s.Keys[0].KeysFrom(int(id)) {
+			if b := s.Keys[0].RevokeKey(&l, false); b != nil {
+				revoked = append(revoked, b)
+			}
+		}
+	}
+	// the coordinator starts the next round once every server returns, so the revocations are delivered first
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	s.propagateRevocation(0, revoked, true, false, &wg)
+	if len(r.Keys) == 0 {
+		return &coord.Empty{}, nil
+	}
+	layer := int(r.Layer)
+	revoked = make([]*processMessages.BootstrapKey, 0)
+	for _, k := range r.Keys {
+		l := crypto.LookupKey{}
+		copy(l[:], k)
+		if b := s.Keys[layer].RevokeKey(&l, false); b != nil {
+			revoked = append(revoked, b)
+		}
+	}
+	s.propagateRevocation(layer, revoked, true, true, &wg)
+	return &coord.Empty{}, nil
+}
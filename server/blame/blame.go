@@ -0,0 +1,163 @@
+package blame
+
+import (
+	"bytes"
+	"crypto/sha512"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+/*
+Blame protocol for messages missing at the end of a layer
+
+- Each batch between servers is signed by its sender over its digest,
+  and the receiver keeps the digest and signature with the batch in its audit log
+- The server that is missing messages finds the keys that were never used
+- It asks the server that should have sent each message for signed evidence
+- The upstream server answers with the digest of the batch it sent, and whether it still holds a path for each key
+  (keys it dropped were revoked on the rest of their paths before the batch was sent, so they are never missing)
+- Every missing key is included in a signed accusation naming the link,
+  with the upstream server's answer and its signature on the batch that arrived,
+  so anyone given the batch can check the messages are not in it, and the round continues without the dropped users
+*/
+
+const BATCH_HASH_SIZE = sha512.Size
+
+// Create the request for the server that should have sent the messages under keys on this layer
+func NewBlameRequest(c *common.CommonState, layer, accused int, reverse bool, keys []crypto.LookupKey) *messages.SignedMessage {
+	r := BlameRequest{
+		Reverse: reverse,
+		Keys:    keys,
+	}
+	m := messages.NewSignedMessage(r.Len(), c.Round, layer, c.MyId, 0, accused, len(keys), messages.NetworkMessage_ServerBlameRequest)
+	r.PackTo(m.Data)
+	c.Sign(m)
+	return m
+}
+
+// Called by the upstream server with its key tables for each layer, and the digest of the batch it sent to the requester
+// Messages forwarded to layer l came from the table of layer l-1 (or l+1 for boomerang messages)
+func RespondToRequest(c *common.CommonState, m *messages.SignedMessage, tables []*processMessages.KeyLookupTable, sent []byte) (*messages.SignedMessage, error) {
+	if m.Sender < 0 || m.Sender >= len(c.VerificationKeys) {
+		return nil, errors.Rejected("unknown sender")
+	}
+	if !crypto.Verify(c.VerificationKeys[m.Sender], m.GetSignedData(), m.Signature) {
+		return nil, errors.Rejected("signature")
+	}
+	r := BlameRequest{}
+	err := r.InterpretFrom(m.Data)
+	if err != nil {
+		return nil, err
+	}
+	layer := m.Layer - 1
+	if r.Reverse {
+		layer = m.Layer + 1
+	}
+	if layer < 0 || layer >= len(tables) || tables[layer] == nil {
+		return nil, errors.Rejected("layer")
+	}
+	if len(sent) != 0 && len(sent) != BATCH_HASH_SIZE {
+		sent = nil
+	}
+	resp := BlameResponse{
+		Keys:      r.Keys,
+		Forwarded: make([]bool, len(r.Keys)),
+		BatchHash: sent,
+	}
+	for i := range r.Keys {
+		// forward messages are sent under the outgoing key, boomerang messages under the incoming key
+		resp.Forwarded[i] = tables[layer].Lookup(&r.Keys[i], !r.Reverse) != nil
+	}
+	sm := messages.NewSignedMessage(resp.Len(), m.Round, m.Layer, c.MyId, 0, m.Sender, len(r.Keys), messages.NetworkMessage_ServerBlameResponse)
+	resp.PackTo(sm.Data)
+	c.Sign(sm)
+	return sm, nil
+}
+
+// Create a signed accusation against the accused for all of the keys
+// evidence is the accused's response, or nil if it did not give a valid one,
+// and batchHash and batchSignature are the digest and signature of the batch received from it, or nil if none arrived
+func NewAccusation(c *common.CommonState, round, layer, accused int, keys []crypto.LookupKey, evidence *messages.SignedMessage, batchHash []byte, batchSignature crypto.Signature) *Accusation {
+	a := &Accusation{
+		Round:   round,
+		Layer:   layer,
+		Accuser: c.MyId,
+		Accused: accused,
+		Keys:    keys,
+	}
+	if evidence != nil && checkEvidence(c, a, evidence) && coversKeys(evidence, keys) {
+		a.Evidence = evidence
+	}
+	if len(batchHash) == BATCH_HASH_SIZE && len(batchSignature) == crypto.SIGNATURE_SIZE {
+		a.BatchHash = batchHash
+		a.BatchSignature = batchSignature
+	}
+	a.Signature = crypto.Sign(c.SecretSigningKey, a.signedData())
+	return a
+}
+
+// Check the accusation is signed by the accuser, that the batch is signed by the accused,
+// and that any evidence is signed by the accused and covers every key in the accusation
+func (a *Accusation) Verify(c *common.CommonState) bool {
+	if a.Accuser < 0 || a.Accuser >= len(c.VerificationKeys) {
+		return false
+	}
+	if a.Accused < 0 || a.Accused >= len(c.VerificationKeys) {
+		return false
+	}
+	if !crypto.Verify(c.VerificationKeys[a.Accuser], a.signedData(), a.Signature) {
+		return false
+	}
+	if len(a.BatchHash) > 0 && !crypto.VerifyDigest(c.VerificationKeys[a.Accused], a.BatchHash, a.BatchSignature) {
+		return false
+	}
+	if a.Evidence == nil {
+		// the accused did not respond
+		return true
+	}
+	return checkEvidence(c, a, a.Evidence) && coversKeys(a.Evidence, a.Keys)
+}
+
+// Whether the accused answered with a digest other than the one it signed for the batch that arrived
+// i.e. it signed two different batches for the link
+func (a *Accusation) Equivocated() bool {
+	if a.Evidence == nil || len(a.BatchHash) == 0 {
+		return false
+	}
+	r := BlameResponse{}
+	if r.InterpretFrom(a.Evidence.Data) != nil {
+		return false
+	}
+	return len(r.BatchHash) > 0 && !bytes.Equal(r.BatchHash, a.BatchHash)
+}
+
+func checkEvidence(c *common.CommonState, a *Accusation, evidence *messages.SignedMessage) bool {
+	if evidence.Type != messages.NetworkMessage_ServerBlameResponse ||
+		evidence.Round != a.Round || evidence.Layer != a.Layer ||
+		evidence.Sender != a.Accused || evidence.Dest != a.Accuser {
+		return false
+	}
+	return crypto.Verify(c.VerificationKeys[a.Accused], evidence.GetSignedData(), evidence.Signature)
+}
+
+// whether the accused was asked about every key
+func coversKeys(evidence *messages.SignedMessage, keys []crypto.LookupKey) bool {
+	r := BlameResponse{}
+	if r.InterpretFrom(evidence.Data) != nil {
+		return false
+	}
+	asked := make(map[crypto.LookupKey]bool)
+	for _, k := range r.Keys {
+		asked[k] = true
+	}
+	for _, k := range keys {
+		if !asked[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package blame
+
+import (
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/nizk"
+	"github.com/simonlangowski/lightning1/network/messages"
+)
+
+// Sent to the server that should have forwarded the missing messages
+type BlameRequest struct {
+	// boomerang messages travel from the next server rather than the previous server
+	Reverse bool
+	// the keys the messages should have been sent under
+	Keys []crypto.LookupKey
+}
+
+// Signed by the upstream server as evidence
+// A key it still holds means it received the message and had to forward it
+// A key it does not hold should have been revoked on the rest of its path before the batch was sent
+type BlameResponse struct {
+	Keys      []crypto.LookupKey
+	Forwarded []bool
+	// the digest of the batch it signed and sent, or empty if it sent none
+	BatchHash []byte
+}
+
+// Names the link from Accused to Accuser as having dropped the messages for Keys
+type Accusation struct {
+	Round   int
+	Layer   int
+	Accuser int
+	Accused int
+	Keys    []crypto.LookupKey
+	// the accused's signed response, or nil if it did not respond
+	Evidence *messages.SignedMessage
+	// the digest of the batch the accuser received from the accused, with the accused's signature of it,
+	// or empty if no batch arrived
+	// the batch itself is in the accuser's audit log
+	BatchHash      []byte
+	BatchSignature crypto.Signature
+	Signature      crypto.Signature
+}
+
+// Sent to the neighbouring servers on the paths of revoked keys
+// the keys are incoming keys of the receiver's layer, or outgoing keys if Reverse
+type Revocation struct {
+	Reverse bool
+	Keys    []crypto.LookupKey
+}
+
+// Sent by a member of the client's group to the server an envelope of a disputed path establishment message was sent to
+// the round and layer of the envelope are those of the message, and the group is the client's
+// the envelope is not sent, the server makes it from the complaint and the steps of the earlier layers
+type TracebackRequest struct {
+	Client    int64
+	Disputed  int    // the path establishment round the client complained about
+	Complaint []byte // the client's complaint, signed under the first key of the path
+	// the signed steps of the servers of the earlier layers
+	Steps []*messages.SignedMessage
+}
+
+// Signed by that server as evidence of how it decrypted the envelope
+type TracebackStep struct {
+	// the shared key of the envelope's InKey, with the proof that the server derived it with its secret key
+	Shared crypto.DHPublicKey
+	Proof  nizk.DecryptionProof
+	// the decrypted next hop
+	Next []byte
+}
+
+// Where a traceback ended
+type Verdict struct {
+	Round  int
+	Layer  int
+	Server int // the blamed server, or -1 if the client is blamed
+	// the signed steps of the servers before the blamed one
+	Steps []*messages.SignedMessage
+	// the first server's signed receipt the client disputed, or nil if it got none
+	Receipt *messages.SignedMessage
+}
+
+// Sent by a member of a client's anytrust group to the first server of a path the client complained about
+type PathRepair struct {
+	Client int64
+	// the incoming key of the path's first layer
+	Key crypto.LookupKey
+}
@@ -0,0 +1,100 @@
+package blame
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+func TestAccusation(t *testing.T) {
+	layer := 1
+	states := common.NewMockCommonStates(3, &common.CommonState{NumServers: 3, Round: 2})
+	upstream, accuser, observer := states[0], states[1], states[2]
+
+	// the upstream server has a path for the first user but dropped the second without revoking it
+	forwarded, _ := crypto.NewSigningKeyPair()
+	dropped, _ := crypto.NewSigningKeyPair()
+	incoming, _ := crypto.NewSigningKeyPair()
+	tables := []*processMessages.KeyLookupTable{processMessages.NewKeyLookupTable(upstream, layer), nil}
+	_, err := tables[0].AddKey(incoming, nil, 0, accuser.MyId, forwarded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the batch the upstream server signed and sent, which arrived without the messages
+	batch := []byte("batch without the messages")
+	digest := crypto.BatchDigest(batch)
+	batchSignature := crypto.PreHashSign(batch, upstream.SecretSigningKey)
+
+	keys := []crypto.LookupKey{forwarded.LookupKey(), dropped.LookupKey()}
+	req := NewBlameRequest(accuser, layer, upstream.MyId, false, keys)
+	evidence, err := RespondToRequest(upstream, req, tables, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evidence.Type == req.Type {
+		t.Fatalf("Response has the type of the request")
+	}
+	// the dropped key was not revoked downstream, so it is blamed on the link too
+	a := NewAccusation(accuser, accuser.Round, layer, upstream.MyId, keys, evidence, digest, batchSignature)
+	if len(a.Keys) != len(keys) || a.Evidence == nil || a.Equivocated() {
+		t.Fatalf("Accusation should contain every key and the evidence")
+	}
+	if !a.Verify(observer) {
+		t.Fatalf("Accusation did not verify")
+	}
+
+	b := &Accusation{}
+	err = b.InterpretFrom(a.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Verify(observer) || !bytes.Equal(b.BatchHash, digest) {
+		t.Fatalf("Unmarshalled accusation did not verify")
+	}
+
+	// the accuser cannot blame the upstream server for a batch it did not sign
+	b.BatchHash = crypto.BatchDigest([]byte("another batch"))
+	b.Signature = crypto.Sign(accuser.SecretSigningKey, b.signedData())
+	if b.Verify(observer) {
+		t.Fatalf("Accusation verified with a batch the accused did not sign")
+	}
+
+	// nor for keys it was never asked about
+	req = NewBlameRequest(accuser, layer, upstream.MyId, false, keys[1:])
+	evidence, err = RespondToRequest(upstream, req, tables, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = NewAccusation(accuser, accuser.Round, layer, upstream.MyId, keys[1:], evidence, digest, batchSignature)
+	b.Keys = keys
+	b.Signature = crypto.Sign(accuser.SecretSigningKey, b.signedData())
+	if b.Verify(observer) {
+		t.Fatalf("Accusation verified without evidence for every key")
+	}
+
+	// an answer naming another batch than the one that arrived shows the upstream server signed two
+	req = NewBlameRequest(accuser, layer, upstream.MyId, false, keys)
+	evidence, err = RespondToRequest(upstream, req, tables, crypto.BatchDigest([]byte("another batch")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = NewAccusation(accuser, accuser.Round, layer, upstream.MyId, keys, evidence, digest, batchSignature)
+	if !a.Verify(observer) || !a.Equivocated() {
+		t.Fatalf("Equivocation not shown")
+	}
+
+	// without a response or a batch every key is blamed on the link
+	a = NewAccusation(accuser, accuser.Round, layer, upstream.MyId, keys, nil, nil, nil)
+	if len(a.Keys) != len(keys) || !a.Verify(observer) {
+		t.Fatalf("Accusation without evidence did not verify")
+	}
+	b = &Accusation{}
+	err = b.InterpretFrom(a.Marshal())
+	if err != nil || !b.Verify(observer) {
+		t.Fatalf("Unmarshalled accusation without evidence did not verify")
+	}
+}
@@ -0,0 +1,124 @@
+package blame
+
+import (
+	"sync"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+/*
+Complaints about path establishment receipts
+
+- A client whose receipt for a path establishment round is wrong or missing sends its anytrust group
+  the envelope it submitted, and the first server's signed receipt if it got one
+- Each member of the group traces the path through the layers established so far
+- Only the client that submitted the envelope can complain about it: the complaint is signed under the InKey
+  of the first layer, and the first layer key of the path names the client
+- The receipt must be signed by the first server for the client and the disputed round, and is kept with the verdict
+- A path the client malformed is not repaired, the complaint is rejected
+- Otherwise a server broke the path, or dropped the receipt on its way back, so the path cannot be used:
+  the members ask the first server of the path to revoke it, which revokes it once every member of the group
+  that was not dropped asked, and passes the revocation along the path
+- The client forgets the path, and establishes a new one, before any lightning round is sent over it
+*/
+
+// Ask the first server of a client's path to revoke it
+func NewPathRepair(c *common.CommonState, group, dest, client int, key crypto.LookupKey) *messages.SignedMessage {
+	r := PathRepair{Client: int64(client), Key: key}
+	m := messages.NewSignedMessage(r.Len(), c.Round, 0, c.MyId, group, dest, 1, messages.NetworkMessage_ServerPathRepair)
+	r.PackTo(m.Data)
+	c.Sign(m)
+	return m
+}
+
+// The members of each group that asked to revoke a path, until all of them have
+type RepairVotes struct {
+	mu    sync.Mutex
+	votes map[repairVote]map[int]bool
+}
+
+type repairVote struct {
+	group  int
+	client int64
+	key    crypto.LookupKey
+}
+
+func NewRepairVotes() *RepairVotes {
+	return &RepairVotes{votes: make(map[repairVote]map[int]bool)}
+}
+
+// Count the request of a member of the group, returns true once, when every member that was not dropped asked
+func (v *RepairVotes) Add(c *common.CommonState, group, sender int, r *PathRepair) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	vote := repairVote{group: group, client: r.Client, key: r.Key}
+	senders := v.votes[vote]
+	if senders == nil {
+		senders = make(map[int]bool)
+		v.votes[vote] = senders
+	}
+	senders[sender] = true
+	for _, member := range c.GroupConfigs.Groups[int64(group)].Servers {
+		if !senders[int(member)] && !c.IsDropped(int(member)) {
+			return false
+		}
+	}
+	delete(v.votes, vote)
+	return true
+}
+
+// Remove the first layer key of the path, if the sender is in the group it claims and the path is the client's,
+// once every member of the group asked
+// returns the removed key, or nil if it was already revoked or more members have to ask
+func ApplyPathRepair(c *common.CommonState, m *messages.SignedMessage, table *processMessages.KeyLookupTable, votes *RepairVotes) (*processMessages.BootstrapKey, error) {
+	if m.Sender < 0 || m.Sender >= len(c.VerificationKeys) {
+		return nil, errors.Rejected("unknown sender")
+	}
+	if !crypto.Verify(c.VerificationKeys[m.Sender], m.GetSignedData(), m.Signature) {
+		return nil, errors.Rejected("signature")
+	}
+	if !inGroup(c, int(m.Group), m.Sender) {
+		return nil, errors.Rejected("sender not in group")
+	}
+	r := PathRepair{}
+	err := r.InterpretFrom(m.Data)
+	if err != nil {
+		return nil, err
+	}
+	if table == nil {
+		return nil, errors.Rejected("layer")
+	}
+	b := table.Lookup(&r.Key, false)
+	if b == nil {
+		// already revoked
+		return nil, nil
+	}
+	// the previous server of the first layer is the client
+	if int64(b.PrevServer) != r.Client {
+		return nil, errors.Rejected("path of another client")
+	}
+	if !votes.Add(c, int(m.Group), m.Sender, &r) {
+		return nil, nil
+	}
+	return table.RevokeKey(&r.Key, false), nil
+}
+
+func inGroup(c *common.CommonState, group, sid int) bool {
+	if c.GroupConfigs == nil {
+		return false
+	}
+	g := c.GroupConfigs.Groups[int64(group)]
+	if g == nil {
+		return false
+	}
+	for _, member := range g.Servers {
+		if int(member) == sid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package blame
+
+import (
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+func TestRepairVotes(t *testing.T) {
+	c := &common.CommonState{
+		NumServers:   5,
+		GroupConfigs: &config.Groups{Groups: map[int64]*config.Group{1: {Gid: 1, Servers: []int64{1, 2, 3}}}},
+	}
+	votes := NewRepairVotes()
+	r := &PathRepair{Client: 7}
+	r.Key[0] = 1
+	// one member is not enough
+	if votes.Add(c, 1, 1, r) || votes.Add(c, 1, 1, r) {
+		t.Fatalf("Path revoked at the request of one member")
+	}
+	// nor is a request for another path
+	other := &PathRepair{Client: 7}
+	if votes.Add(c, 1, 2, other) || votes.Add(c, 1, 3, other) {
+		t.Fatalf("Requests for different paths were counted together")
+	}
+	if votes.Add(c, 1, 2, r) {
+		t.Fatalf("Path revoked before every member asked")
+	}
+	if !votes.Add(c, 1, 3, r) {
+		t.Fatalf("Path not revoked once every member asked")
+	}
+	// a dropped member is not waited for
+	c.DropServers([]int64{3})
+	dropped := &PathRepair{Client: 8}
+	if votes.Add(c, 1, 1, dropped) {
+		t.Fatalf("Path revoked at the request of one member")
+	}
+	if !votes.Add(c, 1, 2, dropped) {
+		t.Fatalf("Path not revoked once every member that was not dropped asked")
+	}
+}
@@ -0,0 +1,401 @@
+package blame
+
+import (
+	"encoding/binary"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+)
+
+func (r *BlameRequest) Len() int {
+	return 1 + 4 + len(r.Keys)*crypto.KEY_SIZE
+}
+
+func (r *BlameRequest) PackTo(b []byte) {
+	if len(b) != r.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	b[0] = 0
+	if r.Reverse {
+		b[0] = 1
+	}
+	packKeys(b[1:], r.Keys)
+}
+
+func (r *BlameRequest) InterpretFrom(b []byte) error {
+	if len(b) < 1+4 {
+		return errors.Rejected("length")
+	}
+	r.Reverse = b[0] == 1
+	var err error
+	r.Keys, err = interpretKeys(b[1:])
+	if err != nil {
+		return err
+	}
+	if len(b) != r.Len() {
+		return errors.Rejected("length")
+	}
+	return nil
+}
+
+func (r *Revocation) Len() int {
+	return 1 + 4 + len(r.Keys)*crypto.KEY_SIZE
+}
+
+func (r *Revocation) PackTo(b []byte) {
+	(*BlameRequest)(r).PackTo(b)
+}
+
+func (r *Revocation) InterpretFrom(b []byte) error {
+	return (*BlameRequest)(r).InterpretFrom(b)
+}
+
+func (r *BlameResponse) Len() int {
+	return 4 + len(r.Keys)*(crypto.KEY_SIZE+1) + batchLen(r.BatchHash, false)
+}
+
+func (r *BlameResponse) PackTo(b []byte) {
+	if len(b) != r.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	pos := packKeys(b, r.Keys)
+	for i, f := range r.Forwarded {
+		b[pos+i] = 0
+		if f {
+			b[pos+i] = 1
+		}
+	}
+	pos += len(r.Forwarded)
+	packBatch(b[pos:], r.BatchHash, nil)
+}
+
+func (r *BlameResponse) InterpretFrom(b []byte) error {
+	var err error
+	r.Keys, err = interpretKeys(b)
+	if err != nil {
+		return err
+	}
+	pos := 4 + len(r.Keys)*crypto.KEY_SIZE
+	if len(b) < pos+len(r.Keys)+1 {
+		return errors.Rejected("length")
+	}
+	r.Forwarded = make([]bool, len(r.Keys))
+	for i := range r.Forwarded {
+		r.Forwarded[i] = b[pos+i] == 1
+	}
+	pos += len(r.Keys)
+	r.BatchHash, _, err = interpretBatch(b[pos:], false)
+	if err != nil {
+		return err
+	}
+	if len(b) != r.Len() {
+		return errors.Rejected("length")
+	}
+	return nil
+}
+
+func (a *Accusation) Len() int {
+	return 4*4 + 4 + len(a.Keys)*crypto.KEY_SIZE + 4 + evidenceLen(a.Evidence) + batchLen(a.BatchHash, true) + crypto.SIGNATURE_SIZE
+}
+
+func (a *Accusation) PackTo(b []byte) {
+	if len(b) != a.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	copy(b, a.signedData())
+	copy(b[len(b)-crypto.SIGNATURE_SIZE:], a.Signature)
+}
+
+func (a *Accusation) InterpretFrom(b []byte) error {
+	if len(b) < 4*4+4+4+1+crypto.SIGNATURE_SIZE {
+		return errors.Rejected("length")
+	}
+	a.Round = int(binary.LittleEndian.Uint32(b[0:4]))
+	a.Layer = int(binary.LittleEndian.Uint32(b[4:8]))
+	a.Accuser = int(binary.LittleEndian.Uint32(b[8:12]))
+	a.Accused = int(binary.LittleEndian.Uint32(b[12:16]))
+	pos := 16
+	var err error
+	a.Keys, err = interpretKeys(b[pos:])
+	if err != nil {
+		return err
+	}
+	pos += 4 + len(a.Keys)*crypto.KEY_SIZE
+	if len(b) < pos+4+1+crypto.SIGNATURE_SIZE {
+		return errors.Rejected("length")
+	}
+	l := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+	pos += 4
+	if l < 0 || len(b) < pos+l+1+crypto.SIGNATURE_SIZE {
+		return errors.Rejected("length")
+	}
+	a.Evidence = nil
+	if l > 0 {
+		if l < messages.Metadata_size+crypto.SIGNATURE_SIZE {
+			return errors.Rejected("length")
+		}
+		e := make([]byte, l)
+		copy(e, b[pos:pos+l])
+		a.Evidence = messages.ParseSignedMessage(&messages.NetworkMessage{
+			Data:      e[:l-crypto.SIGNATURE_SIZE],
+			Signature: e[l-crypto.SIGNATURE_SIZE:],
+		})
+	}
+	pos += l
+	a.BatchHash, a.BatchSignature, err = interpretBatch(b[pos:len(b)-crypto.SIGNATURE_SIZE], true)
+	if err != nil {
+		return err
+	}
+	if len(b) != a.Len() {
+		return errors.Rejected("length")
+	}
+	return a.Signature.InterpretFrom(b[len(b)-crypto.SIGNATURE_SIZE:])
+}
+
+func (a *Accusation) Marshal() []byte {
+	b := make([]byte, a.Len())
+	a.PackTo(b)
+	return b
+}
+
+// everything but the accuser's signature
+func (a *Accusation) signedData() []byte {
+	b := make([]byte, a.Len()-crypto.SIGNATURE_SIZE)
+	binary.LittleEndian.PutUint32(b[0:4], uint32(a.Round))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(a.Layer))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(a.Accuser))
+	binary.LittleEndian.PutUint32(b[12:16], uint32(a.Accused))
+	pos := 16
+	pos += packKeys(b[pos:], a.Keys)
+	binary.LittleEndian.PutUint32(b[pos:pos+4], uint32(evidenceLen(a.Evidence)))
+	pos += 4
+	if a.Evidence != nil {
+		pos += copy(b[pos:], a.Evidence.GetSignedData())
+		pos += copy(b[pos:], a.Evidence.Signature)
+	}
+	packBatch(b[pos:], a.BatchHash, a.BatchSignature)
+	return b
+}
+
+func evidenceLen(e *messages.SignedMessage) int {
+	if e == nil {
+		return 0
+	}
+	return messages.Metadata_size + len(e.Data) + crypto.SIGNATURE_SIZE
+}
+
+// a flag, followed by the digest of a batch if there is one, and its signature if signed
+func batchLen(hash []byte, signed bool) int {
+	if len(hash) == 0 {
+		return 1
+	}
+	if signed {
+		return 1 + BATCH_HASH_SIZE + crypto.SIGNATURE_SIZE
+	}
+	return 1 + BATCH_HASH_SIZE
+}
+
+func packBatch(b []byte, hash []byte, signature crypto.Signature) {
+	b[0] = 0
+	if len(hash) == 0 {
+		return
+	}
+	b[0] = 1
+	copy(b[1:1+BATCH_HASH_SIZE], hash)
+	if signature != nil {
+		copy(b[1+BATCH_HASH_SIZE:], signature)
+	}
+}
+
+func interpretBatch(b []byte, signed bool) ([]byte, crypto.Signature, error) {
+	if len(b) < 1 {
+		return nil, nil, errors.Rejected("length")
+	}
+	if b[0] == 0 {
+		return nil, nil, nil
+	}
+	l := 1 + BATCH_HASH_SIZE
+	if signed {
+		l += crypto.SIGNATURE_SIZE
+	}
+	if len(b) < l {
+		return nil, nil, errors.Rejected("length")
+	}
+	hash := make([]byte, BATCH_HASH_SIZE)
+	copy(hash, b[1:])
+	if !signed {
+		return hash, nil, nil
+	}
+	signature := make(crypto.Signature, crypto.SIGNATURE_SIZE)
+	copy(signature, b[1+BATCH_HASH_SIZE:])
+	return hash, signature, nil
+}
+
+// write a count followed by the keys, and return the number of bytes written
+func packKeys(b []byte, keys []crypto.LookupKey) int {
+	binary.LittleEndian.PutUint32(b[0:4], uint32(len(keys)))
+	pos := 4
+	for _, k := range keys {
+		copy(b[pos:pos+crypto.KEY_SIZE], k[:])
+		pos += crypto.KEY_SIZE
+	}
+	return pos
+}
+
+func interpretKeys(b []byte) ([]crypto.LookupKey, error) {
+	if len(b) < 4 {
+		return nil, errors.Rejected("length")
+	}
+	n := int(binary.LittleEndian.Uint32(b[0:4]))
+	if n < 0 || len(b)-4 < n*crypto.KEY_SIZE {
+		return nil, errors.Rejected("length")
+	}
+	keys := make([]crypto.LookupKey, n)
+	pos := 4
+	for i := range keys {
+		copy(keys[i][:], b[pos:pos+crypto.KEY_SIZE])
+		pos += crypto.KEY_SIZE
+	}
+	return keys, nil
+}
+
+func (r *TracebackRequest) Len() int {
+	l := 8 + 4 + 4 + len(r.Complaint) + 4
+	for _, step := range r.Steps {
+		l += 4 + evidenceLen(step)
+	}
+	return l
+}
+
+func (r *TracebackRequest) PackTo(b []byte) {
+	if len(b) != r.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	binary.LittleEndian.PutUint64(b[0:8], uint64(r.Client))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(r.Disputed))
+	binary.LittleEndian.PutUint32(b[12:16], uint32(len(r.Complaint)))
+	pos := 16
+	pos += copy(b[pos:], r.Complaint)
+	binary.LittleEndian.PutUint32(b[pos:pos+4], uint32(len(r.Steps)))
+	pos += 4
+	for _, step := range r.Steps {
+		pos += packSigned(b[pos:], step)
+	}
+}
+
+func (r *TracebackRequest) InterpretFrom(b []byte) error {
+	if len(b) < 16 {
+		return errors.Rejected("length")
+	}
+	r.Client = int64(binary.LittleEndian.Uint64(b[0:8]))
+	r.Disputed = int(binary.LittleEndian.Uint32(b[8:12]))
+	l := int(binary.LittleEndian.Uint32(b[12:16]))
+	pos := 16
+	if l < 0 || len(b) < pos+l+4 {
+		return errors.Rejected("length")
+	}
+	r.Complaint = b[pos : pos+l]
+	pos += l
+	n := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+	pos += 4
+	if n < 0 || n > len(b)-pos {
+		return errors.Rejected("length")
+	}
+	r.Steps = make([]*messages.SignedMessage, n)
+	for i := range r.Steps {
+		var read int
+		var err error
+		r.Steps[i], read, err = interpretSigned(b[pos:])
+		if err != nil {
+			return err
+		}
+		pos += read
+	}
+	if pos != len(b) {
+		return errors.Rejected("length")
+	}
+	return nil
+}
+
+// write the length of a signed message followed by the message, and return the number of bytes written
+func packSigned(b []byte, m *messages.SignedMessage) int {
+	binary.LittleEndian.PutUint32(b[0:4], uint32(evidenceLen(m)))
+	pos := 4
+	pos += copy(b[pos:], m.GetSignedData())
+	pos += copy(b[pos:], m.Signature)
+	return pos
+}
+
+func interpretSigned(b []byte) (*messages.SignedMessage, int, error) {
+	if len(b) < 4 {
+		return nil, 0, errors.Rejected("length")
+	}
+	l := int(binary.LittleEndian.Uint32(b[0:4]))
+	if l < messages.Metadata_size+crypto.SIGNATURE_SIZE || len(b)-4 < l {
+		return nil, 0, errors.Rejected("length")
+	}
+	e := make([]byte, l)
+	copy(e, b[4:4+l])
+	m := messages.ParseSignedMessage(&messages.NetworkMessage{
+		Data:      e[:l-crypto.SIGNATURE_SIZE],
+		Signature: e[l-crypto.SIGNATURE_SIZE:],
+	})
+	if m == nil {
+		return nil, 0, errors.Rejected("step")
+	}
+	return m, 4 + l, nil
+}
+
+// the length of a step without the next hop
+const tracebackStepBaseLength = 3*crypto.POINT_SIZE + crypto.SCALAR_SIZE
+
+func (s *TracebackStep) Len() int {
+	return tracebackStepBaseLength + len(s.Next)
+}
+
+func (s *TracebackStep) PackTo(b []byte) {
+	if len(b) != s.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	s.Shared.PackTo(b[:crypto.POINT_SIZE])
+	s.Proof.PackTo(b[crypto.POINT_SIZE:tracebackStepBaseLength])
+	copy(b[tracebackStepBaseLength:], s.Next)
+}
+
+func (s *TracebackStep) InterpretFrom(b []byte) error {
+	if len(b) < tracebackStepBaseLength {
+		return errors.Rejected("length")
+	}
+	err := s.Shared.InterpretFrom(b[:crypto.POINT_SIZE])
+	if err != nil {
+		return err
+	}
+	err = s.Proof.InterpretFrom(b[crypto.POINT_SIZE:tracebackStepBaseLength])
+	if err != nil {
+		return err
+	}
+	s.Next = b[tracebackStepBaseLength:]
+	return nil
+}
+
+func (r *PathRepair) Len() int {
+	return 8 + crypto.KEY_SIZE
+}
+
+func (r *PathRepair) PackTo(b []byte) {
+	if len(b) != r.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	binary.LittleEndian.PutUint64(b, uint64(r.Client))
+	copy(b[8:], r.Key[:])
+}
+
+func (r *PathRepair) InterpretFrom(b []byte) error {
+	if len(b) != r.Len() {
+		return errors.Rejected("length")
+	}
+	r.Client = int64(binary.LittleEndian.Uint64(b))
+	copy(r.Key[:], b[8:])
+	return nil
+}
@@ -0,0 +1,68 @@
+package blame
+
+import (
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+/*
+Revocation of users' paths
+
+- A server revokes keys in its table for a layer (after blame, or when the operator revokes a client)
+- It sends the outgoing keys to the next server, and the incoming keys to the previous server on each path
+- Each of those servers removes the key if the sender is its neighbour on the path, and passes it on
+- Every server on the path then skips envelopes under the keys in this and every later round,
+  instead of waiting for them and blaming the link they should have arrived on
+*/
+
+// Create the revocation for the keys of layer held by dest
+func NewRevocation(c *common.CommonState, layer, dest int, reverse bool, keys []crypto.LookupKey) *messages.SignedMessage {
+	r := Revocation{
+		Reverse: reverse,
+		Keys:    keys,
+	}
+	m := messages.NewSignedMessage(r.Len(), c.Round, layer, c.MyId, 0, dest, len(keys), messages.NetworkMessage_ServerRevocation)
+	r.PackTo(m.Data)
+	c.Sign(m)
+	return m
+}
+
+// Remove the keys from the table of the revocation's layer, if the sender is the neighbour on their path
+// returns whether the revocation came from the next server, and the removed keys
+func ApplyRevocation(c *common.CommonState, m *messages.SignedMessage, tables []*processMessages.KeyLookupTable) (bool, []*processMessages.BootstrapKey, error) {
+	if m.Sender < 0 || m.Sender >= len(c.VerificationKeys) {
+		return false, nil, errors.Rejected("unknown sender")
+	}
+	if !crypto.Verify(c.VerificationKeys[m.Sender], m.GetSignedData(), m.Signature) {
+		return false, nil, errors.Rejected("signature")
+	}
+	r := Revocation{}
+	err := r.InterpretFrom(m.Data)
+	if err != nil {
+		return false, nil, err
+	}
+	if m.Layer < 0 || m.Layer >= len(tables) || tables[m.Layer] == nil {
+		return false, nil, errors.Rejected("layer")
+	}
+	table := tables[m.Layer]
+	removed := make([]*processMessages.BootstrapKey, 0, len(r.Keys))
+	for i := range r.Keys {
+		b := table.Lookup(&r.Keys[i], r.Reverse)
+		if b == nil {
+			// already revoked
+			continue
+		}
+		if (r.Reverse && b.NextServer != m.Sender) || (!r.Reverse && b.PrevServer != m.Sender) {
+			// only a server on the path can revoke it
+			continue
+		}
+		b = table.RevokeKey(&r.Keys[i], r.Reverse)
+		if b != nil {
+			removed = append(removed, b)
+		}
+	}
+	return r.Reverse, removed, nil
+}
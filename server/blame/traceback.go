@@ -0,0 +1,251 @@
+package blame
+
+import (
+	"bytes"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/nizk"
+	"github.com/simonlangowski/lightning1/crypto/token"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/prepareMessages"
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+/*
+Traceback protocol for a disputed path establishment message
+
+- Starting from the envelope the client submitted, each server an envelope was sent to
+  reveals the shared key it derived from the envelope's InKey, with a DLEQ proof that it
+  used its ServerSecretKey, and the decryption of the envelope
+- Anyone can then check the decryption with the shared key, and make the envelope for the next server
+- A server that does not respond, or whose proof or decryption does not check, is blamed
+- The envelopes are signed under keys only the client knows, so an envelope that does not
+  decrypt to a well formed path message is blamed on the client
+- A path that is well formed at every layer leaves nothing to dispute, and is also blamed on the client
+The shared keys of the path are revealed, so only the disputed message loses its anonymity
+- A server only reveals its step to a member of the client's group, for a recent complaint the client signed
+  under the first key of the path, and for the envelope the steps of the earlier layers lead to,
+  so no server can have the paths of other clients decrypted
+*/
+
+// Asks a server for its step, e.g. over the network
+type Prover func(server int, request *messages.SignedMessage) (*messages.SignedMessage, error)
+
+// The client's complaint about a path, which the servers on the path check before revealing their steps
+type disputedPath struct {
+	client    int
+	group     int
+	round     int // the disputed path establishment round
+	complaint prepareMessages.Complaint
+}
+
+func NewTracebackRequest(c *common.CommonState, layer, dest int, d *disputedPath, steps []*messages.SignedMessage) *messages.SignedMessage {
+	r := TracebackRequest{
+		Client:    int64(d.client),
+		Disputed:  d.round,
+		Complaint: d.complaint.Marshal(),
+		Steps:     steps,
+	}
+	// layer l of a path is established in round l
+	m := messages.NewSignedMessage(r.Len(), layer, layer, c.MyId, d.group, dest, 1, messages.NetworkMessage_ServerTraceback)
+	r.PackTo(m.Data)
+	c.Sign(m)
+	return m
+}
+
+// Called by the server the envelope was sent to
+func RespondToTraceback(c *common.CommonState, m *messages.SignedMessage) (*messages.SignedMessage, error) {
+	if m.Sender < 0 || m.Sender >= len(c.VerificationKeys) {
+		return nil, errors.Rejected("unknown sender")
+	}
+	if !crypto.Verify(c.VerificationKeys[m.Sender], m.GetSignedData(), m.Signature) {
+		return nil, errors.Rejected("signature")
+	}
+	if !inGroup(c, int(m.Group), m.Sender) {
+		return nil, errors.Rejected("sender not in group")
+	}
+	r := TracebackRequest{}
+	err := r.InterpretFrom(m.Data)
+	if err != nil {
+		return nil, err
+	}
+	d := &disputedPath{client: int(r.Client), group: int(m.Group), round: r.Disputed}
+	if d.complaint.InterpretFrom(r.Complaint) != nil || !checkComplaint(c, d) {
+		return nil, errors.Rejected("complaint")
+	}
+	// the traceback follows the complaint, while it is pending
+	if d.round > c.Round || d.round < c.Round-1 || m.Layer != m.Round || m.Layer > d.round || len(r.Steps) != m.Layer {
+		return nil, errors.Rejected("layer")
+	}
+	// the envelope the steps of the earlier layers lead to
+	envelope := d.complaint.Envelope
+	prev := d.client
+	for layer, sm := range r.Steps {
+		e, inPoint, server, ok := openEnvelope(c, envelope, layer, prev)
+		if !ok {
+			return nil, errors.Rejected("envelope")
+		}
+		step, ok := checkStep(c, layer, layer, server, e, inPoint, sm)
+		if !ok {
+			return nil, errors.Rejected("step")
+		}
+		envelope = nextEnvelope(c, layer, step)
+		if envelope == nil {
+			return nil, errors.Rejected("envelope")
+		}
+		prev = server
+	}
+	e, inPoint, server, ok := openEnvelope(c, envelope, m.Layer, prev)
+	if !ok || server != c.MyId {
+		return nil, errors.Rejected("envelope")
+	}
+	proof, shared := nizk.NewDecryptionProof(inPoint, &c.ServerSecretKey)
+	nonce := crypto.Nonce(m.Round, m.Layer, c.MyId)
+	step := TracebackStep{
+		Shared: *shared,
+		Proof:  *proof,
+		Next:   crypto.SecretOpen(e.SignedCiphertext, &nonce, shared.AsShared()),
+	}
+	sm := messages.NewSignedMessage(step.Len(), m.Round, m.Layer, c.MyId, 0, m.Sender, 1, messages.NetworkMessage_ServerTraceback)
+	step.PackTo(sm.Data)
+	c.Sign(sm)
+	return sm, nil
+}
+
+// Follow the path of the envelope the client submitted through its layers, until a server or the client is blamed
+// the client signed the complaint about the disputed round for its group, and the servers are asked for their steps with prove
+func Traceback(c *common.CommonState, client, group, disputed int, complaint *prepareMessages.Complaint, numLayers int, prove Prover) *Verdict {
+	d := &disputedPath{client: client, group: group, round: disputed, complaint: *complaint}
+	v := &Verdict{Server: -1}
+	envelope := complaint.Envelope
+	prev := client
+	for layer := 0; layer < numLayers; layer++ {
+		// layer l of a path is established in round l
+		round := layer
+		v.Round, v.Layer = round, layer
+		e, inPoint, server, ok := openEnvelope(c, envelope, layer, prev)
+		if !ok {
+			return v
+		}
+		sm, err := prove(server, NewTracebackRequest(c, layer, server, d, v.Steps))
+		if err != nil {
+			v.Server = server
+			return v
+		}
+		step, ok := checkStep(c, round, layer, server, e, inPoint, sm)
+		if !ok {
+			v.Server = server
+			return v
+		}
+		v.Steps = append(v.Steps, sm)
+		envelope = nextEnvelope(c, layer, step)
+		if envelope == nil {
+			// the client made a malformed envelope for the next layer
+			v.Round, v.Layer = round+1, layer+1
+			return v
+		}
+		prev = server
+	}
+	// the last layer continues through the anytrust groups, whose decryptions are checked in the checkpoint
+	return v
+}
+
+// Check the client signed the complaint about the disputed round under the first key of the envelope it submitted,
+// which ties the envelope to the client
+func VerifyComplaint(c *common.CommonState, client, group, disputed int, complaint *prepareMessages.Complaint) bool {
+	return checkComplaint(c, &disputedPath{client: client, group: group, round: disputed, complaint: *complaint})
+}
+
+// the complaint is signed under the InKey of the envelope the client submitted for the first layer
+func checkComplaint(c *common.CommonState, d *disputedPath) bool {
+	e, _, _, ok := openEnvelope(c, d.complaint.Envelope, 0, d.client)
+	if !ok || len(d.complaint.Signature) != crypto.SIGNATURE_SIZE {
+		return false
+	}
+	return crypto.Verify(e.InKey, d.complaint.SignedData(int64(d.client), d.group, d.round), d.complaint.Signature)
+}
+
+// check the envelope of the layer was sent by prev, and find the server it was sent to
+func openEnvelope(c *common.CommonState, envelope []byte, layer, prev int) (*common.PathEstablishmentEnvelope, *crypto.DHPublicKey, int, bool) {
+	// layer l of a path is established in round l
+	e, inPoint, ok := parseEnvelope(envelope)
+	if !ok || !checkEnvelope(c, e, envelope, layer, layer, prev) {
+		return nil, nil, 0, false
+	}
+	tokenHash := e.InToken.Hash()
+	return e, inPoint, int(c.HashToServer(&tokenHash)), true
+}
+
+// the envelope of the next layer in the decryption of a checked step, or nil if the client malformed it
+func nextEnvelope(c *common.CommonState, layer int, step *TracebackStep) []byte {
+	boomerangLength := c.BoomerangMessageLengths[layer]
+	info := common.PathEstablishmentInfo{}
+	if len(step.Next) < crypto.POINT_SIZE+token.TOKEN_SIZE+boomerangLength || info.InterpretFrom(step.Next, boomerangLength) != nil {
+		return nil
+	}
+	next := common.PathEstablishmentEnvelope{
+		InKey:            info.OutKey,
+		InToken:          info.OutToken,
+		SignedCiphertext: info.NextEnvelope,
+	}
+	return next.Marshal()
+}
+
+// the client is blamed
+func (v *Verdict) ClientBlamed() bool {
+	return v.Server < 0
+}
+
+// the path was well formed at every traced layer, so neither the client nor a server could be blamed for it
+func (v *Verdict) WellFormed() bool {
+	return v.ClientBlamed() && len(v.Steps) > v.Layer
+}
+
+func parseEnvelope(b []byte) (*common.PathEstablishmentEnvelope, *crypto.DHPublicKey, bool) {
+	if len(b) < crypto.POINT_SIZE+token.TOKEN_SIZE+crypto.SIGNATURE_SIZE {
+		return nil, nil, false
+	}
+	e := &common.PathEstablishmentEnvelope{}
+	if e.InterpretFrom(b) != nil {
+		return nil, nil, false
+	}
+	inPoint, err := e.InKey.ToCurvePoint()
+	if err != nil {
+		return nil, nil, false
+	}
+	return e, inPoint, true
+}
+
+// the envelope is signed under its InKey, whose token was issued for the sender
+func checkEnvelope(c *common.CommonState, e *common.PathEstablishmentEnvelope, b []byte, round, layer, prev int) bool {
+	if c.CombinedKey != nil && !processMessages.VerifyToken(c.CombinedKey, &e.InToken, round, layer, prev, e.InKey) {
+		return false
+	}
+	tokenHash := e.InToken.Hash()
+	server := int(c.HashToServer(&tokenHash))
+	// the signed data is written over the raw envelope
+	signed := &common.PathEstablishmentEnvelope{}
+	signed.InterpretFrom(append([]byte{}, b...))
+	return crypto.Verify(e.InKey, signed.GetSignedData(round, layer, server), e.ReadSignature())
+}
+
+// the step is signed by the server, proves the shared key, and decrypts the envelope with it
+func checkStep(c *common.CommonState, round, layer, server int, e *common.PathEstablishmentEnvelope, inPoint *crypto.DHPublicKey, sm *messages.SignedMessage) (*TracebackStep, bool) {
+	if sm == nil || sm.Sender != server || sm.Round != round || sm.Layer != layer || sm.Type != messages.NetworkMessage_ServerTraceback {
+		return nil, false
+	}
+	if !crypto.Verify(c.VerificationKeys[server], sm.GetSignedData(), sm.Signature) {
+		return nil, false
+	}
+	step := &TracebackStep{}
+	if len(sm.Data) < tracebackStepBaseLength || step.InterpretFrom(sm.Data) != nil {
+		return nil, false
+	}
+	if !step.Proof.Verify(inPoint, &c.ServerPublicKeys[server], &step.Shared) {
+		return nil, false
+	}
+	nonce := crypto.Nonce(round, layer, server)
+	return step, bytes.Equal(step.Next, crypto.SecretOpen(e.SignedCiphertext, &nonce, step.Shared.AsShared()))
+}
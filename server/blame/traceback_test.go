@@ -0,0 +1,180 @@
+package blame
+
+import (
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/prepareMessages"
+)
+
+func TestTraceback(t *testing.T) {
+	numLayers := 3
+	_, groupKey := crypto.NewDHKeyPair()
+	disputed := numLayers - 1
+	states := common.NewMockCommonStates(4, &common.CommonState{
+		NumServers:              4,
+		NumLayers:               numLayers,
+		Round:                   disputed,
+		GroupPublicKey:          groupKey,
+		BoomerangMessageLengths: prepareMessages.BoomerangLengths(numLayers, 8, 1),
+		// the auditor is the only member of the client's group
+		GroupConfigs: &config.Groups{Groups: map[int64]*config.Group{0: {Gid: 0, Servers: []int64{0}}}},
+	})
+	client, err := prepareMessages.NewClient(states[0], 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, _, err := client.MakeOptimizedPathEstablishmentMessage(nil, numLayers, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	honest := func(server int, req *messages.SignedMessage) (*messages.SignedMessage, error) {
+		return RespondToTraceback(states[server], req)
+	}
+	auditor := states[0]
+	complain := func(e []byte) *prepareMessages.Complaint {
+		complaint := &prepareMessages.Complaint{Envelope: e}
+		complaint.Signature = client.PathKeys[0].SigningKey.Sign(complaint.SignedData(client.ID, 0, disputed))
+		return complaint
+	}
+	complaint := complain(envelope.Marshal())
+
+	// a well formed path is blamed on the client that disputed it
+	v := Traceback(auditor, int(client.ID), 0, disputed, complaint, numLayers, honest)
+	if !v.ClientBlamed() || !v.WellFormed() || len(v.Steps) != numLayers {
+		t.Fatalf("Honest path was not traced to the client")
+	}
+
+	// a server that publishes the wrong decryption is blamed
+	liar := int(client.PathKeys[1].ServerID)
+	lying := func(server int, req *messages.SignedMessage) (*messages.SignedMessage, error) {
+		m, err := honest(server, req)
+		if err != nil || server != liar || req.Layer != 1 {
+			return m, err
+		}
+		step := TracebackStep{}
+		step.InterpretFrom(m.Data)
+		step.Next[0] ^= 1
+		step.PackTo(m.Data)
+		states[server].Sign(m)
+		return m, nil
+	}
+	v = Traceback(auditor, int(client.ID), 0, disputed, complaint, numLayers, lying)
+	if v.ClientBlamed() || v.Server != liar || v.Layer != 1 {
+		t.Fatalf("Server publishing a wrong decryption was not blamed")
+	}
+
+	// a server that publishes a shared key it cannot prove is blamed
+	cheating := func(server int, req *messages.SignedMessage) (*messages.SignedMessage, error) {
+		m, err := honest(server, req)
+		if err != nil || server != liar || req.Layer != 1 {
+			return m, err
+		}
+		step := TracebackStep{}
+		step.InterpretFrom(m.Data)
+		_, step.Shared = crypto.NewDHKeyPair()
+		step.PackTo(m.Data)
+		states[server].Sign(m)
+		return m, nil
+	}
+	v = Traceback(auditor, int(client.ID), 0, disputed, complaint, numLayers, cheating)
+	if v.ClientBlamed() || v.Server != liar {
+		t.Fatalf("Server with an unproven shared key was not blamed")
+	}
+
+	// a server that does not respond is blamed
+	first := int(client.PathKeys[0].ServerID)
+	silent := func(server int, req *messages.SignedMessage) (*messages.SignedMessage, error) {
+		if server == first {
+			return nil, errors.Timeout(0, []int{server})
+		}
+		return honest(server, req)
+	}
+	v = Traceback(auditor, int(client.ID), 0, disputed, complaint, numLayers, silent)
+	if v.Server != first || v.Layer != 0 {
+		t.Fatalf("Silent server was not blamed")
+	}
+
+	// a client that corrupts the envelope of an inner layer is blamed at that layer
+	shared := client.PathKeys[0].Shared
+	nonce := crypto.Nonce(0, 0, first)
+	info := common.PathEstablishmentInfo{}
+	inner := crypto.SecretOpen(envelope.SignedCiphertext, &nonce, shared)
+	info.InterpretFrom(inner, auditor.BoomerangMessageLengths[0])
+	info.NextEnvelope[0] ^= 1
+	envelope.SignedCiphertext = client.Encrypt(info.Marshal(), client.PathKeys[0], 0, 0, first, false)
+	v = Traceback(auditor, int(client.ID), 0, disputed, complain(envelope.Marshal()), numLayers, honest)
+	if !v.ClientBlamed() || v.WellFormed() || v.Layer != 1 || len(v.Steps) != 1 {
+		t.Fatalf("Corrupted path was not blamed on the client")
+	}
+}
+
+func TestTracebackRequests(t *testing.T) {
+	numLayers := 3
+	_, groupKey := crypto.NewDHKeyPair()
+	disputed := numLayers - 1
+	states := common.NewMockCommonStates(4, &common.CommonState{
+		NumServers:              4,
+		NumLayers:               numLayers,
+		Round:                   disputed,
+		GroupPublicKey:          groupKey,
+		BoomerangMessageLengths: prepareMessages.BoomerangLengths(numLayers, 8, 1),
+		GroupConfigs:            &config.Groups{Groups: map[int64]*config.Group{0: {Gid: 0, Servers: []int64{0}}}},
+	})
+	client, err := prepareMessages.NewClient(states[0], 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, _, err := client.MakeOptimizedPathEstablishmentMessage(nil, numLayers, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &disputedPath{client: int(client.ID), group: 0, round: disputed, complaint: prepareMessages.Complaint{Envelope: envelope.Marshal()}}
+	d.complaint.Signature = client.PathKeys[0].SigningKey.Sign(d.complaint.SignedData(client.ID, 0, disputed))
+	first := int(client.PathKeys[0].ServerID)
+	second := int(client.PathKeys[1].ServerID)
+
+	step, err := RespondToTraceback(states[first], NewTracebackRequest(states[0], 0, first, d, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the next layer is only answered with the steps that lead to it
+	_, err = RespondToTraceback(states[second], NewTracebackRequest(states[0], 1, second, d, []*messages.SignedMessage{step}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RespondToTraceback(states[second], NewTracebackRequest(states[0], 1, second, d, nil)); err == nil {
+		t.Fatalf("Envelope not under the complaint was decrypted")
+	}
+	// only for a member of the client's group
+	outsider := (first + 1) % 4
+	if outsider == 0 {
+		outsider = 1
+	}
+	if _, err := RespondToTraceback(states[first], NewTracebackRequest(states[outsider], 0, first, d, nil)); err == nil {
+		t.Fatalf("Traceback answered for a server outside the client's group")
+	}
+	// only for a complaint signed by the client
+	forged := *d
+	_, sk := crypto.NewSigningKeyPair()
+	forged.complaint.Signature = sk.Sign(forged.complaint.SignedData(client.ID, 0, disputed))
+	if _, err := RespondToTraceback(states[first], NewTracebackRequest(states[0], 0, first, &forged, nil)); err == nil {
+		t.Fatalf("Traceback answered without the client's complaint")
+	}
+	// the complaint only names the client that submitted the envelope
+	if !VerifyComplaint(states[0], int(client.ID), 0, disputed, &d.complaint) {
+		t.Fatalf("Client's complaint was not verified")
+	}
+	if VerifyComplaint(states[0], int(client.ID)+1, 0, disputed, &d.complaint) {
+		t.Fatalf("Complaint verified for the envelope of another client")
+	}
+	// only while the complaint is pending
+	states[first].Round = disputed + 2
+	if _, err := RespondToTraceback(states[first], NewTracebackRequest(states[0], 0, first, d, nil)); err == nil {
+		t.Fatalf("Traceback answered for an old complaint")
+	}
+}
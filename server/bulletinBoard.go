@@ -0,0 +1,14 @@
+package server
+
+import (
+	"github.com/simonlangowski/lightning1/bulletin"
+)
+
+// Post the submissions and final messages of this server's groups to b
+func (s *Server) SetBulletinBoard(b bulletin.BulletinBoard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.GroupAliases {
+		g.board = b
+	}
+}
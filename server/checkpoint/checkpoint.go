@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/nizk"
 	"github.com/simonlangowski/lightning1/errors"
 	"github.com/simonlangowski/lightning1/network/messages"
 	"github.com/simonlangowski/lightning1/network/synchronization"
@@ -12,8 +13,12 @@ import (
 
 type VerificationKeyTable struct {
 	mu    sync.Mutex
-	keys  map[[crypto.VERIFICATION_KEY_SIZE]byte]bool
+	keys  map[[crypto.VERIFICATION_KEY_SIZE]byte]bool // the anonymous keys of the round, and whether their message arrived
 	count int
+	// the anonymous keys each last server brought in during path establishment
+	// the keys of a round are ratcheted by the last servers, which prove each is the ratchet of one of these
+	allowed    map[[crypto.VERIFICATION_KEY_SIZE]byte]int
+	registered map[int]bool // last servers that registered their keys for the round
 }
 
 type Checkpoint struct {
@@ -33,13 +38,10 @@ func NewCheckpointState(c *common.CommonState, myGroupId int, secret *crypto.DHP
 		myGroupId:   myGroupId,
 		numGroups:   c.NumGroups,
 
-		groupKeyShare: secret,
-		AnonymousSigningKeys: VerificationKeyTable{
-			keys:  make(map[[32]byte]bool),
-			count: 0,
-		},
-		synchronizer:  synchronizer,
-		FinalMessages: make([][]byte, 0),
+		groupKeyShare:        secret,
+		AnonymousSigningKeys: newVerificationKeyTable(),
+		synchronizer:         synchronizer,
+		FinalMessages:        make([][]byte, 0),
 	}
 }
 
@@ -56,15 +58,26 @@ func (c *Checkpoint) HandleCheckpointMessage(metadata *messages.Metadata, messag
 	if err != nil {
 		return errors.BadElementError()
 	}
-	c.AnonymousSigningKeys.Add(cm.AnonymousVerificationKey)
+	c.AnonymousSigningKeys.Allow(cm.AnonymousVerificationKey, metadata.Sender)
 	r := CheckpointResponse{}
-	r.PartialKey = c.groupKeyShare.Mul(pt)
+	proof, partialKey := nizk.NewDecryptionProof(pt, c.groupKeyShare)
+	r.PartialKey = *partialKey
+	r.Proof = *proof
 	r.PublicKey = cm.AnonymousVerificationKey.LookupKey()
 	r.PackTo(response)
 
 	return nil
 }
 
+func newVerificationKeyTable() VerificationKeyTable {
+	return VerificationKeyTable{
+		keys:       make(map[[crypto.VERIFICATION_KEY_SIZE]byte]bool),
+		count:      0,
+		allowed:    make(map[[crypto.VERIFICATION_KEY_SIZE]byte]int),
+		registered: make(map[int]bool),
+	}
+}
+
 func (s *VerificationKeyTable) Add(key crypto.VerificationKey) {
 	buf := [crypto.VERIFICATION_KEY_SIZE]byte{}
 	copy(buf[:], key)
@@ -73,6 +86,79 @@ func (s *VerificationKeyTable) Add(key crypto.VerificationKey) {
 	s.keys[buf] = false
 }
 
+// the last server may register one ratcheted key for the path of the key in each round
+func (s *VerificationKeyTable) Allow(key crypto.VerificationKey, server int) {
+	buf := [crypto.VERIFICATION_KEY_SIZE]byte{}
+	copy(buf[:], key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.allowed[buf]; exists {
+		return
+	}
+	s.allowed[buf] = server
+}
+
+// The last server ratchets the allowed key of a path with the scalar of the round, and proves it did
+func NewAnonymousKey(allowed crypto.VerificationKey, r *crypto.DHPrivateKey) (*AnonymousKey, error) {
+	pt, err := allowed.ToCurvePoint()
+	if err != nil {
+		return nil, errors.BadElementError()
+	}
+	proof, key := nizk.NewDecryptionProof(pt, r)
+	return &AnonymousKey{
+		Key:     crypto.VerificationKey(key.Bytes()),
+		Allowed: allowed,
+		Ratchet: *r.PublicKey(),
+		Proof:   *proof,
+	}, nil
+}
+
+// check the key is the allowed key times the scalar of the ratchet
+// so a last server cannot register a key it holds the secret of, since it does not know the secret of the allowed key
+func (k *AnonymousKey) Verify() bool {
+	allowed, err := k.Allowed.ToCurvePoint()
+	if err != nil {
+		return false
+	}
+	key, err := k.Key.ToCurvePoint()
+	if err != nil {
+		return false
+	}
+	return k.Proof.Verify(allowed, &k.Ratchet, key)
+}
+
+// the ratcheted keys of the round of the paths ending at the server
+// each must be the ratchet of a different key the server brought in, or none of them are registered
+func (s *VerificationKeyTable) Register(server int, keys []AnonymousKey) error {
+	// the proofs are checked before taking the lock
+	for i := range keys {
+		if !keys[i].Verify() {
+			return errors.Rejected("anonymous key proof")
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.registered[server] {
+		return errors.Rejected("anonymous keys already registered")
+	}
+	seen := make(map[[crypto.VERIFICATION_KEY_SIZE]byte]bool, len(keys))
+	for i := range keys {
+		buf := [crypto.VERIFICATION_KEY_SIZE]byte{}
+		copy(buf[:], keys[i].Allowed)
+		if sid, ok := s.allowed[buf]; !ok || sid != server || seen[buf] {
+			return errors.Rejected("anonymous key not allowed")
+		}
+		seen[buf] = true
+	}
+	s.registered[server] = true
+	for i := range keys {
+		buf := [crypto.VERIFICATION_KEY_SIZE]byte{}
+		copy(buf[:], keys[i].Key)
+		s.keys[buf] = false
+	}
+	return nil
+}
+
 func (s *VerificationKeyTable) GetAndMark(key crypto.VerificationKey) error {
 	buf := [crypto.VERIFICATION_KEY_SIZE]byte{}
 	copy(buf[:], key)
@@ -90,15 +176,41 @@ func (s *VerificationKeyTable) GetAndMark(key crypto.VerificationKey) error {
 	return nil
 }
 
-func (s *VerificationKeyTable) ResetSignatureMarking() {
+// the keys of the last round are not used again, the last servers register the keys of the new round
+func (s *VerificationKeyTable) NewRound() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for k := range s.keys {
-		s.keys[k] = false
-	}
+	s.keys = make(map[[crypto.VERIFICATION_KEY_SIZE]byte]bool)
+	s.registered = make(map[int]bool)
 	s.count = 0
 }
 
+// A last server registers the anonymous keys of the round of the paths ending at this group
+func (c *Checkpoint) HandleAnonymousKeys(m *messages.SignedMessage) error {
+	if m.Sender < 0 || m.Sender >= c.commonState.NumServers || !c.commonState.Verify(m) {
+		return errors.Rejected("signature")
+	}
+	if m.Round < c.commonState.Round {
+		return errors.Late(m.Round)
+	} else if m.Round != c.commonState.Round {
+		return errors.Rejected("round")
+	}
+	k := AnonymousKeys{}
+	err := k.InterpretFrom(m.Data)
+	if err != nil {
+		return err
+	}
+	return c.AnonymousSigningKeys.Register(m.Sender, k.Keys)
+}
+
+func NewAnonymousKeys(c *common.CommonState, group, dest int, keys []AnonymousKey) *messages.SignedMessage {
+	k := AnonymousKeys{Keys: keys}
+	m := messages.NewSignedMessage(k.Len(), c.Round, c.NumLayers, c.MyId, group, dest, len(keys), messages.NetworkMessage_ServerAnonymousKeys)
+	k.PackTo(m.Data)
+	c.Sign(m)
+	return m
+}
+
 func (c *Checkpoint) HandleTrusteeMessage(metadata *messages.Metadata, message []byte) error {
 	// err := c.synchronizer.SyncOnce(int(metadata.Layer), int(metadata.Sender))
 
@@ -121,6 +233,28 @@ func (c *Checkpoint) HandleTrusteeMessage(metadata *messages.Metadata, message [
 	return nil
 }
 
+// remove the keys that were never marked, and return them
+func (s *VerificationKeyTable) DropUnmarked() []crypto.VerificationKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := make([]crypto.VerificationKey, 0)
+	for k, used := range s.keys {
+		if !used {
+			key := make([]byte, crypto.VERIFICATION_KEY_SIZE)
+			copy(key, k[:])
+			dropped = append(dropped, key)
+			delete(s.keys, k)
+		}
+	}
+	return dropped
+}
+
+// The users whose final messages did not arrive are removed
+// The link that dropped each message was blamed by the server that was missing it
+func (c *Checkpoint) DropMissingSignatures() []crypto.VerificationKey {
+	return c.AnonymousSigningKeys.DropUnmarked()
+}
+
 func (c *Checkpoint) AllSignaturesAccountedFor() bool {
 	return c.AnonymousSigningKeys.count == len(c.AnonymousSigningKeys.keys)
 }
@@ -2,6 +2,7 @@ package checkpoint
 
 import (
 	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/nizk"
 	"github.com/simonlangowski/lightning1/crypto/token"
 )
 
@@ -14,4 +15,19 @@ type CheckpointInfo struct {
 type CheckpointResponse struct {
 	PublicKey  crypto.LookupKey
 	PartialKey crypto.DHPublicKey
+	Proof      nizk.DecryptionProof // PartialKey is the decryption with the member's share of the group key
+}
+
+// Sent by a last server to the members of a group at the start of each lightning round
+// the ratcheted anonymous keys of the round of the paths ending at the group
+type AnonymousKeys struct {
+	Keys []AnonymousKey
+}
+
+// The anonymous key of a path in one round, bound to the key the group allowed for the path
+type AnonymousKey struct {
+	Key     crypto.VerificationKey // the allowed key times the scalar of the round
+	Allowed crypto.VerificationKey
+	Ratchet crypto.DHPublicKey   // the scalar of the round times the generator
+	Proof   nizk.DecryptionProof // Key is the decryption of Allowed with the scalar of Ratchet
 }
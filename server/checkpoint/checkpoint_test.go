@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
 )
 
 func TestDecryptionLogic(t *testing.T) {
@@ -24,3 +25,64 @@ func TestDecryptionLogic(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestRegisterAnonymousKeys(t *testing.T) {
+	table := newVerificationKeyTable()
+	round := 3
+	keys := make([]AnonymousKey, 4)
+	for i := range keys {
+		vk, _ := crypto.NewSigningKeyPair()
+		table.Allow(vk, 1)
+		secret, _ := crypto.NewDHKeyPair()
+		k, err := NewAnonymousKey(vk, crypto.RatchetScalar(secret.Bytes(), round))
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = *k
+	}
+	// a key whose secret the last server holds, proven against a key it made up
+	ownVerificationKey, _ := crypto.NewSigningKeyPair()
+	own, _ := NewAnonymousKey(ownVerificationKey, crypto.RatchetScalar([]byte("own"), round))
+	// or claimed to be the ratchet of an allowed key
+	forged := *own
+	forged.Allowed = keys[3].Allowed
+	for _, bad := range [][]AnonymousKey{
+		{keys[0], *own},
+		{keys[0], forged},
+		{keys[0], keys[0]},
+	} {
+		if _, ok := table.Register(1, bad).(*errors.RejectedError); !ok {
+			t.Fatalf("Registered keys not bound to allowed keys")
+		}
+		if len(table.keys) != 0 {
+			t.Fatalf("Keys of a rejected registration were registered")
+		}
+	}
+	// the allowed keys of another last server
+	if _, ok := table.Register(2, keys[:1]).(*errors.RejectedError); !ok {
+		t.Fatalf("Registered the keys of another server")
+	}
+	if err := table.Register(1, keys); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := table.Register(1, keys).(*errors.RejectedError); !ok {
+		t.Fatalf("Registered twice in a round")
+	}
+	for i := range keys {
+		if table.GetAndMark(keys[i].Key) != nil {
+			t.Fatalf("Registered key %d not found", i)
+		}
+	}
+
+	// registrations survive marshalling
+	m := AnonymousKeys{Keys: keys}
+	b := make([]byte, m.Len())
+	m.PackTo(b)
+	received := AnonymousKeys{}
+	if received.InterpretFrom(b) != nil || len(received.Keys) != len(keys) || !received.Keys[2].Verify() {
+		t.Fatalf("Anonymous keys did not survive marshalling")
+	}
+	if _, ok := received.InterpretFrom(b[:len(b)-1]).(*errors.RejectedError); !ok {
+		t.Fatalf("Short anonymous keys not rejected")
+	}
+}
@@ -1,14 +1,17 @@
 package checkpoint
 
 import (
+	"encoding/binary"
+
 	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/nizk"
 	"github.com/simonlangowski/lightning1/crypto/token"
 	"github.com/simonlangowski/lightning1/errors"
 )
 
 var TOKEN_MESSAGE_LENGTH = token.TOKEN_SIZE + crypto.VERIFICATION_KEY_SIZE
 
-const RESPONSE_LENGTH = crypto.KEY_SIZE + crypto.POINT_SIZE
+const RESPONSE_LENGTH = crypto.KEY_SIZE + crypto.POINT_SIZE + nizk.DECRYPTION_PROOF_SIZE
 
 func (c *CheckpointInfo) Len() int {
 	return TOKEN_MESSAGE_LENGTH
@@ -48,14 +51,99 @@ func (c *CheckpointResponse) InterpretFrom(b []byte) error {
 	if len(b) != c.Len() {
 		return errors.LengthInvalidError()
 	}
-	copy(c.PublicKey[:], b[:crypto.KEY_SIZE])
-	return c.PartialKey.InterpretFrom(b[crypto.KEY_SIZE:])
+	pos := crypto.KEY_SIZE
+	copy(c.PublicKey[:], b[:pos])
+	err := c.PartialKey.InterpretFrom(b[pos : pos+crypto.POINT_SIZE])
+	if err != nil {
+		return err
+	}
+	pos += crypto.POINT_SIZE
+	return c.Proof.InterpretFrom(b[pos:])
 }
 
 func (c *CheckpointResponse) PackTo(b []byte) {
 	if len(b) != c.Len() {
 		panic(errors.LengthInvalidError())
 	}
-	copy(b[:crypto.KEY_SIZE], c.PublicKey[:])
-	c.PartialKey.PackTo(b[crypto.KEY_SIZE:])
+	pos := crypto.KEY_SIZE
+	copy(b[:pos], c.PublicKey[:])
+	c.PartialKey.PackTo(b[pos : pos+crypto.POINT_SIZE])
+	pos += crypto.POINT_SIZE
+	c.Proof.PackTo(b[pos:])
+}
+
+const ANONYMOUS_KEY_LENGTH = 2*crypto.VERIFICATION_KEY_SIZE + crypto.POINT_SIZE + nizk.DECRYPTION_PROOF_SIZE
+
+func (k *AnonymousKey) Len() int {
+	return ANONYMOUS_KEY_LENGTH
+}
+
+func (k *AnonymousKey) PackTo(b []byte) {
+	if len(b) != k.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	pos := 0
+	k.Key.PackTo(b[pos : pos+crypto.VERIFICATION_KEY_SIZE])
+	pos += crypto.VERIFICATION_KEY_SIZE
+	k.Allowed.PackTo(b[pos : pos+crypto.VERIFICATION_KEY_SIZE])
+	pos += crypto.VERIFICATION_KEY_SIZE
+	k.Ratchet.PackTo(b[pos : pos+crypto.POINT_SIZE])
+	pos += crypto.POINT_SIZE
+	k.Proof.PackTo(b[pos:])
+}
+
+// sent by another server, so bad keys are rejected
+func (k *AnonymousKey) InterpretFrom(b []byte) error {
+	if len(b) != k.Len() {
+		return errors.Rejected("length")
+	}
+	pos := 0
+	k.Key = crypto.VerificationKey(b[pos : pos+crypto.VERIFICATION_KEY_SIZE])
+	pos += crypto.VERIFICATION_KEY_SIZE
+	k.Allowed = crypto.VerificationKey(b[pos : pos+crypto.VERIFICATION_KEY_SIZE])
+	pos += crypto.VERIFICATION_KEY_SIZE
+	if k.Ratchet.InterpretFrom(b[pos:pos+crypto.POINT_SIZE]) != nil {
+		return errors.Rejected("point")
+	}
+	pos += crypto.POINT_SIZE
+	if k.Proof.InterpretFrom(b[pos:]) != nil {
+		return errors.Rejected("proof")
+	}
+	return nil
+}
+
+func (k *AnonymousKeys) Len() int {
+	return 4 + len(k.Keys)*ANONYMOUS_KEY_LENGTH
+}
+
+func (k *AnonymousKeys) PackTo(b []byte) {
+	if len(b) != k.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	binary.LittleEndian.PutUint32(b, uint32(len(k.Keys)))
+	pos := 4
+	for i := range k.Keys {
+		k.Keys[i].PackTo(b[pos : pos+ANONYMOUS_KEY_LENGTH])
+		pos += ANONYMOUS_KEY_LENGTH
+	}
+}
+
+func (k *AnonymousKeys) InterpretFrom(b []byte) error {
+	if len(b) < 4 {
+		return errors.Rejected("length")
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	if n > (len(b)-4)/ANONYMOUS_KEY_LENGTH || len(b) != 4+n*ANONYMOUS_KEY_LENGTH {
+		return errors.Rejected("length")
+	}
+	k.Keys = make([]AnonymousKey, n)
+	pos := 4
+	for i := range k.Keys {
+		err := k.Keys[i].InterpretFrom(b[pos : pos+ANONYMOUS_KEY_LENGTH])
+		if err != nil {
+			return err
+		}
+		pos += ANONYMOUS_KEY_LENGTH
+	}
+	return nil
 }
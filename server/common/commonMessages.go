@@ -38,12 +38,6 @@ type FinalLightningMessage struct {
 	Message                  []byte                 // The user's message
 }
 
-// Zero is not a point on the curve
-// We can do this because hidden by TLS, but isn't constant time
-func (l *LightningEnvelope) IsDummy() bool {
-	return l.Key == crypto.LookupKey{}
-}
-
 func ValidateSignature(key crypto.VerificationKey, m *messages.SignedMessage) bool {
 	return crypto.VerifyMessage(key, m.GetSignedData(), m.Signature)
 }
@@ -3,10 +3,13 @@ package common
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"sync/atomic"
 
 	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
 	"github.com/simonlangowski/lightning1/crypto"
 	"github.com/simonlangowski/lightning1/crypto/token"
+	"github.com/simonlangowski/lightning1/errors"
 	"github.com/simonlangowski/lightning1/network/messages"
 )
 
@@ -36,13 +39,16 @@ type CommonState struct {
 	ServerPublicKeys []crypto.DHPublicKey // server authenticated encryption public keys
 	ServerSecretKey  crypto.DHPrivateKey  // corresponding to the public diffie helman key parts for each server
 
-	CombinedKey *token.TokenPublicKey // public key shared by all anytrust groups
-	// PublicGroupKeys [][]*token.TokenPublicKey // group, server, used for signing tokens
+	CombinedKey     *token.TokenPublicKey     // public key shared by all anytrust groups
+	PublicGroupKeys [][]*token.TokenPublicKey // group, position, used to check partial token signatures
+	GroupShareKeys  [][]crypto.DHPublicKey    // group, position, used to check partial decryptions
 
 	GroupPublicKey crypto.DHPublicKey // public key shared by all anytrust groups
 	// a different secret is held for each group this server is a member of, in checkpoint.go
 
-	RevokedKeys []map[crypto.DHPublicKey]bool // user keys revoked at each layer
+	// []bool of the servers the coordinator dropped; their users are routed to a replacement
+	// replaced rather than changed, since it is read while messages are processed
+	dropped atomic.Value
 
 	Shufflers []*config.Shuffler
 }
@@ -102,8 +108,84 @@ func (c *CommonState) Verify(m *messages.SignedMessage) bool {
 	return crypto.VerifyExpanded(c.ExpandedVerificationKeys[m.Sender], m.GetSignedData(), m.Signature)
 }
 
-func (c *CommonState) IsRevoked(layer int, key *crypto.DHPublicKey) bool {
-	return c.RevokedKeys[layer][*key]
+// Only for servers every server agrees were dropped, i.e. those in the round info from the coordinator,
+// since the servers route by the list
+// copies the list, so that states sharing the old list (e.g. clients) do not change mid round
+func (c *CommonState) DropServers(ids []int64) {
+	dropped := make([]bool, c.NumServers)
+	copy(dropped, c.droppedList())
+	for _, sid := range ids {
+		if sid >= 0 && sid < int64(c.NumServers) {
+			dropped[sid] = true
+		}
+	}
+	c.dropped.Store(dropped)
+}
+
+// share the dropped servers of another state
+func (c *CommonState) CopyDropped(from *CommonState) {
+	c.dropped.Store(from.droppedList())
+}
+
+func (c *CommonState) droppedList() []bool {
+	dropped, _ := c.dropped.Load().([]bool)
+	return dropped
+}
+
+func (c *CommonState) IsDropped(sid int) bool {
+	dropped := c.droppedList()
+	return sid >= 0 && sid < len(dropped) && dropped[sid]
+}
+
+func (c *CommonState) DroppedServers() []int64 {
+	ids := make([]int64, 0)
+	for sid, dropped := range c.droppedList() {
+		if dropped {
+			ids = append(ids, int64(sid))
+		}
+	}
+	return ids
+}
+
+// the next server that was not dropped takes over the users of a dropped server
+func (c *CommonState) Replacement(sid int) int {
+	dropped := c.droppedList()
+	for i := 0; i < c.NumServers; i++ {
+		r := (sid + i) % c.NumServers
+		if r >= len(dropped) || !dropped[r] {
+			return r
+		}
+	}
+	return sid
+}
+
+// the public keys of the token and group key shares of each group, from the key exchange
+func (c *CommonState) SetPublicGroupKeys(keys map[int64]*coord.ShareKeys) error {
+	publicGroupKeys := make([][]*token.TokenPublicKey, c.NumGroups)
+	groupShareKeys := make([][]crypto.DHPublicKey, c.NumGroups)
+	for gid, k := range keys {
+		if gid < 0 || gid >= int64(c.NumGroups) {
+			return errors.BadMetadataError()
+		}
+		publicGroupKeys[gid] = make([]*token.TokenPublicKey, len(k.Keys))
+		for i := range k.Keys {
+			publicGroupKeys[gid][i] = &token.TokenPublicKey{}
+			err := publicGroupKeys[gid][i].InterpretFrom(k.Keys[i])
+			if err != nil {
+				return err
+			}
+		}
+		groupShareKeys[gid] = make([]crypto.DHPublicKey, len(k.GroupKeys))
+		for i := range k.GroupKeys {
+			err := groupShareKeys[gid][i].InterpretFrom(k.GroupKeys[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	c.PublicGroupKeys = publicGroupKeys
+	c.GroupShareKeys = groupShareKeys
+	return nil
 }
 
 func NewMockCommonStates(n int, template *CommonState) []*CommonState {
@@ -112,6 +194,7 @@ func NewMockCommonStates(n int, template *CommonState) []*CommonState {
 	// return only one group?
 	states := make([]*CommonState, n)
 	publicSignatureKeys := make([]crypto.VerificationKey, n)
+	expandedKeys := make([]*crypto.ExpandedVerificationKey, n)
 	authPublicKeys := make([]crypto.DHPublicKey, n)
 	if template == nil {
 		template = &CommonState{NumServers: n}
@@ -120,11 +203,13 @@ func NewMockCommonStates(n int, template *CommonState) []*CommonState {
 		verifyKey, signingKey := crypto.NewSigningKeyPair()
 		privateKey, publicKey := crypto.NewDHKeyPair()
 		publicSignatureKeys[i] = verifyKey
+		expandedKeys[i], _ = verifyKey.ExpandKey()
 		authPublicKeys[i] = publicKey
 		states[i] = &CommonState{}
 		*states[i] = *template
 		states[i].MyId = i
 		states[i].VerificationKeys = publicSignatureKeys
+		states[i].ExpandedVerificationKeys = expandedKeys
 		states[i].SecretSigningKey = signingKey
 		states[i].ServerPublicKeys = authPublicKeys
 		states[i].ServerSecretKey = privateKey
@@ -137,5 +222,5 @@ func (c *CommonState) HashToGroup(hash *[token.HASH_SIZE]byte) uint64 {
 }
 
 func (c *CommonState) HashToServer(hash *[token.HASH_SIZE]byte) uint64 {
-	return binary.LittleEndian.Uint64(hash[:]) % uint64(c.NumServers)
+	return uint64(c.Replacement(int(binary.LittleEndian.Uint64(hash[:]) % uint64(c.NumServers))))
 }
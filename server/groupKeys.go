@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
+	"github.com/simonlangowski/lightning1/server/keyExchange"
+)
+
+// Run the key exchange with the other servers, and return the public keys
+// only public keys leave this server
+func (s *Server) exchangeKeys() (*coord.KeyInformation, error) {
+	if s.Caller == nil {
+		err := s.Connect()
+		if err != nil {
+			return nil, err
+		}
+	}
+	keys, err := s.keyExchange.Run(s.Caller)
+	if err != nil {
+		return nil, err
+	}
+	s.groupKeys = keys
+	resp := &coord.KeyInformation{TokenShareKeys: make(map[int64]*coord.ShareKeys)}
+	for gid, k := range keys {
+		s.saveGroupKeys(k)
+		resp.TokenShareKeys[int64(gid)] = shareKeys(k)
+		// every group has the same public keys
+		resp.GroupId = int64(gid)
+		resp.TokenPublicKey = make([]byte, mcl.G2_LEN)
+		k.TokenPublicKey.PackTo(resp.TokenPublicKey)
+		resp.GroupKey = make([]byte, crypto.POINT_SIZE)
+		k.GroupPublicKey.PackTo(resp.GroupKey)
+	}
+	return resp, nil
+}
+
+// the public keys of the token and group key shares of each member of the group
+func shareKeys(k *keyExchange.GroupKeys) *coord.ShareKeys {
+	sk := &coord.ShareKeys{
+		Keys:      make([][]byte, len(k.TokenShareKeys)),
+		GroupKeys: make([][]byte, len(k.GroupShareKeys)),
+	}
+	for i := range k.TokenShareKeys {
+		sk.Keys[i] = make([]byte, mcl.G2_LEN)
+		k.TokenShareKeys[i].PackTo(sk.Keys[i])
+	}
+	for i := range k.GroupShareKeys {
+		sk.GroupKeys[i] = make([]byte, crypto.POINT_SIZE)
+		k.GroupShareKeys[i].PackTo(sk.GroupKeys[i])
+	}
+	return sk
+}
+
+func (s *Server) groupKeyFile(gid int32) string {
+	return filepath.Join(s.keyDirectory, fmt.Sprintf("group%d.keys", gid))
+}
+
+func (s *Server) saveGroupKeys(k *keyExchange.GroupKeys) {
+	if s.keyDirectory == "" {
+		return
+	}
+	b := make([]byte, k.Len())
+	k.PackTo(b)
+	tmp := s.groupKeyFile(k.Group) + ".tmp"
+	err := os.WriteFile(tmp, b, 0600)
+	if err == nil {
+		err = os.Rename(tmp, s.groupKeyFile(k.Group))
+	}
+	if err != nil {
+		// the keys can still be used until this server restarts
+		log.Printf("%d: could not save keys for group %d: %v", s.CommonState.MyId, k.Group, err)
+	}
+}
+
+// load keys from a previous key exchange for the groups this server is in
+func (s *Server) loadGroupKeys() error {
+	keys := make(map[int32]*keyExchange.GroupKeys)
+	for gid := range s.GroupAliases {
+		b, err := os.ReadFile(s.groupKeyFile(gid))
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		k := &keyExchange.GroupKeys{}
+		err = k.InterpretFrom(b)
+		if err != nil {
+			return err
+		}
+		keys[gid] = k
+	}
+	s.groupKeys = keys
+	return nil
+}
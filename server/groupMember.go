@@ -1,8 +1,10 @@
 package server
 
 import (
+	"log"
 	"sync"
 
+	"github.com/simonlangowski/lightning1/bulletin"
 	"github.com/simonlangowski/lightning1/crypto"
 	"github.com/simonlangowski/lightning1/crypto/token"
 	"github.com/simonlangowski/lightning1/errors"
@@ -10,7 +12,6 @@ import (
 	"github.com/simonlangowski/lightning1/network/synchronization"
 	"github.com/simonlangowski/lightning1/server/checkpoint"
 	"github.com/simonlangowski/lightning1/server/common"
-	"github.com/simonlangowski/lightning1/server/keyExchange"
 	"github.com/simonlangowski/lightning1/server/prepareMessages"
 )
 
@@ -29,7 +30,6 @@ final decryption release
 type groupMember struct {
 	c                      *common.CommonState
 	CheckpointState        *checkpoint.Checkpoint
-	keyExchange            []*keyExchange.KeyExchange
 	messagePreparer        *prepareMessages.MessagePreparer
 	signingKey             token.TokenSigningKey
 	secretShare            crypto.DHPrivateKey
@@ -38,13 +38,14 @@ type groupMember struct {
 	mu                     sync.Mutex
 	messagesReady          bool
 	messagesWait           *sync.Cond
+	fragmented             bool // reassemble the final messages
+	board                  bulletin.BulletinBoard
 }
 
 func NewGroupMember(myGroupNumber int, common *common.CommonState) *groupMember {
 	g := &groupMember{
 		c:             common,
 		myGroupNumber: myGroupNumber,
-		// keyExchange:   make([]*keyExchange.KeyExchange, numLayers),
 	}
 	g.messagesWait = sync.NewCond(&g.mu)
 	g.checkpointSynchronizer = synchronization.NewSynchronizer(g.c.Round, 0, g.c.NumServers, g)
@@ -52,42 +53,61 @@ func NewGroupMember(myGroupNumber int, common *common.CommonState) *groupMember
 	// these take pointers to the keys, whose values will be set layer
 	g.CheckpointState = checkpoint.NewCheckpointState(g.c, g.myGroupNumber, &g.secretShare, g.checkpointSynchronizer)
 	g.messagePreparer = prepareMessages.NewMessagePreparer(common, &g.signingKey, myGroupNumber)
-
-	// for i := range g.keyExchange {
-	// 	if g.myGroupNumber == config.MASTER_GROUP {
-	// 		g.keyExchange[i] = keyExchange.NewMasterKeyExchange(i, common.MasterGroupSize)
-	// 	} else {
-	// 		g.keyExchange[i] = keyExchange.NewKeyExchange(i, common.MasterGroupSize)
-	// 	}
-	// }
 	return g
 }
 
 func (g *groupMember) OnThreshold(layer int) (int, int) {
-	if g.CheckpointState.AllSignaturesAccountedFor() {
-		g.mu.Lock()
-		defer g.mu.Unlock()
-		g.messagesReady = true
-		g.messagesWait.Broadcast()
-	} else {
-		// do blame and recovery protocol
-		panic(errors.MissingMessages())
+	if !g.CheckpointState.AllSignaturesAccountedFor() {
+		// continue without the missing users
+		dropped := g.CheckpointState.DropMissingSignatures()
+		log.Printf("%d: group %d dropped %d users in round %d", g.c.MyId, g.myGroupNumber, len(dropped), g.c.Round)
+	}
+	if g.fragmented {
+		// fragments that reached other groups are reassembled when the outputs of the groups are combined
+		g.CheckpointState.FinalMessages = prepareMessages.Reassemble(g.CheckpointState.FinalMessages)
 	}
+	g.postFinalMessages()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.messagesReady = true
+	g.messagesWait.Broadcast()
 	return g.c.NumServers, layer + 1
 }
 
-func (g *groupMember) NewLightningRound(checkpointLayer int) {
+func (g *groupMember) NewLightningRound(checkpointLayer int, fragmented bool) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.CheckpointState.FinalMessages = make([][]byte, 0)
+	g.fragmented = fragmented
 	g.messagesReady = false
 	g.checkpointSynchronizer.Reset(g.c.Round, checkpointLayer, g.c.NumServers)
-	g.CheckpointState.AnonymousSigningKeys.ResetSignatureMarking()
+	g.CheckpointState.AnonymousSigningKeys.NewRound()
 }
 
+// The post contains the client's signed submission, which is posted publicly once checked
 func (g *groupMember) HandleMessageSubmission(m *messages.SignedMessage) error {
-	// post starting message to public bulletin board?
-	return g.messagePreparer.MarkSubmitted(int64(m.Sender), m)
+	submission := bulletin.ParseSubmission(m.Data)
+	if submission == nil || submission.Sender != m.Sender {
+		return errors.Rejected("submission")
+	}
+	err := g.messagePreparer.MarkSubmitted(int64(submission.Sender), submission)
+	if err != nil || g.board == nil {
+		return err
+	}
+	_, err = g.board.Post(bulletin.SubmissionEntry(submission))
+	return err
+}
+
+// sign and post the messages released by the group this round
+func (g *groupMember) postFinalMessages() {
+	if g.board == nil || len(g.CheckpointState.FinalMessages) == 0 {
+		return
+	}
+	f := bulletin.NewFinalMessages(g.c.Round, g.myGroupNumber, g.c.MyId, g.CheckpointState.FinalMessages, g.c.SecretSigningKey)
+	_, err := g.board.Post(f.Marshal())
+	if err != nil {
+		log.Printf("%d: could not post final messages for group %d: %v", g.c.MyId, g.myGroupNumber, err)
+	}
 }
 
 func (g *groupMember) SetKeys(t *token.TokenSigningKey, s *crypto.DHPrivateKey) {
@@ -103,33 +123,3 @@ func (g *groupMember) GetMessages() [][]byte {
 	}
 	return g.CheckpointState.FinalMessages
 }
-
-// func (g *groupMember) ExchangeKeys(c *network.Caller) error {
-// 	// could be done in parallel
-// 	errs := make(chan error)
-// 	for _, k := range g.keyExchange {
-// 		go func(k *keyExchange.KeyExchange) {
-// 			if g.myGroupNumber == config.MASTER_GROUP {
-// 				errs <- k.StartKeyExchange(c)
-// 			} else {
-// 				errs <- k.RetrieveKeys(c)
-// 			}
-// 		}(k)
-// 	}
-// 	for range g.keyExchange {
-// 		err := <-errs
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-// 	return nil
-// }
-
-// // trustee key?
-// func (g *groupMember) GetPublicKeys() ([]crypto.CurvePoint, token.TokenPublicKey) {
-// 	signingKeys := make([]crypto.CurvePoint, len(g.keyExchange))
-// 	for i, k := range g.keyExchange {
-// 		signingKeys[i] = k.GetPublicKeys()
-// 	}
-// 	return signingKeys
-// }
@@ -66,6 +66,26 @@ func (h *Handlers) HandleSignedMessage(_ context.Context, m *messages.NetworkMes
 	if message == nil {
 		return nil, errors.BadMetadataError()
 	}
+	if message.Type == messages.NetworkMessage_KeySharePush {
+		// keys are exchanged before any round
+		return &messages.NetworkMessage{}, h.s.keyExchange.ReceiveKeyShare(message)
+	} else if message.Type == messages.NetworkMessage_BeaconPush {
+		// the beacon runs between rounds
+		return &messages.NetworkMessage{}, h.s.beacon.ReceiveBeacon(message)
+	} else if message.Type == messages.NetworkMessage_ServerRevocation {
+		// the key tables outlive rounds, and the sender may be blocked finishing a layer
+		return &messages.NetworkMessage{}, h.s.HandleRevocation(message)
+	} else if message.Type == messages.NetworkMessage_ServerPathRepair {
+		// like a revocation, the path repair changes the key tables
+		return &messages.NetworkMessage{}, h.s.HandlePathRepair(message)
+	} else if message.Type == messages.NetworkMessage_ServerTraceback {
+		// a traceback disputes a path established in an earlier round
+		response, err := h.s.HandleTraceback(message)
+		if err != nil {
+			return nil, err
+		}
+		return response.AsNetworkMessage(), nil
+	}
 	err := h.WaitForRound(message.Round)
 	if err != nil {
 		return nil, err
@@ -76,9 +96,6 @@ func (h *Handlers) HandleSignedMessage(_ context.Context, m *messages.NetworkMes
 	}
 	var response *messages.SignedMessage = nil
 	switch message.Type {
-	// Receive a share of a key
-	case messages.NetworkMessage_KeySharePush:
-		response, err = nil, h.s.GroupAliases[message.Group].keyExchange[message.Layer].ReceiveKeyShare(message)
 	case messages.NetworkMessage_ClientRegister:
 		response, err = nil, h.s.GroupAliases[message.Group].messagePreparer.RegisterClient(message)
 		// Request token signing from servers
@@ -89,8 +106,18 @@ func (h *Handlers) HandleSignedMessage(_ context.Context, m *messages.NetworkMes
 		// TODO: Use anytrust group to check this signature
 		// Also if this is too large, will also need to read from stream
 		response, err = h.s.HandleSubmissionMessage(message)
+	case messages.NetworkMessage_ClientBulletinPost:
+		response, err = nil, h.s.GroupAliases[message.Group].HandleMessageSubmission(message)
+	case messages.NetworkMessage_ClientComplaint:
+		response, err = nil, h.s.HandleComplaint(message)
+	case messages.NetworkMessage_ServerAnonymousKeys:
+		response, err = nil, h.s.GroupAliases[message.Group].CheckpointState.HandleAnonymousKeys(message)
 	case messages.NetworkMessage_ClientGetReceipt:
 		response, err = h.s.GetReceipt(message)
+	case messages.NetworkMessage_ClientGetReceiptBucket:
+		response, err = h.s.GetReceiptBucket(message)
+	case messages.NetworkMessage_ServerBlameRequest:
+		response, err = h.s.HandleBlameRequest(message)
 	default:
 		err = errors.UnrecognizedError()
 	}
@@ -141,6 +168,9 @@ func (h *Handlers) HandleTcpStream(c *network.ConnectionManager, source int, vk
 func (h *Handlers) checkForGroup(t messages.NetworkMessage_MessageType, group int32) (bool, bool) {
 	if t == messages.NetworkMessage_ClientRegister ||
 		t == messages.NetworkMessage_ClientTokenRequest ||
+		t == messages.NetworkMessage_ClientBulletinPost ||
+		t == messages.NetworkMessage_ClientComplaint ||
+		t == messages.NetworkMessage_ServerAnonymousKeys ||
 		t == messages.NetworkMessage_GroupCheckpointToken ||
 		t == messages.NetworkMessage_GroupCheckpointSignature {
 		_, exists := h.s.GroupAliases[group]
@@ -157,10 +187,14 @@ func (h *Handlers) HealthCheck(_ context.Context, _ *messages.NetworkMessage) (*
 // to skip path establishment and just test lightning
 func (h *Handlers) SkipPathGen(_ context.Context, k *messages.SkipPathGenMessage) (*messages.NetworkMessage, error) {
 	if k.Group >= 0 {
-		// anonymous verification key
+		// anonymous verification key of the path, and its ratcheted key for this round
+		// the last server registers the ratcheted keys of later rounds
 		verKey := crypto.VerificationKey{}
+		roundKey := crypto.VerificationKey{}
 		verKey.InterpretFrom(k.SendingKey)
-		h.s.GroupAliases[k.Group].CheckpointState.AnonymousSigningKeys.Add(verKey)
+		roundKey.InterpretFrom(k.ForwardKey)
+		h.s.GroupAliases[k.Group].CheckpointState.AnonymousSigningKeys.Allow(verKey, int(k.SendingServer))
+		h.s.GroupAliases[k.Group].CheckpointState.AnonymousSigningKeys.Add(roundKey)
 	} else {
 		sendingKey := crypto.VerificationKey{}
 		forwardingKey := crypto.VerificationKey{}
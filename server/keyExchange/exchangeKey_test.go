@@ -6,10 +6,15 @@ import (
 	"testing"
 
 	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/crypto"
 	"github.com/simonlangowski/lightning1/crypto/ec"
+	"github.com/simonlangowski/lightning1/crypto/pairing"
+	"github.com/simonlangowski/lightning1/crypto/pairing/kyber_wrap"
+	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
+	"github.com/simonlangowski/lightning1/crypto/token"
 	"github.com/simonlangowski/lightning1/network"
 	"github.com/simonlangowski/lightning1/network/messages"
-	"github.com/simonlangowski/lightning1/network/synchronization"
+	"github.com/simonlangowski/lightning1/server/common"
 )
 
 func TestSigningKeyShare(t *testing.T) {
@@ -42,83 +47,104 @@ func TestSigningKeyShare(t *testing.T) {
 }
 
 type MockKeyHandler struct {
-	k             *KeyExchange
-	t             *testing.T
-	expectedLayer int
+	k *KeyExchange
+	t *testing.T
 	messages.UnimplementedMessageHandlersServer
 }
 
 func (m *MockKeyHandler) HandleSignedMessage(_ context.Context, raw *messages.NetworkMessage) (*messages.NetworkMessage, error) {
 	message := messages.ParseSignedMessage(raw)
-	if message.Layer != m.expectedLayer {
-		m.t.FailNow()
-	}
 	if message.Type != messages.NetworkMessage_KeySharePush {
 		m.t.FailNow()
 	}
-	return nil, m.k.ReceiveKeyShare(message)
+	return &messages.NetworkMessage{}, m.k.ReceiveKeyShare(message)
 }
 
 func TestKeyExchange(t *testing.T) {
-	masterGroupSize := 3
-	otherGroupSize := 3
-	layerNumber := 5
-	masterGroup := make([]*KeyExchange, masterGroupSize)
-	for i := range masterGroup {
-		masterGroup[i] = NewMasterKeyExchange(i, layerNumber, masterGroupSize)
-	}
-	otherGroup := make([]*KeyExchange, otherGroupSize)
-	for i := range masterGroup {
-		otherGroup[i] = NewKeyExchange(i, layerNumber, masterGroupSize)
+	numServers := 6
+	groups := map[int64]*config.Group{
+		config.MASTER_GROUP: {Gid: config.MASTER_GROUP, Servers: []int64{0, 1, 2}},
+		// server 2 is in both groups, and server 5 in neither
+		1: {Gid: 1, Servers: []int64{2, 3, 4}},
 	}
-	mockMasterHandlers := make([]*MockKeyHandler, masterGroupSize)
-	mockOtherHandlers := make([]*MockKeyHandler, otherGroupSize)
-	allHandlers := make([]messages.MessageHandlersServer, masterGroupSize+otherGroupSize)
-	groups := make(map[int64]*config.Group)
-	groups[0] = &config.Group{
-		Gid:     0,
-		Servers: make([]int64, 0),
+	states := common.NewMockCommonStates(numServers, &common.CommonState{
+		NumServers:   numServers,
+		GroupConfigs: &config.Groups{Groups: groups},
+	})
+	exchanges := make([]*KeyExchange, numServers)
+	handlers := make([]messages.MessageHandlersServer, numServers)
+	for i := range exchanges {
+		exchanges[i] = NewKeyExchange(states[i])
+		handlers[i] = &MockKeyHandler{k: exchanges[i], t: t}
 	}
-	groups[1] = &config.Group{
-		Gid:     1,
-		Servers: make([]int64, 0),
+	type result struct {
+		id   int
+		keys map[int32]*GroupKeys
+		err  error
 	}
-	for i := range mockMasterHandlers {
-		mockMasterHandlers[i] = &MockKeyHandler{
-			k:             masterGroup[i],
-			t:             t,
-			expectedLayer: layerNumber,
-		}
-		allHandlers[i] = mockMasterHandlers[i]
-		groups[0].Servers = append(groups[0].Servers, int64(i))
+	done := make(chan result)
+	for i, k := range exchanges {
+		go func(i int, k *KeyExchange) {
+			caller := network.NewMockCaller(handlers)
+			caller.SetGroups(groups)
+			keys, err := k.Run(caller)
+			done <- result{i, keys, err}
+		}(i, k)
 	}
-	for i := range mockOtherHandlers {
-		mockOtherHandlers[i] = &MockKeyHandler{
-			k:             otherGroup[i],
-			t:             t,
-			expectedLayer: layerNumber,
+	keys := make([]map[int32]*GroupKeys, numServers)
+	for range exchanges {
+		r := <-done
+		if r.err != nil {
+			t.Fatal(r.err)
 		}
-		allHandlers[i+masterGroupSize] = mockOtherHandlers[i]
-		groups[1].Servers = append(groups[1].Servers, int64(i+masterGroupSize))
+		keys[r.id] = r.keys
 	}
-	done := make(chan error)
-	for i, k := range masterGroup {
-		go func(i int, k *KeyExchange) {
-			mockNetwork := network.NewMockCaller(allHandlers)
-			mockNetwork.SetGroups(groups)
-			done <- k.StartKeyExchange(mockNetwork)
-		}(i, k)
+	if len(keys[2]) != 2 || len(keys[5]) != 0 {
+		t.Fatalf("Wrong groups")
 	}
-	for range masterGroup {
-		err := <-done
-		if err != nil {
-			t.Log(err)
-			t.Fail()
+
+	// the shares of each group add up to the same public keys
+	publicKeys := keys[0][config.MASTER_GROUP]
+	for gid, group := range groups {
+		groupKey := crypto.ZeroPoint()
+		xs := make([]mcl.Fr, 0)
+		shares := make([]mcl.Fr, 0)
+		for idx, sid := range group.Servers {
+			g := keys[sid][int32(gid)]
+			if g.Position != idx || !g.SamePublicKeys(publicKeys) {
+				t.Fatalf("Group %d does not agree on the public keys", gid)
+			}
+			var shareKey mcl.G2
+			mcl.G2Mul(&shareKey, &kyber_wrap.G2Generator, &g.TokenShare)
+			if !shareKey.IsEqual(&g.TokenShareKeys[idx]) {
+				t.Fatalf("Share key of %d does not match", sid)
+			}
+			groupKey.Accumulate(g.GroupShare.PublicKey())
+			// the last members of the group are enough
+			if idx >= len(group.Servers)-g.Threshold {
+				xs = append(xs, pairing.ShareIndex(idx))
+				shares = append(shares, g.TokenShare)
+			}
 		}
+		var tokenSecret mcl.Fr
+		var tokenKey mcl.G2
+		mcl.FrLagrangeInterpolation(&tokenSecret, xs, shares)
+		mcl.G2Mul(&tokenKey, &kyber_wrap.G2Generator, &tokenSecret)
+		if !tokenKey.IsEqual(&publicKeys.TokenPublicKey) || !groupKey.Equals(&publicKeys.GroupPublicKey) {
+			t.Fatalf("Shares of group %d do not match the public keys", gid)
+		}
+	}
+	if config.SkipToken && !publicKeys.TokenPublicKey.IsEqual(&token.SecretKey.X) {
+		t.Fatalf("Token key is not the fixed key")
 	}
-	// Check for no deadlock
-	for _, k := range otherGroup {
-		k.synchronizer.Sync(synchronization.Completed)
+
+	// keys are written to and read from files
+	g := keys[2][1]
+	b := make([]byte, g.Len())
+	g.PackTo(b)
+	loaded := &GroupKeys{}
+	err := loaded.InterpretFrom(b)
+	if err != nil || !loaded.SamePublicKeys(g) || !loaded.TokenShare.IsEqual(&g.TokenShare) || loaded.Position != g.Position {
+		t.Fatalf("Keys not read back")
 	}
-	// check that the other groups secret keys correspond to the same public keys as the master group
 }
@@ -1,165 +1,389 @@
 package keyExchange
 
 import (
-	"crypto/rand"
-	"math/big"
+	"log"
 	"sync"
 
 	"github.com/simonlangowski/lightning1/config"
-	"github.com/simonlangowski/lightning1/crypto/ec"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/commitments"
+	"github.com/simonlangowski/lightning1/crypto/pairing"
+	"github.com/simonlangowski/lightning1/crypto/pairing/kyber_wrap"
+	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
+	"github.com/simonlangowski/lightning1/crypto/token"
+	"github.com/simonlangowski/lightning1/errors"
 	"github.com/simonlangowski/lightning1/network"
 	"github.com/simonlangowski/lightning1/network/messages"
 	"github.com/simonlangowski/lightning1/network/synchronization"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+/*
+Distributed key generation for the anytrust groups
+
+Every group holds Shamir shares of the same token (BLS) key, so any config.TokenThreshold members can issue tokens,
+and additive shares of the same group (diffie hellman) key
+Each member of the master group is a dealer that contributes a random secret to both keys
+1. Each dealer sends a hash commitment to its public keys to the master group
+2. Once a dealer has the commitment of every dealer, it sends each member of every group
+   a share of its secrets, with Feldman commitments to the token polynomial and to all of the group key shares in that group
+3. Each member checks its shares against the Feldman commitments, and members of the master group
+   check that the commitments add up to the public keys the dealer committed to
+4. Each member adds up its shares from every dealer
+
+Since at least one dealer in the master group is honest, no one learns the secrets,
+and the commitments stop the last dealer from choosing its key based on the keys of the others
+A dealer that shows different public keys to different groups leaves them with different public keys,
+which the coordinator detects when it compares the public keys returned by each server
+*/
+
+// steps of the exchange, sent in the layer field
+const (
+	COMMIT_STEP = 0
+	SHARE_STEP  = 1
+	DONE_STEP   = 2
 )
 
 type KeyExchange struct {
-	myId        int
-	layerNumber int
-	master      bool
+	c        *common.CommonState
+	dealers  []int64 // the master group
+	position int     // my position in the master group, or -1
 
-	secretSigningKey *ec.ScalarElement
-	publicSigningKey *ec.Point
+	// my contribution if I am a dealer
+	tokenSecret mcl.Fr
+	groupSecret *crypto.DHPrivateKey
+	commitment  *commitments.Commitment
+	opening     *commitments.CommitmentOpening
 
-	synchronizer *synchronization.Synchronizer
-	mu           sync.Mutex
+	commitments []*commitments.Commitment // received from each dealer
+	commitSync  *synchronization.Synchronizer
+	groups      map[int32]*GroupKeys
+	failed      []int64 // dealers whose shares did not verify
+	mu          sync.Mutex
 }
 
-func NewMasterKeyExchange(myId, layerNumber, masterGroupSize int) *KeyExchange {
-	k := NewKeyExchange(myId, layerNumber, masterGroupSize)
-	k.master = true
-	// If master group, choose random secrets
-	k.secretSigningKey, _ = ec.RandomCurveScalar(rand.Reader)
-	return k
+// The keys held by a member of a group after the exchange
+type GroupKeys struct {
+	Group      int32
+	Position   int
+	Threshold  int // of the token key
+	TokenShare mcl.Fr
+	GroupShare crypto.DHPrivateKey
+
+	// public keys of each member's shares
+	TokenShareKeys []mcl.G2
+	GroupShareKeys []crypto.DHPublicKey
+
+	// shared by all groups
+	TokenPublicKey mcl.G2
+	GroupPublicKey crypto.DHPublicKey
+
+	sync *synchronization.Synchronizer
 }
 
-func NewKeyExchange(myID, layerNumber, masterGroupSize int) *KeyExchange {
-	k := &KeyExchange{
-		myId:        myID,
-		layerNumber: layerNumber,
-		master:      false,
-	}
-	k.secretSigningKey = ec.NewScalarElement(new(big.Int))
-	k.publicSigningKey = ec.ZeroPoint()
-	k.synchronizer = synchronization.NewSynchronizer(0, synchronization.InProgress, masterGroupSize, k)
+func NewKeyExchange(c *common.CommonState) *KeyExchange {
+	k := &KeyExchange{c: c}
+	k.reset()
 	return k
 }
 
-// call in master group only
-func (k *KeyExchange) StartKeyExchange(c *network.Caller) error {
-	if !k.master {
-		panic("Key exchange starts in master group")
+// start a new exchange with new secrets
+func (k *KeyExchange) reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.dealers = k.c.GroupConfigs.Groups[config.MASTER_GROUP].Servers
+	k.position = -1
+	for i, sid := range k.dealers {
+		if int(sid) == k.c.MyId {
+			k.position = i
+		}
 	}
-	done := make(chan error)
-	for i := range c.Groups {
-		go func(i int) {
-			if i == config.MASTER_GROUP {
-				// send shares of public key
-				done <- k.SendPublicKeyShares(c)
-			} else {
-				// send shares of secret key
-				done <- k.SendPrivateKeyShares(c, i)
+	k.commitments = make([]*commitments.Commitment, len(k.dealers))
+	k.commitSync = synchronization.NewSynchronizer(0, COMMIT_STEP, len(k.dealers), k)
+	k.groups = make(map[int32]*GroupKeys)
+	k.failed = make([]int64, 0)
+	for gid, group := range k.c.GroupConfigs.Groups {
+		for idx, sid := range group.Servers {
+			if int(sid) == k.c.MyId {
+				k.groups[int32(gid)] = k.newGroupKeys(int32(gid), idx, len(group.Servers))
 			}
-		}(i)
+		}
+	}
+	if k.position >= 0 {
+		k.chooseSecrets()
+	}
+}
+
+func (k *KeyExchange) newGroupKeys(gid int32, position, groupSize int) *GroupKeys {
+	g := &GroupKeys{
+		Group:          gid,
+		Position:       position,
+		Threshold:      config.TokenThreshold(groupSize),
+		GroupShare:     *crypto.ZeroScalar(),
+		TokenShareKeys: make([]mcl.G2, groupSize),
+		GroupShareKeys: make([]crypto.DHPublicKey, groupSize),
+	}
+	g.TokenShare.Clear()
+	for i := range g.TokenShareKeys {
+		g.TokenShareKeys[i].Clear()
+		g.GroupShareKeys[i] = *crypto.ZeroPoint()
 	}
-	for range c.Groups {
-		err := <-done
-		if err != nil {
-			return err
+	g.sync = synchronization.NewSynchronizer(0, SHARE_STEP, len(k.dealers), k)
+	return g
+}
+
+func (k *KeyExchange) chooseSecrets() {
+	if config.SkipToken {
+		// tokens are made with the fixed key, so it must be the combined key
+		if k.position == 0 {
+			log.Print("Warning: Using fixed token key is insecure")
+			k.tokenSecret = token.SecretKey.Share
+		} else {
+			k.tokenSecret.Clear()
 		}
+	} else {
+		k.tokenSecret.Random()
+	}
+	k.groupSecret = crypto.RandomCurveScalar()
+	var tokenKey mcl.G2
+	mcl.G2Mul(&tokenKey, &kyber_wrap.G2Generator, &k.tokenSecret)
+	var err error
+	err, k.commitment, k.opening = commitments.MakeCommitment(publicKeyBytes(&tokenKey, k.groupSecret.PublicKey()))
+	if err != nil {
+		panic(err)
 	}
-	k.synchronizer.Sync(synchronization.Completed)
-	return nil
 }
 
-func (k *KeyExchange) SendPublicKeyShares(c *network.Caller) error {
-	data := NewPublicKeysMessage()
-	data.SigningKey = *ec.ScalarBaseMult(k.secretSigningKey)
-	m := messages.NewSignedMessage(data.Len(), 0, k.layerNumber, k.myId, config.MASTER_GROUP, 0, 1, messages.NetworkMessage_KeySharePush)
-	data.PackTo(m.Data)
-	c.SendToGroup(config.MASTER_GROUP, m)
-	return nil
+// Run the exchange, dealing shares if this server is in the master group
+// returns the keys for each group this server is a member of
+func (k *KeyExchange) Run(c *network.Caller) (map[int32]*GroupKeys, error) {
+	err := k.Deal(c)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := k.Wait()
+	// all messages for this exchange have been received
+	k.reset()
+	return keys, err
 }
 
-func (k *KeyExchange) SendPrivateKeyShares(c *network.Caller, group int) error {
-	// I send one share to each member of a group
-	// At least one member is honest (and therefore non colluding) so my secret is not revealed
-	numShares := len(c.Groups[group])
-	signingShares := ec.AdditiveShares(k.secretSigningKey, numShares)
+// Send my commitment, then my shares once every dealer has committed
+func (k *KeyExchange) Deal(c *network.Caller) error {
+	if k.position < 0 {
+		return nil
+	}
+	commit := KeyCommitMessage{Commitment: *k.commitment}
+	m := messages.NewSignedMessage(commit.Len(), 0, COMMIT_STEP, k.c.MyId, config.MASTER_GROUP, 0, 1, messages.NetworkMessage_KeySharePush)
+	commit.PackTo(m.Data)
+	k.c.Sign(m)
+	_, err := c.SendToGroup(config.MASTER_GROUP, m)
+	if err != nil {
+		return err
+	}
+	k.commitSync.Sync(SHARE_STEP)
+
 	done := make(chan error)
-	for i, server := range c.Groups[group] {
-		go func(i int, server int) {
-			data := NewPrivateKeySharesMessage()
-			data.SecretSigningShare = *signingShares[i]
-			m := messages.NewSignedMessage(data.Len(), 0, k.layerNumber, k.myId, group, 0, 1, messages.NetworkMessage_KeySharePush)
+	for gid, group := range k.c.GroupConfigs.Groups {
+		go func(gid int, servers []int64) {
+			done <- k.sendShares(c, gid, servers)
+		}(int(gid), group.Servers)
+	}
+	for range k.c.GroupConfigs.Groups {
+		e := <-done
+		if e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (k *KeyExchange) sendShares(c *network.Caller, gid int, servers []int64) error {
+	// one share for each member of the group
+	// at least one member is honest (and therefore non colluding) so my group secret is not revealed
+	// fewer than the threshold of members cannot recover my token secret
+	tokenShares, coefficients := pairing.ShamirShares(&k.tokenSecret, len(servers), config.TokenThreshold(len(servers)))
+	groupShares := crypto.AdditiveShares(k.groupSecret, len(servers))
+	tokenCommitments := commitments.CommitTokenPolynomial(coefficients)
+	groupCommitments := commitments.CommitDHShares(groupShares)
+	done := make(chan error)
+	for i, sid := range servers {
+		go func(i int, sid int) {
+			data := KeyShareMessage{
+				Opening:          *k.opening,
+				TokenCommitments: *tokenCommitments,
+				GroupCommitments: *groupCommitments,
+				TokenShare:       tokenShares[i],
+				GroupShare:       *groupShares[i],
+			}
+			m := messages.NewSignedMessage(data.Len(), 0, SHARE_STEP, k.c.MyId, gid, sid, 1, messages.NetworkMessage_KeySharePush)
 			data.PackTo(m.Data)
-			_, err := c.SendSignedMessage(server, m)
+			k.c.Sign(m)
+			_, err := c.SendSignedMessage(sid, m)
 			done <- err
-		}(i, int(server))
+		}(i, int(sid))
 	}
-	for range c.Groups[group] {
-		err := <-done
-		if err != nil {
-			return err
+	var err error
+	for range servers {
+		e := <-done
+		if e != nil {
+			err = e
 		}
 	}
-	return nil
+	return err
 }
 
 func (k *KeyExchange) ReceiveKeyShare(m *messages.SignedMessage) error {
-	k.mu.Lock()
-	defer k.mu.Unlock()
-	k.synchronizer.Sync(synchronization.InProgress)
-	if k.master {
-		// add together public key shares
-		p := NewPublicKeysMessage()
-		err := p.InterpretFrom(m.Data)
-		if err != nil {
-			return err
-		}
-		k.publicSigningKey.Accumulate(&p.SigningKey)
-	} else {
-		// add together secret key shares
-		p := NewPrivateKeySharesMessage()
-		err := p.InterpretFrom(m.Data)
-		if err != nil {
-			return err
-		}
-		k.secretSigningKey.Accumulate(&p.SecretSigningShare)
+	dealer := k.dealerPosition(m.Sender)
+	if dealer < 0 {
+		return errors.BadMetadataError()
 	}
-	k.synchronizer.Done()
-	return nil
+	if !crypto.Verify(k.c.VerificationKeys[m.Sender], m.GetSignedData(), m.Signature) {
+		return errors.SignatureError()
+	}
+	switch m.Layer {
+	case COMMIT_STEP:
+		return k.receiveCommitment(dealer, m)
+	case SHARE_STEP:
+		return k.receiveShares(dealer, m)
+	}
+	return errors.BadMetadataError()
 }
 
-func (k *KeyExchange) OnThreshold(step int) (int, int) {
-	return 0, step + 1
+func (k *KeyExchange) dealerPosition(sid int) int {
+	for i, d := range k.dealers {
+		if int(d) == sid {
+			return i
+		}
+	}
+	return -1
 }
 
-/*
-func (k *KeyExchange) RetrieveKeys(c *network.Caller) error {
-	k.synchronizer.Sync(synchronization.Completed)
-	i, err := MakeInformationRequest(c)
+func (k *KeyExchange) receiveCommitment(dealer int, m *messages.SignedMessage) error {
+	if k.position < 0 {
+		return errors.WrongServerError()
+	}
+	err := k.commitSync.SyncOnce(COMMIT_STEP, dealer)
+	if err != nil {
+		return err
+	}
+	defer k.commitSync.Done(COMMIT_STEP, dealer)
+	commit := &KeyCommitMessage{}
+	err = commit.InterpretFrom(m.Data)
 	if err != nil {
+		// the shares from this dealer will not verify
 		return err
 	}
-	k.publicSigningKey = &i.SigningKeys[k.layerNumber]
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.commitments[dealer] = &commit.Commitment
 	return nil
 }
 
-func MakeInformationRequest(c *network.Caller) (*InformationRequest, error) {
-	// choose any random member of the master group to request the keys from
-	masterGroupServers := c.Groups[config.MASTER_GROUP].Servers
-	server := masterGroupServers[mrand.Intn(len(masterGroupServers))]
-	m := &messages.SignedMessage{
-		MessageType: messages.SignedMessage_InformationRequest,
+func (k *KeyExchange) receiveShares(dealer int, m *messages.SignedMessage) error {
+	k.mu.Lock()
+	g := k.groups[m.Group]
+	k.mu.Unlock()
+	if g == nil || m.Dest != k.c.MyId {
+		return errors.WrongServerError()
+	}
+	if k.position >= 0 {
+		// dealers reveal their keys only after every dealer has committed
+		k.commitSync.Sync(SHARE_STEP)
 	}
-	result, err := c.SendSignedMessage(int(server), m)
+	err := g.sync.SyncOnce(SHARE_STEP, dealer)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer g.sync.Done(SHARE_STEP, dealer)
+	s := &KeyShareMessage{}
+	err = s.InterpretFrom(m.Data)
+	if err != nil || !k.verifyShares(g, dealer, s) {
+		log.Printf("%d: shares from dealer %d for group %d did not verify", k.c.MyId, m.Sender, g.Group)
+		k.mu.Lock()
+		k.failed = append(k.failed, int64(m.Sender))
+		k.mu.Unlock()
+		return err
 	}
-	return NewInformationRequest(result.Data), nil
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	mcl.FrAdd(&g.TokenShare, &g.TokenShare, &s.TokenShare)
+	g.GroupShare.Accumulate(&s.GroupShare)
+	for i := range g.TokenShareKeys {
+		mcl.G2Add(&g.TokenShareKeys[i], &g.TokenShareKeys[i], s.TokenCommitments.ShareKey(i))
+		g.GroupShareKeys[i].Accumulate(&s.GroupCommitments.Shares[i])
+	}
+	return nil
 }
-*/
-func (k *KeyExchange) GetPublicKeys() *ec.Point {
-	k.synchronizer.Sync(synchronization.Completed)
-	return k.publicSigningKey
+
+func (k *KeyExchange) verifyShares(g *GroupKeys, dealer int, s *KeyShareMessage) bool {
+	if len(s.TokenCommitments.Coefficients) != g.Threshold || len(s.GroupCommitments.Shares) != len(g.GroupShareKeys) {
+		return false
+	}
+	if !s.TokenCommitments.VerifyShare(g.Position, &s.TokenShare) || !s.GroupCommitments.VerifyShare(g.Position, &s.GroupShare) {
+		return false
+	}
+	if k.position >= 0 {
+		// the shares add up to the keys the dealer committed to
+		k.mu.Lock()
+		c := k.commitments[dealer]
+		k.mu.Unlock()
+		if c == nil || !c.Open(&s.Opening, publicKeyBytes(s.TokenCommitments.PublicKey(), s.GroupCommitments.PublicKey())) {
+			return false
+		}
+	}
+	return true
+}
+
+// Wait for the shares from every dealer, and return the keys for each of my groups
+func (k *KeyExchange) Wait() (map[int32]*GroupKeys, error) {
+	k.mu.Lock()
+	groups := k.groups
+	k.mu.Unlock()
+	for _, g := range groups {
+		g.sync.Sync(DONE_STEP)
+	}
+	if k.position >= 0 {
+		k.commitSync.Sync(SHARE_STEP)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.failed) > 0 {
+		log.Printf("%d: key exchange failed, shares from dealers %v did not verify", k.c.MyId, k.failed)
+		return nil, errors.CommitFailure()
+	}
+	var first *GroupKeys
+	for _, g := range groups {
+		g.sumPublicKeys()
+		if first != nil && !first.SamePublicKeys(g) {
+			return nil, errors.GroupAgreementError()
+		}
+		first = g
+	}
+	return groups, nil
+}
+
+func (k *KeyExchange) OnThreshold(step int) (int, int) {
+	return len(k.dealers), step + 1
+}
+
+func (g *GroupKeys) sumPublicKeys() {
+	// the token key shares are on a polynomial, so any threshold of them interpolate the key
+	xs := make([]mcl.Fr, g.Threshold)
+	for i := range xs {
+		xs[i] = pairing.ShareIndex(i)
+	}
+	mcl.G2LagrangeInterpolation(&g.TokenPublicKey, xs, g.TokenShareKeys[:g.Threshold])
+	g.GroupPublicKey = *crypto.ZeroPoint()
+	for i := range g.GroupShareKeys {
+		g.GroupPublicKey.Accumulate(&g.GroupShareKeys[i])
+	}
+}
+
+func (g *GroupKeys) SamePublicKeys(o *GroupKeys) bool {
+	return g.TokenPublicKey.IsEqual(&o.TokenPublicKey) && g.GroupPublicKey.Equals(&o.GroupPublicKey)
+}
+
+// the key used to sign tokens for this group
+func (g *GroupKeys) SigningKey() *token.TokenSigningKey {
+	return token.NewTokenSigningKey(&g.TokenShare)
 }
@@ -2,109 +2,168 @@ package keyExchange
 
 import (
 	"encoding/binary"
-	"math/big"
 
-	"github.com/simonlangowski/lightning1/crypto/ec"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/commitments"
+	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
 	"github.com/simonlangowski/lightning1/errors"
 )
 
-func SigningCurveScalarAdd(dest *big.Int, src *big.Int) *big.Int {
-	dest = dest.Add(src, dest)
-	dest = dest.Mod(dest, ec.CURVE_MOD)
-	return dest
+// A dealer's hash commitment to its public keys
+type KeyCommitMessage struct {
+	Commitment commitments.Commitment
 }
 
-type PublicKeyMessage struct {
-	SigningKey ec.Point
+// A dealer's shares for one member of a group
+// The public keys of the dealer are the sums of the share commitments
+type KeyShareMessage struct {
+	Opening          commitments.CommitmentOpening
+	TokenCommitments commitments.TokenShareCommitments
+	GroupCommitments commitments.DHShareCommitments
+	TokenShare       mcl.Fr
+	GroupShare       crypto.DHPrivateKey
 }
 
-type PrivateKeyShareMessage struct {
-	SecretSigningShare ec.ScalarElement
+func (k *KeyCommitMessage) Len() int {
+	return k.Commitment.Len()
 }
 
-type InformationRequest struct {
-	Round       uint32
-	NumLayers   uint32
-	SigningKeys []ec.Point
+func (k *KeyCommitMessage) PackTo(b []byte) {
+	k.Commitment.PackTo(b)
 }
 
-func (p *PublicKeyMessage) Len() int {
-	return ec.CurveElementSize
+func (k *KeyCommitMessage) InterpretFrom(b []byte) error {
+	return k.Commitment.InterpretFrom(b)
 }
 
-func (p *PublicKeyMessage) PackTo(b []byte) {
-	if len(b) != p.Len() {
+func (k *KeyShareMessage) Len() int {
+	return 8 + commitments.OPENING_SIZE + k.TokenCommitments.Len() + k.GroupCommitments.Len() + mcl.FR_LEN + crypto.SCALAR_SIZE
+}
+
+// number of token coefficients and group shares, opening, commitments, then the shares for the recipient
+func (k *KeyShareMessage) PackTo(b []byte) {
+	if len(b) != k.Len() {
 		panic(errors.LengthInvalidError())
 	}
-	p.SigningKey.PackTo(b[:])
+	binary.LittleEndian.PutUint32(b[0:4], uint32(len(k.TokenCommitments.Coefficients)))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(len(k.GroupCommitments.Shares)))
+	pos := 8
+	k.Opening.PackTo(b[pos : pos+commitments.OPENING_SIZE])
+	pos += commitments.OPENING_SIZE
+	k.TokenCommitments.PackTo(b[pos : pos+k.TokenCommitments.Len()])
+	pos += k.TokenCommitments.Len()
+	k.GroupCommitments.PackTo(b[pos : pos+k.GroupCommitments.Len()])
+	pos += k.GroupCommitments.Len()
+	k.TokenShare.PackTo(b[pos : pos+mcl.FR_LEN])
+	pos += mcl.FR_LEN
+	copy(b[pos:], k.GroupShare.Bytes())
 }
 
-// first initialize arrays to correct size
-func (p *PublicKeyMessage) InterpretFrom(b []byte) error {
-	if len(b) != p.Len() {
+func (k *KeyShareMessage) InterpretFrom(b []byte) error {
+	if len(b) < 8 {
 		return errors.LengthInvalidError()
 	}
-	p.SigningKey.InterpretFrom(b[:])
-	return nil
-}
-
-func NewPublicKeysMessage() *PublicKeyMessage {
-	p := &PublicKeyMessage{}
-	p.SigningKey.X = new(big.Int)
-	p.SigningKey.Y = new(big.Int)
-	return p
-}
-
-func (p *PrivateKeyShareMessage) Len() int {
-	return ec.ScalarElementSize
-}
-
-func (p *PrivateKeyShareMessage) PackTo(b []byte) {
-	p.SecretSigningShare.PackTo(b[:])
+	numCoefficients := int(binary.LittleEndian.Uint32(b[0:4]))
+	numShares := int(binary.LittleEndian.Uint32(b[4:8]))
+	if len(b) != 8+commitments.OPENING_SIZE+numCoefficients*mcl.G2_LEN+numShares*crypto.POINT_SIZE+mcl.FR_LEN+crypto.SCALAR_SIZE {
+		return errors.LengthInvalidError()
+	}
+	pos := 8
+	err := k.Opening.InterpretFrom(b[pos : pos+commitments.OPENING_SIZE])
+	if err != nil {
+		return err
+	}
+	pos += commitments.OPENING_SIZE
+	err = k.TokenCommitments.InterpretFrom(b[pos : pos+numCoefficients*mcl.G2_LEN])
+	if err != nil {
+		return err
+	}
+	pos += numCoefficients * mcl.G2_LEN
+	err = k.GroupCommitments.InterpretFrom(b[pos : pos+numShares*crypto.POINT_SIZE])
+	if err != nil {
+		return err
+	}
+	pos += numShares * crypto.POINT_SIZE
+	err = k.TokenShare.InterpretFrom(b[pos : pos+mcl.FR_LEN])
+	if err != nil {
+		return err
+	}
+	pos += mcl.FR_LEN
+	return k.GroupShare.InterpretFrom(b[pos:])
 }
 
-func (p *PrivateKeyShareMessage) InterpretFrom(b []byte) error {
-	return p.SecretSigningShare.InterpretFrom(b[:])
+// the bytes of the public keys that the dealer commits to
+func publicKeyBytes(tokenKey *mcl.G2, groupKey *crypto.DHPublicKey) []byte {
+	b := make([]byte, mcl.G2_LEN+crypto.POINT_SIZE)
+	tokenKey.PackTo(b[:mcl.G2_LEN])
+	groupKey.PackTo(b[mcl.G2_LEN:])
+	return b
 }
 
-func NewPrivateKeySharesMessage() *PrivateKeyShareMessage {
-	p := &PrivateKeyShareMessage{}
-	p.SecretSigningShare = *ec.NewScalarElement(new(big.Int))
-	return p
+func (g *GroupKeys) Len() int {
+	return 4 + 4 + 4 + mcl.FR_LEN + crypto.SCALAR_SIZE + 4 + len(g.TokenShareKeys)*(mcl.G2_LEN+crypto.POINT_SIZE)
 }
 
-func NewInformationRequest(b []byte) *InformationRequest {
-	i := &InformationRequest{}
-	pos := 0
-	i.Round = binary.LittleEndian.Uint32(b[:4])
-	pos += 4
-	i.NumLayers = binary.LittleEndian.Uint32(b[pos : pos+4])
+// group, position, threshold, shares, then the public keys of each member's shares
+// the public keys of the group are computed from the share keys
+func (g *GroupKeys) PackTo(b []byte) {
+	if len(b) != g.Len() || len(g.TokenShareKeys) != len(g.GroupShareKeys) {
+		panic(errors.LengthInvalidError())
+	}
+	binary.LittleEndian.PutUint32(b[0:4], uint32(g.Group))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(g.Position))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(g.Threshold))
+	pos := 12
+	g.TokenShare.PackTo(b[pos : pos+mcl.FR_LEN])
+	pos += mcl.FR_LEN
+	pos += copy(b[pos:pos+crypto.SCALAR_SIZE], g.GroupShare.Bytes())
+	binary.LittleEndian.PutUint32(b[pos:pos+4], uint32(len(g.TokenShareKeys)))
 	pos += 4
-	i.SigningKeys = make([]ec.Point, i.NumLayers)
-	for j := range i.SigningKeys {
-		i.SigningKeys[j].X = new(big.Int)
-		i.SigningKeys[j].Y = new(big.Int)
-		i.SigningKeys[j].InterpretFrom(b[pos : pos+ec.CurveElementSize])
-		pos += ec.CurveElementSize
+	for i := range g.TokenShareKeys {
+		g.TokenShareKeys[i].PackTo(b[pos : pos+mcl.G2_LEN])
+		pos += mcl.G2_LEN
+		g.GroupShareKeys[i].PackTo(b[pos : pos+crypto.POINT_SIZE])
+		pos += crypto.POINT_SIZE
 	}
-	return i
 }
 
-func (i *InformationRequest) Len() int {
-	return 8 + len(i.SigningKeys)*ec.CurveElementSize
-}
-
-func (i *InformationRequest) PackTo(b []byte) {
-	if len(b) != i.Len() {
-		panic(errors.LengthInvalidError())
+func (g *GroupKeys) InterpretFrom(b []byte) error {
+	if len(b) < 16+mcl.FR_LEN+crypto.SCALAR_SIZE {
+		return errors.LengthInvalidError()
 	}
-	pos := 0
-	binary.LittleEndian.PutUint32(b[:4], i.Round)
-	pos += 4
-	binary.LittleEndian.PutUint32(b[pos:pos+4], i.NumLayers)
+	g.Group = int32(binary.LittleEndian.Uint32(b[0:4]))
+	g.Position = int(binary.LittleEndian.Uint32(b[4:8]))
+	g.Threshold = int(binary.LittleEndian.Uint32(b[8:12]))
+	pos := 12
+	err := g.TokenShare.InterpretFrom(b[pos : pos+mcl.FR_LEN])
+	if err != nil {
+		return err
+	}
+	pos += mcl.FR_LEN
+	err = g.GroupShare.InterpretFrom(b[pos : pos+crypto.SCALAR_SIZE])
+	if err != nil {
+		return err
+	}
+	pos += crypto.SCALAR_SIZE
+	numShares := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
 	pos += 4
-	for j := range i.SigningKeys {
-		i.SigningKeys[j].PackTo(b[pos : pos+ec.CurveElementSize])
-		pos += ec.CurveElementSize
+	if len(b) != pos+numShares*(mcl.G2_LEN+crypto.POINT_SIZE) || g.Threshold < 1 || g.Threshold > numShares {
+		return errors.LengthInvalidError()
+	}
+	g.TokenShareKeys = make([]mcl.G2, numShares)
+	g.GroupShareKeys = make([]crypto.DHPublicKey, numShares)
+	for i := range g.TokenShareKeys {
+		err = g.TokenShareKeys[i].InterpretFrom(b[pos : pos+mcl.G2_LEN])
+		if err != nil {
+			return err
+		}
+		pos += mcl.G2_LEN
+		err = g.GroupShareKeys[i].InterpretFrom(b[pos : pos+crypto.POINT_SIZE])
+		if err != nil {
+			return err
+		}
+		pos += crypto.POINT_SIZE
 	}
+	g.sumPublicKeys()
+	return nil
 }
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+// Checkpoint key tables and group keys to dir, and load any keys already there
+// so a restarted server can rejoin lightning rounds without new paths or a new key exchange
+func (s *Server) SetKeyDirectory(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	s.keyDirectory = dir
+	keys := make([]*processMessages.KeyLookupTable, 0)
+	for layer := 0; ; layer++ {
+		t := processMessages.NewKeyLookupTable(s.CommonState, layer)
+		err := t.LoadTableFromFile(s.keyFile(layer))
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return err
+		}
+		keys = append(keys, t)
+	}
+	if len(keys) > 0 {
+		s.Keys = keys
+	}
+	return s.loadGroupKeys()
+}
+
+func (s *Server) keyFile(layer int) string {
+	return filepath.Join(s.keyDirectory, fmt.Sprintf("layer%d.keys", layer))
+}
+
+func (s *Server) checkpointKeys(layer int) {
+	if s.keyDirectory == "" {
+		return
+	}
+	err := s.Keys[layer].WriteTableToFile(s.keyFile(layer))
+	if err != nil {
+		// the round can continue, but this server will not be able to recover these keys
+		log.Printf("%d: could not checkpoint keys for layer %d: %v", s.CommonState.MyId, layer, err)
+	}
+}
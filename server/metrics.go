@@ -0,0 +1,79 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/metrics"
+	"github.com/simonlangowski/lightning1/server/processMessages"
+)
+
+var (
+	messagesParsed    = metrics.NewCounter("lightning_messages_parsed_total", "Messages decrypted by this server", "layer")
+	messagesDropped   = metrics.NewCounter("lightning_messages_dropped_total", "Messages this server did not forward", "layer", "reason")
+	checkpointLatency = metrics.NewHistogram("lightning_checkpoint_seconds", "Time for the anytrust groups to decrypt the path messages of the last layer", metrics.LatencyBuckets)
+	roundGauge        = metrics.NewGauge("lightning_round", "Round this server is in")
+)
+
+// count the messages of a chunk that were parsed, and why each of the others was not
+func countParsed(layer int, keys []*processMessages.BootstrapKey, errs []error) {
+	parsed := 0
+	dropped := make(map[string]int)
+	for i, k := range keys {
+		if k != nil {
+			parsed++
+		} else {
+			dropped[reason(errs[i])]++
+		}
+	}
+	l := strconv.Itoa(layer)
+	messagesParsed.Add(float64(parsed), l)
+	for r, n := range dropped {
+		messagesDropped.Add(float64(n), l, r)
+	}
+}
+
+func countDropped(layer, n int, reason string) {
+	if n > 0 {
+		messagesDropped.Add(float64(n), strconv.Itoa(layer), reason)
+	}
+}
+
+// the reasons of the untyped errors returned while parsing, by their fixed text
+// (creating the errors here would log them)
+var parseReasons = map[string]string{
+	"Key not found":                "unknown_key",
+	"Duplicate message":            "duplicate",
+	"Unable to decrypt message":    "decryption",
+	"Token invalid":                "token",
+	"Message sent to wrong server": "wrong_server",
+	"Element is not on curve":      "bad_element",
+	"Message length invalid":       "length",
+	"Metadata does not match":      "metadata",
+}
+
+// the label of the error that stopped a message, one of a fixed set
+// the text of an error can name servers, rounds or peers, so it is never a label
+func reason(err error) string {
+	switch err.(type) {
+	case nil:
+		// the key was revoked, or its message was a duplicate
+		return "skipped"
+	case *errors.OverflowError:
+		return "overflow"
+	case *errors.TimeoutError:
+		return "timeout"
+	case *errors.LateError:
+		return "late"
+	case *errors.BadDecryptionError, *errors.BadPartialError:
+		return "decryption"
+	case *errors.RevokedError, *errors.PathRevokedError:
+		return "revoked"
+	case *errors.RejectedError:
+		return "rejected"
+	}
+	if r, ok := parseReasons[err.Error()]; ok {
+		return r
+	}
+	return "other"
+}
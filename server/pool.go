@@ -5,7 +5,7 @@ import (
 	"runtime"
 	"sync"
 
-	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/config"
 	"github.com/simonlangowski/lightning1/errors"
 	"github.com/simonlangowski/lightning1/network"
 	"github.com/simonlangowski/lightning1/network/messages"
@@ -16,18 +16,11 @@ type Job struct {
 	idx      int
 	m        *messages.Metadata
 	Message  []byte
+	Messages [][]byte // a chunk of messages, to batch verify signatures or tokens
 	Response []byte
 	wg       *sync.WaitGroup
 }
 
-// type SignatureJob struct {
-// 	Message   []byte
-// 	Signature crypto.Signature
-// 	Vk        crypto.VerificationKey
-// 	Evk       *crypto.ExpandedVerificationKey
-// 	done      chan bool
-// }
-
 type WorkPool struct {
 	jobs         chan Job
 	errorHandler func(error)
@@ -53,33 +46,41 @@ func (s *Server) WorkerPoolProcessStream(m *messages.Metadata, metadataBytes []b
 	h := sha512.New()
 	h.Write(metadataBytes)
 	err := s.checkMessage(m)
-	defer s.synchronizer.Done()
-	if err != nil {
+	if _, late := err.(*errors.TimeoutError); late {
+		// the sender was dropped from the layer, so its messages are no longer expected
+		for range stream.Buff {
+		}
+		return nil
+	} else if err != nil {
 		return err
 	}
+	defer s.synchronizer.Done(m.Layer, m.Sender)
+	batch := s.audit.NewBatch(metadataBytes)
+	// a batch whose stream fails is not recorded
+	defer batch.Close()
+	cover := network.NewCover(s.CommonState, m.Sender, m)
 	idx := 0
+	// messages are processed in chunks, so their signatures (or tokens for path messages) are checked together
+	chunk := make([][]byte, 0, config.BatchSize)
 	for message := range stream.Buff {
 		h.Write(message)
+		batch.Add(message)
 		idx++
-		// discard dummies?
-		allZero := true
-		// zero key does not appear
-		for i := 0; i < crypto.KEY_SIZE; i++ {
-			if message[i] != 0 {
-				allZero = false
-				break
-			}
+		// every message is checked, so dummies take the same time to recognise as real messages
+		if cover.IsDummy(message) {
+			continue
 		}
-		if !allZero {
+		chunk = append(chunk, message)
+		if len(chunk) == config.BatchSize {
 			wg.Add(1)
-			s.pool.jobs <- Job{
-				idx:     idx,
-				m:       m,
-				Message: message,
-				wg:      &wg,
-			}
+			s.pool.jobs <- Job{idx: idx, m: m, Messages: chunk, wg: &wg}
+			chunk = make([][]byte, 0, config.BatchSize)
 		}
 	}
+	if len(chunk) > 0 {
+		wg.Add(1)
+		s.pool.jobs <- Job{idx: idx, m: m, Messages: chunk, wg: &wg}
+	}
 	if stream.Err != nil {
 		return stream.Err
 	}
@@ -88,6 +89,11 @@ func (s *Server) WorkerPoolProcessStream(m *messages.Metadata, metadataBytes []b
 		errors.DebugPrint("Verifying %v %v %v", m, s.CommonState.VerificationKeys[m.Sender], stream.Signature)
 		return errors.SignatureError()
 	}
+	s.recordReceived(m.Layer, m.Sender, h.Sum(nil), stream.Signature)
+	err = batch.Commit(stream.Signature)
+	if err != nil {
+		return err
+	}
 	wg.Wait()
 	return nil
 }
@@ -105,13 +111,16 @@ func (s *Server) WorkerPoolProcessGroup(m *messages.Metadata, metadataBytes []by
 		if err != nil {
 			return err
 		}
-		defer group.checkpointSynchronizer.Done()
+		defer group.checkpointSynchronizer.Done(int(m.Layer), int(m.Sender))
 	} else if m.Type == messages.NetworkMessage_GroupCheckpointToken {
 		response = messages.NewSignedMessage(checkpoint.RESPONSE_LENGTH*int(m.NumMessages), s.CommonState.Round, s.CommonState.NumLayers, s.CommonState.MyId, int(m.Group), m.Sender, int(m.NumMessages), messages.NetworkMessage_GroupCheckpointToken)
 	}
+	batch := s.audit.NewBatch(metadataBytes)
+	defer batch.Close()
 	pos := 0
 	for message := range stream.Buff {
 		h.Write(message)
+		batch.Add(message)
 		wg.Add(1)
 		j := Job{
 			m:       m,
@@ -132,6 +141,10 @@ func (s *Server) WorkerPoolProcessGroup(m *messages.Metadata, metadataBytes []by
 		// errors.DebugPrint("Verifying %v %v %v", m, s.CommonState.VerificationKeys[m.Sender], stream.Signature)
 		return errors.SignatureError()
 	}
+	err := batch.Commit(stream.Signature)
+	if err != nil {
+		return err
+	}
 	wg.Wait()
 	if response != nil {
 		s.CommonState.Sign(response)
@@ -149,14 +162,26 @@ func (w *WorkPool) ProcessThread() {
 		// start := time.Now()
 		switch job.m.Type {
 		case messages.NetworkMessage_ServerMessageForward:
-			err = w.s.handleLightningMessage(metadata, stream)
+			if job.Messages != nil {
+				err = w.s.handleLightningMessages(metadata, job.Messages)
+			} else {
+				err = w.s.handleLightningMessage(metadata, stream)
+			}
 			// config.LogTime("Checked %v part %d in %v", metadata, job.idx, time.Since(start))
 			// boomerang back
 		case messages.NetworkMessage_ServerMessageReverse:
-			err = w.s.HandleBoomerangMessage(metadata, stream)
+			if job.Messages != nil {
+				err = w.s.HandleBoomerangMessages(metadata, job.Messages)
+			} else {
+				err = w.s.HandleBoomerangMessage(metadata, stream)
+			}
 			// path establishment forwards
 		case messages.NetworkMessage_PathMessageForward:
-			err = w.s.handlePathMessage(metadata, stream)
+			if job.Messages != nil {
+				err = w.s.handlePathMessages(metadata, job.Messages)
+			} else {
+				err = w.s.handlePathMessage(metadata, stream)
+			}
 
 			// Check Tokens
 		case messages.NetworkMessage_GroupCheckpointToken:
@@ -190,15 +215,3 @@ func (w *WorkPool) ProcessThread() {
 // 		}
 // 	}
 // }
-
-// func (w *WorkPool) SignatureCoProcessor(signatureJobs chan *SignatureJob) {
-// 	// check signatures in parallel with decryption
-// 	// TODO: batch verify when len(signatureJobs) is high (e.g we have a backup of things to verify)
-// 	for j := range signatureJobs {
-// 		if j.Evk != nil {
-// 			j.done <- crypto.VerifyExpanded(j.Evk, j.Message, j.Signature)
-// 		} else {
-// 			j.done <- crypto.Verify(j.Vk, j.Message, j.Signature)
-// 		}
-// 	}
-// }
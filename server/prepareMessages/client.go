@@ -3,7 +3,10 @@ package prepareMessages
 import (
 	"bytes"
 	"crypto/rand"
+	"log"
 
+	"github.com/simonlangowski/lightning1/bulletin"
+	board "github.com/simonlangowski/lightning1/bulletin/messages"
 	"github.com/simonlangowski/lightning1/config"
 	coord "github.com/simonlangowski/lightning1/coordinator/messages"
 	"github.com/simonlangowski/lightning1/crypto"
@@ -27,6 +30,10 @@ type Client struct {
 	routingKey               crypto.LookupKey
 	AnonymousVerificationKey crypto.VerificationKey
 	Receipts                 [][]byte
+	PostToBoard              bool   // also send submissions to the anytrust group to be posted
+	lastSubmission           []byte // signed submission, kept to check it was posted
+	envelope                 []byte // submitted path establishment envelope, kept to complain about its path
+	receiptEvidence          []byte // signed response to the last receipt check
 }
 
 type PathKey struct {
@@ -70,9 +77,12 @@ func (t *Client) SubmitPathEstablishmentMessage(c *network.Caller, message *comm
 	submission := messages.NewSignedMessage(message.Len(), t.Common.Round, 0, int(t.ID), t.group, dest, 1, messages.NetworkMessage_ClientMessageSubmission)
 	message.PackTo(submission.Data)
 	common.SignMessage(t.submissionKey, submission)
+	t.lastSubmission = bulletin.PackSubmission(submission)
+	t.envelope = message.Marshal()
 	_, err := c.SendSignedMessage(dest, submission)
-	// post to public bulletin board - send to anytrust group since message is signed
-	// _, err = c.SendToGroup(t.group, submission)
+	if err == nil && t.PostToBoard {
+		err = t.PostSubmission(c)
+	}
 	return err
 }
 
@@ -169,18 +179,51 @@ func (t *Client) GetToken(c *network.Caller, message []byte, layer int) (*token.
 	m := messages.NewSignedMessage(tr.Len(), t.Common.Round, layer, int(t.ID), t.group, 0, 1, messages.NetworkMessage_ClientTokenRequest)
 	tr.PackTo(m.Data)
 	common.SignMessage(t.submissionKey, m)
-	responses, err := c.SendToGroup(t.group, m)
-	if err != nil {
-		return nil, err
-	}
-	partialSignatures := make([]mcl.G1, len(responses))
-	for i := range partialSignatures {
-		err := partialSignatures[i].InterpretFrom(responses[i].Data)
-		if err != nil {
-			return nil, err
+	// finish once enough members of the group have responded, so offline members do not block tokens
+	threshold := config.TokenThreshold(len(c.Groups[t.group]))
+	partialSignatures := make([]mcl.G1, 0, threshold)
+	positions := make([]int, 0, threshold)
+	badServers := make([]int, 0)
+	for r := range c.SendToGroupAsync(t.group, m) {
+		if r.Err != nil || r.Response == nil {
+			continue
+		}
+		var partial mcl.G1
+		if partial.InterpretFrom(r.Response.Data) != nil {
+			badServers = append(badServers, c.Groups[t.group][r.Position])
+			continue
+		}
+		partialSignatures = append(partialSignatures, partial)
+		positions = append(positions, r.Position)
+		if len(partialSignatures) < threshold {
+			continue
 		}
+		token, ok := issuanceInfo.TryCreate(partialSignatures, positions)
+		if ok {
+			return token, nil
+		}
+		// check each partial with the public key of its share, and wait for other members to replace the bad ones
+		if t.Common.PublicGroupKeys == nil || t.Common.PublicGroupKeys[t.group] == nil {
+			continue
+		}
+		bad := issuanceInfo.BadPartials(partialSignatures, positions, t.Common.PublicGroupKeys[t.group])
+		for i := len(bad) - 1; i >= 0; i-- {
+			badServers = append(badServers, c.Groups[t.group][positions[bad[i]]])
+			partialSignatures = append(partialSignatures[:bad[i]], partialSignatures[bad[i]+1:]...)
+			positions = append(positions[:bad[i]], positions[bad[i]+1:]...)
+		}
+		if len(bad) > 0 && len(partialSignatures) >= threshold {
+			token, ok = issuanceInfo.TryCreate(partialSignatures, positions)
+			if ok {
+				log.Printf("Client %d: made token without bad partials from servers %v", t.ID, badServers)
+				return token, nil
+			}
+		}
+	}
+	if len(badServers) > 0 {
+		return nil, errors.BadPartial(badServers)
 	}
-	return issuanceInfo.Create(partialSignatures)
+	return issuanceInfo.Create(partialSignatures, positions)
 }
 
 func (t *Client) BoomerangBase(currentPath []*PathKey, round, boomerangLimit int) ([]byte, []byte) {
@@ -202,10 +245,13 @@ func (t *Client) BoomerangBase(currentPath []*PathKey, round, boomerangLimit int
 }
 
 // onion encrypt the message under the path keys.
+// each layer is signed with the key of the round, so the servers cannot link the rounds of a path
 func (t *Client) OnionEncrypt(message []byte, keys []*PathKey) []byte {
 	// onion encryption from last to first layer
 	for layer := len(keys) - 1; layer >= 0; layer-- {
-		message = t.Encrypt(message, keys[layer], t.Common.Round, layer, int(keys[layer].ServerID), false)
+		nonce := crypto.Nonce(t.Common.Round, layer, int(keys[layer].ServerID))
+		signingKey := keys[layer].SigningKey.Ratchet(crypto.RatchetScalar(keys[layer].Shared, t.Common.Round))
+		message = crypto.SignedSecretSeal(message, &nonce, keys[layer].Shared, signingKey)
 	}
 	return message
 }
@@ -226,47 +272,146 @@ func (t *Client) GetFinalMessage(numLayers int, message []byte) *common.FinalLig
 	finalMessage := &common.FinalLightningMessage{
 		Message: message,
 	}
-	finalMessage.Signature = crypto.SignData(t.PathKeys[numLayers].SigningKey, finalMessage.MarshalSigned())
+	finalMessage.Signature = t.anonymousSigningKey(numLayers).Sign(finalMessage.MarshalSigned())
 	return finalMessage
 }
 
+// The anonymous key of the round, ratcheted with the key shared with the last server
+func (t *Client) anonymousSigningKey(numLayers int) *crypto.RatchetedSigningKey {
+	last := t.PathKeys[numLayers-1].ServerID
+	shared := t.PathKeys[numLayers].Secret.SharedKey(&t.Common.ServerPublicKeys[last])
+	return t.PathKeys[numLayers].SigningKey.Ratchet(crypto.RatchetScalar(shared, t.Common.Round))
+}
+
 // Submit the message to the network in lightning round
 func (t *Client) SendLightningMessage(c *network.Caller, keys []*PathKey, message []byte) error {
 	finalMessage := t.GetFinalMessage(len(keys)-1, message)
 	submission := common.LightningEnvelope{
-		Key:              t.routingKey,
+		Key:              crypto.RatchetLookup(keys[0].Shared, t.routingKey, t.Common.Round),
 		SignedCiphertext: t.OnionEncrypt(finalMessage.MarshalI(), keys[:t.Common.NumLayers]),
 	}
 	submissionMessage := messages.NewSignedMessage(submission.Len(), t.Common.Round, 0, int(t.ID), t.group, 0, 1, messages.NetworkMessage_ClientMessageSubmission)
 	submission.PackTo(submissionMessage.Data)
 	common.SignMessage(t.submissionKey, submissionMessage)
-	// Send to the first server and public bulletin board?
-	_, err := c.SendSignedMessage(int(keys[0].ServerID), submissionMessage)
-	// _, err = c.SendToGroup(t.group, submissionMessage)
+	t.lastSubmission = bulletin.PackSubmission(submissionMessage)
+	resp, err := c.SendSignedMessage(int(keys[0].ServerID), submissionMessage)
+	if err == nil && t.isPathRevokedNotice(resp, keys[0].ServerID, &submission.Key) {
+		// the client establishes a new path in the next path establishment round
+		t.PathKeys = nil
+		return errors.PathRevoked()
+	}
+	if err == nil && t.PostToBoard {
+		err = t.PostSubmission(c)
+	}
 	return err
 }
 
+// A notice from the first server of the path that the path was revoked, for the lookup key of the submission
+func (t *Client) isPathRevokedNotice(resp *messages.SignedMessage, first int64, key *crypto.LookupKey) bool {
+	return resp != nil && resp.Type == messages.NetworkMessage_ClientPathRevoked &&
+		resp.Sender == int(first) && resp.Round == t.Common.Round &&
+		bytes.Equal(resp.Data, key[:]) && t.Common.Verify(resp)
+}
+
+// Send the last submission to the anytrust group, which posts it to the public bulletin board
+// since the submission is signed the group can check it came from this client
+func (t *Client) PostSubmission(c *network.Caller) error {
+	m := messages.NewSignedMessage(len(t.lastSubmission), t.Common.Round, 0, int(t.ID), t.group, 0, 1, messages.NetworkMessage_ClientBulletinPost)
+	copy(m.Data, t.lastSubmission)
+	// the submission inside is signed, the post is not
+	m.PackMetadata()
+	_, err := c.SendToGroup(t.group, m)
+	return err
+}
+
+// Check the last submission was posted to the board ending at the trusted head
+func (t *Client) CheckPosted(b bulletin.BulletinBoard, head *board.BoardHead) bool {
+	submission := bulletin.ParseSubmission(t.lastSubmission)
+	if submission == nil {
+		return false
+	}
+	index := bulletin.FindSubmission(b, 0, submission)
+	return index >= 0 && bulletin.VerifyInclusion(b, index, bulletin.SubmissionEntry(submission), head)
+}
+
 func (t *Client) CheckReceipt(c *network.Caller, round int) error {
 	req := NewClientRequest{}
 	req.ID = t.ID
 	req.VerificationKey = t.verificationKey
 	m := messages.NewSignedMessage(req.Len(), t.Common.Round, -1, int(t.ID), t.group, 0, 1, messages.NetworkMessage_ClientGetReceipt)
 	req.PackTo(m.Data)
-	m.GetSignedData()
+	m.PackMetadata()
 	// common.SignMessage(t.submissionKey, m)
+	t.receiptEvidence = nil
 	receipt, err := c.SendSignedMessage(int(t.PathKeys[0].ServerID), m)
 	if err != nil {
 		return err
 	}
-	if !t.Common.Verify(receipt) {
-		return errors.SignatureError()
+	if receipt.Dest != int(t.ID) || !t.Common.Verify(receipt) {
+		// a receipt the server did not sign for this client is as good as none
+		return errors.WrongReceipt()
 	}
+	t.receiptEvidence = bulletin.PackSubmission(receipt)
 	if !bytes.Equal(t.Receipts[round], receipt.Data) {
 		return errors.WrongReceipt()
 	}
 	return nil
 }
 
+// Check a receipt that stopped beyond the first layer, which is found in its bucket at one of the servers
+// the request does not identify the client, and every server is asked, so that the server holding the receipt is not revealed
+func (t *Client) CheckPrivateReceipt(c *network.Caller, round int) error {
+	// the bucket responses do not say whose receipt is missing
+	t.receiptEvidence = nil
+	receipt := t.Receipts[round]
+	req := ReceiptBucketRequest{Bucket: ReceiptBucket(receipt)}
+	found := false
+	for sid := 0; sid < t.Common.NumServers; sid++ {
+		if t.Common.IsDropped(sid) {
+			continue
+		}
+		// the sender is left out, since the request is anonymous
+		m := messages.NewSignedMessage(req.Len(), t.Common.Round, -1, -1, 0, sid, 1, messages.NetworkMessage_ClientGetReceiptBucket)
+		req.PackTo(m.Data)
+		// the request is not signed
+		m.PackMetadata()
+		bucket, err := c.SendSignedMessage(sid, m)
+		if err != nil {
+			return err
+		}
+		if !t.Common.Verify(bucket) {
+			return errors.WrongReceipt()
+		}
+		// keep asking after the receipt is found, since the servers could tell when the client stopped
+		found = found || InBucket(receipt, bucket.Data)
+	}
+	if !found {
+		return errors.WrongReceipt()
+	}
+	return nil
+}
+
+// Complain to the anytrust group that the receipt of a path establishment round was wrong or missing
+// the group traces the path and revokes it, so the client forgets it and establishes a new one
+func (t *Client) Complain(c *network.Caller, round int) error {
+	if t.envelope == nil || len(t.PathKeys) == 0 {
+		return errors.BadMetadataError()
+	}
+	complaint := Complaint{Envelope: t.envelope, Receipt: t.receiptEvidence}
+	// only the client has the first key of the path
+	complaint.Signature = t.PathKeys[0].SigningKey.Sign(complaint.SignedData(t.ID, t.group, round))
+	// the layer is the disputed round
+	m := messages.NewSignedMessage(complaint.Len(), t.Common.Round, round, int(t.ID), t.group, 0, 1, messages.NetworkMessage_ClientComplaint)
+	complaint.PackTo(m.Data)
+	common.SignMessage(t.submissionKey, m)
+	_, err := c.SendToGroup(t.group, m)
+	if err != nil {
+		return err
+	}
+	t.PathKeys = nil
+	return nil
+}
+
 // to skip path generation
 func (t *Client) SkipPathGen(c *network.Caller, info *coord.RoundInfo) error {
 	numLayers := int(info.NumLayers)
@@ -278,7 +423,7 @@ func (t *Client) SkipPathGen(c *network.Caller, info *coord.RoundInfo) error {
 	for i := 0; i < numLayers; i++ {
 		pk, sk := crypto.NewSigningKeyPair()
 		s, _ := sk.ToScalar()
-		nextServer := r.Intn(numServers)
+		nextServer := t.Common.Replacement(r.Intn(numServers))
 		t.PathKeys[i] = &PathKey{
 			Secret:     *s,
 			SigningKey: sk,
@@ -319,9 +464,13 @@ func (t *Client) SkipPathGen(c *network.Caller, info *coord.RoundInfo) error {
 			return err
 		}
 	}
+	// the last server registers the anonymous keys of later rounds
+	roundKey := t.anonymousSigningKey(numLayers).VerificationKey()
 	f := &messages.SkipPathGenMessage{
-		Group:      int32(group),
-		SendingKey: t.AnonymousVerificationKey.Bytes(),
+		Group:         int32(group),
+		SendingKey:    t.AnonymousVerificationKey.Bytes(),
+		SendingServer: int32(t.PathKeys[numLayers-1].ServerID),
+		ForwardKey:    roundKey.Bytes(),
 	}
 	return c.SkipPathGen(f, group, true)
 }
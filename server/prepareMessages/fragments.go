@@ -0,0 +1,189 @@
+package prepareMessages
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network"
+)
+
+// Messages longer than one slot are split into fragments, one for each of the user's slots
+// Each slot is a client with its own path, so the fragments cannot be linked to each other or the user by their paths
+// The fragments of a message share a random tag, chosen for each message so tags cannot be linked across messages
+// Every message is padded to fill all of the slots, so all users send the same number of slots of the same size
+
+const FRAGMENT_TAG_SIZE = 16
+const FRAGMENT_HEADER_SIZE = FRAGMENT_TAG_SIZE + 2 + 2 + 4
+
+type Fragment struct {
+	Tag   [FRAGMENT_TAG_SIZE]byte
+	Index int
+	Count int
+	// of the whole message, without padding
+	Length int
+	Data   []byte
+}
+
+func (f *Fragment) Len() int {
+	return FRAGMENT_HEADER_SIZE + len(f.Data)
+}
+
+func (f *Fragment) PackTo(b []byte) {
+	if len(b) != f.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	copy(b[:FRAGMENT_TAG_SIZE], f.Tag[:])
+	pos := FRAGMENT_TAG_SIZE
+	binary.LittleEndian.PutUint16(b[pos:pos+2], uint16(f.Index))
+	binary.LittleEndian.PutUint16(b[pos+2:pos+4], uint16(f.Count))
+	binary.LittleEndian.PutUint32(b[pos+4:pos+8], uint32(f.Length))
+	copy(b[FRAGMENT_HEADER_SIZE:], f.Data)
+}
+
+func (f *Fragment) InterpretFrom(b []byte) error {
+	if !f.interpret(b) {
+		return errors.LengthInvalidError()
+	}
+	return nil
+}
+
+// fragments come from anonymous clients, so malformed fragments are skipped without logging an error
+func (f *Fragment) interpret(b []byte) bool {
+	if len(b) < FRAGMENT_HEADER_SIZE {
+		return false
+	}
+	copy(f.Tag[:], b[:FRAGMENT_TAG_SIZE])
+	pos := FRAGMENT_TAG_SIZE
+	f.Index = int(binary.LittleEndian.Uint16(b[pos : pos+2]))
+	f.Count = int(binary.LittleEndian.Uint16(b[pos+2 : pos+4]))
+	f.Length = int(binary.LittleEndian.Uint32(b[pos+4 : pos+8]))
+	f.Data = b[FRAGMENT_HEADER_SIZE:]
+	return f.Count > 0 && f.Index < f.Count
+}
+
+func (f *Fragment) Marshal() []byte {
+	b := make([]byte, f.Len())
+	f.PackTo(b)
+	return b
+}
+
+// the most bytes of a message sent with numSlots slots of slotSize
+func FragmentCapacity(numSlots, slotSize int) int {
+	return numSlots * (slotSize - FRAGMENT_HEADER_SIZE)
+}
+
+// Split the payload into one fragment of slotSize for each slot
+func FragmentMessage(payload []byte, numSlots, slotSize int) ([][]byte, error) {
+	dataSize := slotSize - FRAGMENT_HEADER_SIZE
+	if numSlots <= 0 || numSlots > 0xffff || dataSize <= 0 || len(payload) > FragmentCapacity(numSlots, slotSize) {
+		return nil, errors.LengthInvalidError()
+	}
+	padded := make([]byte, FragmentCapacity(numSlots, slotSize))
+	copy(padded, payload)
+	f := Fragment{
+		Count:  numSlots,
+		Length: len(payload),
+	}
+	_, err := rand.Read(f.Tag[:])
+	if err != nil {
+		return nil, err
+	}
+	fragments := make([][]byte, numSlots)
+	for i := range fragments {
+		f.Index = i
+		f.Data = padded[i*dataSize : (i+1)*dataSize]
+		fragments[i] = f.Marshal()
+	}
+	return fragments, nil
+}
+
+// Combine the fragments of each message
+// A complete message becomes a single fragment, so that the outputs of several trustee groups can be reassembled again
+// Fragments whose message is incomplete are returned unchanged, and malformed fragments are dropped
+func Reassemble(fragments [][]byte) [][]byte {
+	byTag := make(map[[FRAGMENT_TAG_SIZE]byte][]*Fragment)
+	tags := make([][FRAGMENT_TAG_SIZE]byte, 0)
+	for _, b := range fragments {
+		f := &Fragment{}
+		if !f.interpret(b) {
+			continue
+		}
+		if byTag[f.Tag] == nil {
+			tags = append(tags, f.Tag)
+		}
+		byTag[f.Tag] = append(byTag[f.Tag], f)
+	}
+	output := make([][]byte, 0, len(tags))
+	for _, tag := range tags {
+		pieces := byIndex(byTag[tag])
+		whole := combine(pieces)
+		if whole != nil {
+			output = append(output, whole.Marshal())
+			continue
+		}
+		for _, f := range pieces {
+			if f != nil {
+				output = append(output, f.Marshal())
+			}
+		}
+	}
+	return output
+}
+
+// Every member of a group outputs the same fragments, so only the first fragment for each index is kept
+func byIndex(fragments []*Fragment) []*Fragment {
+	first := fragments[0]
+	pieces := make([]*Fragment, first.Count)
+	for _, f := range fragments {
+		if f.Count == first.Count && f.Length == first.Length && pieces[f.Index] == nil {
+			pieces[f.Index] = f
+		}
+	}
+	return pieces
+}
+
+// nil unless there is a fragment for each index
+func combine(pieces []*Fragment) *Fragment {
+	for _, f := range pieces {
+		if f == nil {
+			return nil
+		}
+	}
+	if len(pieces) == 1 {
+		return pieces[0]
+	}
+	whole := &Fragment{Tag: pieces[0].Tag, Count: 1, Length: pieces[0].Length}
+	for _, f := range pieces {
+		whole.Data = append(whole.Data, f.Data...)
+	}
+	return whole
+}
+
+// The payloads of the complete messages among reassembled fragments
+func CompleteMessages(fragments [][]byte) [][]byte {
+	payloads := make([][]byte, 0)
+	for _, b := range fragments {
+		f := &Fragment{}
+		if !f.interpret(b) || f.Count != 1 || f.Length > len(f.Data) {
+			continue
+		}
+		payloads = append(payloads, f.Data[:f.Length])
+	}
+	return payloads
+}
+
+// Send a payload longer than one slot, with one fragment through the path of each slot
+func SendFragmentedMessage(c *network.Caller, slots []*Client, payload []byte, slotSize int) error {
+	fragments, err := FragmentMessage(payload, len(slots), slotSize)
+	if err != nil {
+		return err
+	}
+	for i, slot := range slots {
+		err = slot.SendLightningMessage(c, slot.PathKeys, fragments[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
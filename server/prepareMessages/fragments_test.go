@@ -0,0 +1,62 @@
+package prepareMessages
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestFragments(t *testing.T) {
+	numSlots := 4
+	slotSize := 64
+	payloads := [][]byte{
+		make([]byte, 1),
+		make([]byte, slotSize),
+		make([]byte, FragmentCapacity(numSlots, slotSize)),
+	}
+	all := make([][]byte, 0)
+	for _, p := range payloads {
+		rand.Read(p)
+		fragments, err := FragmentMessage(p, numSlots, slotSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every message uses every slot, whatever its length
+		if len(fragments) != numSlots {
+			t.Fatalf("Wrong number of fragments")
+		}
+		for _, f := range fragments {
+			if len(f) != slotSize {
+				t.Fatalf("Fragment not padded to the slot size")
+			}
+		}
+		all = append(all, fragments...)
+	}
+	rand.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+
+	// split between two groups, which can each only complete some of the messages
+	first := Reassemble(all[:len(all)/2])
+	second := Reassemble(all[len(all)/2:])
+	if len(CompleteMessages(append(first, second...))) == len(payloads) {
+		t.Fatalf("Messages completed without all fragments")
+	}
+	// combining the outputs of the groups completes every message, even with each group's output repeated by its members
+	combined := append(append(first, second...), first...)
+	messages := CompleteMessages(Reassemble(combined))
+	if len(messages) != len(payloads) {
+		t.Fatalf("Reassembled %d messages, expected %d", len(messages), len(payloads))
+	}
+	for _, p := range payloads {
+		found := false
+		for _, m := range messages {
+			found = found || bytes.Equal(m, p)
+		}
+		if !found {
+			t.Fatalf("Message not reassembled")
+		}
+	}
+	// malformed fragments are dropped
+	if len(Reassemble([][]byte{make([]byte, FRAGMENT_HEADER_SIZE-1), make([]byte, slotSize)})) != 0 {
+		t.Fatalf("Malformed fragment kept")
+	}
+}
@@ -31,7 +31,7 @@ func (t *NewClientRequest) PackTo(b []byte) {
 }
 func (t *NewClientRequest) InterpretFrom(b []byte) error {
 	if len(b) != t.Len() {
-		return errors.LengthInvalidError()
+		return errors.Rejected("length")
 	}
 	t.ID = int64(binary.LittleEndian.Uint64(b[:8]))
 	return t.VerificationKey.InterpretFrom(b[8:])
@@ -49,7 +49,7 @@ func (t *TokenRequest) PackTo(b []byte) {
 }
 func (t *TokenRequest) InterpretFrom(b []byte) error {
 	if len(b) != t.Len() {
-		return errors.LengthInvalidError()
+		return errors.Rejected("length")
 	}
 	t.ID = int64(binary.LittleEndian.Uint64(b[:8]))
 	return t.TokenRequest.InterpretFrom(b[8:])
@@ -81,7 +81,7 @@ func (c *Client) Marshal() *MarshallableClient {
 }
 
 func (m *MarshallableClient) Unmarshal(c *common.CommonState) *Client {
-	return &Client{
+	cli := &Client{
 		ID:                       m.ID,
 		submissionKey:            m.SubmissionKey,
 		verificationKey:          m.VerificationKey,
@@ -94,4 +94,10 @@ func (m *MarshallableClient) Unmarshal(c *common.CommonState) *Client {
 		CombinedKey:    c.CombinedKey,
 		GroupPublicKey: c.GroupPublicKey,
 	}
+	// lightning messages are routed under the key of the first layer
+	if len(m.PathKeys) > 0 {
+		vk := m.PathKeys[0].SigningKey.VerificationKey()
+		cli.routingKey = vk.LookupKey()
+	}
+	return cli
 }
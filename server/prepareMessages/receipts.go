@@ -0,0 +1,140 @@
+package prepareMessages
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+// Receipts that stop at a layer beyond the first cannot be fetched by the client from the server holding them
+// without revealing that server is on its path, so every server publishes the receipts it holds in buckets,
+// and the client fetches the bucket of its receipt from every server
+// the bucket is chosen by a hash of the receipt, which is random, so it says nothing about the client
+type ReceiptBuckets struct {
+	mu      sync.Mutex
+	buckets map[uint64][]byte // concatenated receipts
+}
+
+type ReceiptBucketRequest struct {
+	Bucket uint64
+}
+
+// Sent by a client to its anytrust group when the receipt of a path establishment round is wrong or missing
+type Complaint struct {
+	Envelope []byte // the path establishment envelope the client submitted
+	// under the envelope's InKey, which only the client can sign with, over the client, its group and the disputed round
+	// so that the servers on the path can check the client disputed it before revealing their decryptions
+	Signature crypto.Signature
+	Receipt   []byte // the first server's signed response to the receipt check, or empty if there was none
+}
+
+func NewReceiptBuckets() *ReceiptBuckets {
+	return &ReceiptBuckets{buckets: make(map[uint64][]byte)}
+}
+
+func ReceiptBucket(receipt []byte) uint64 {
+	h := sha256.Sum256(receipt)
+	return binary.LittleEndian.Uint64(h[:8]) % config.ReceiptBuckets
+}
+
+func (r *ReceiptBuckets) Add(receipt []byte) {
+	b := ReceiptBucket(receipt)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buckets[b] = append(r.buckets[b], receipt...)
+}
+
+// the receipts in the bucket, which all have the length of the receipts of the round
+func (r *ReceiptBuckets) Get(bucket uint64) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buckets[bucket]
+}
+
+// whether the receipt is one of the receipts of a bucket
+func InBucket(receipt, bucket []byte) bool {
+	if len(receipt) == 0 {
+		return false
+	}
+	for pos := 0; pos+len(receipt) <= len(bucket); pos += len(receipt) {
+		if bytes.Equal(bucket[pos:pos+len(receipt)], receipt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ReceiptBucketRequest) Len() int {
+	return 8
+}
+
+// a buffer of the wrong length is filled as far as it goes, rather than panicking
+func (r *ReceiptBucketRequest) PackTo(b []byte) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], r.Bucket)
+	copy(b, buf[:])
+}
+
+func (r *ReceiptBucketRequest) InterpretFrom(b []byte) error {
+	if len(b) != r.Len() {
+		return errors.Rejected("length")
+	}
+	r.Bucket = binary.LittleEndian.Uint64(b)
+	if r.Bucket >= config.ReceiptBuckets {
+		return errors.Rejected("bucket")
+	}
+	return nil
+}
+
+func (c *Complaint) Len() int {
+	return 4 + len(c.Envelope) + crypto.SIGNATURE_SIZE + len(c.Receipt)
+}
+
+// a buffer of the wrong length is filled as far as it goes, rather than panicking
+func (c *Complaint) PackTo(b []byte) {
+	copy(b, c.Marshal())
+}
+
+func (c *Complaint) Marshal() []byte {
+	b := make([]byte, c.Len())
+	binary.LittleEndian.PutUint32(b, uint32(len(c.Envelope)))
+	pos := 4
+	pos += copy(b[pos:], c.Envelope)
+	// a short signature is padded, so the receipt stays at its offset
+	copy(b[pos:pos+crypto.SIGNATURE_SIZE], c.Signature)
+	pos += crypto.SIGNATURE_SIZE
+	copy(b[pos:], c.Receipt)
+	return b
+}
+
+func (c *Complaint) InterpretFrom(b []byte) error {
+	if len(b) < 4 {
+		return errors.Rejected("length")
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	if n < 0 || n > len(b)-4-crypto.SIGNATURE_SIZE {
+		return errors.Rejected("length")
+	}
+	pos := 4
+	c.Envelope = b[pos : pos+n]
+	pos += n
+	c.Signature = b[pos : pos+crypto.SIGNATURE_SIZE]
+	pos += crypto.SIGNATURE_SIZE
+	c.Receipt = b[pos:]
+	return nil
+}
+
+// the data the client signs under the envelope's InKey
+func (c *Complaint) SignedData(client int64, group, round int) []byte {
+	b := make([]byte, 16+len(c.Envelope))
+	binary.LittleEndian.PutUint64(b[0:8], uint64(client))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(group))
+	binary.LittleEndian.PutUint32(b[12:16], uint32(round))
+	copy(b[16:], c.Envelope)
+	return b
+}
@@ -0,0 +1,61 @@
+package prepareMessages
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+func TestReceiptBuckets(t *testing.T) {
+	buckets := NewReceiptBuckets()
+	receipts := make([][]byte, 100)
+	for i := range receipts {
+		receipts[i] = make([]byte, 8)
+		rand.Read(receipts[i])
+		buckets.Add(receipts[i])
+	}
+	for _, r := range receipts {
+		if !InBucket(r, buckets.Get(ReceiptBucket(r))) {
+			t.Fatalf("Receipt not in its bucket")
+		}
+	}
+	missing := make([]byte, 8)
+	rand.Read(missing)
+	if InBucket(missing, buckets.Get(ReceiptBucket(missing))) {
+		t.Fatalf("Found a receipt that was not added")
+	}
+	// receipts are only matched at their own offsets
+	if InBucket(receipts[0][1:], buckets.Get(ReceiptBucket(receipts[0]))) {
+		t.Fatalf("Matched part of a receipt")
+	}
+
+	req := ReceiptBucketRequest{Bucket: ReceiptBucket(receipts[0])}
+	b := make([]byte, req.Len())
+	req.PackTo(b)
+	parsed := ReceiptBucketRequest{}
+	if parsed.InterpretFrom(b) != nil || parsed.Bucket != req.Bucket {
+		t.Fatalf("Request did not survive marshalling")
+	}
+	// requests from clients are rejected rather than trusted
+	req.Bucket = config.ReceiptBuckets
+	req.PackTo(b)
+	if _, ok := parsed.InterpretFrom(b).(*errors.RejectedError); !ok {
+		t.Fatalf("Out of range bucket not rejected")
+	}
+	if _, ok := parsed.InterpretFrom(b[:4]).(*errors.RejectedError); !ok {
+		t.Fatalf("Short request not rejected")
+	}
+	// packing into a buffer of the wrong length does not panic
+	req.PackTo(b[:4])
+	complaint := Complaint{Envelope: receipts[0], Signature: receipts[1], Receipt: receipts[2]}
+	complaint.PackTo(make([]byte, 3))
+	parsedComplaint := Complaint{}
+	if parsedComplaint.InterpretFrom(complaint.Marshal()) != nil || string(parsedComplaint.Receipt) != string(receipts[2]) {
+		t.Fatalf("Complaint did not survive marshalling")
+	}
+	if _, ok := parsedComplaint.InterpretFrom(complaint.Marshal()[:10]).(*errors.RejectedError); !ok {
+		t.Fatalf("Short complaint not rejected")
+	}
+}
@@ -5,17 +5,21 @@ import (
 
 	"github.com/simonlangowski/lightning1/crypto"
 	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/metrics"
 	"github.com/simonlangowski/lightning1/network/messages"
 	"github.com/simonlangowski/lightning1/server/common"
 
 	"github.com/simonlangowski/lightning1/crypto/token"
 )
 
+var tokensIssued = metrics.NewCounter("lightning_tokens_issued_total", "Partial tokens signed for clients")
+
 type MessagePreparer struct {
 	common   *common.CommonState
 	mapLock  sync.RWMutex
 	markLock sync.Mutex               // could have a lock in each PerClientInfo
 	Clients  map[int64]*PerClientInfo // map clientID -> info
+	revoked  map[int64]bool           // clients that may not register again
 	signer   *token.TokenSigningKey
 	group    int
 }
@@ -30,6 +34,7 @@ func NewMessagePreparer(c *common.CommonState, signer *token.TokenSigningKey, gr
 	return &MessagePreparer{
 		common:  c,
 		Clients: make(map[int64]*PerClientInfo),
+		revoked: make(map[int64]bool),
 		signer:  signer,
 		group:   group,
 	}
@@ -43,36 +48,61 @@ func (p *MessagePreparer) RegisterClient(m *messages.SignedMessage) error {
 	}
 	// check that the user owns this signature since they signed the signature
 	if !common.ValidateSignature(n.VerificationKey, m) {
-		return errors.SignatureError()
+		return errors.Rejected("signature")
 	}
 	p.mapLock.Lock()
 	defer p.mapLock.Unlock()
+	if p.revoked[n.ID] {
+		return errors.ClientRevokedError()
+	}
 	if p.Clients[n.ID] != nil {
-		return errors.Duplicate()
+		// a client may retry its registration
+		return errors.Rejected("already registered")
 	}
 	p.Clients[n.ID] = &PerClientInfo{SignatureKey: n.VerificationKey, signed: -1, submitted: false}
 	return nil
 }
 
 func (p *MessagePreparer) MarkSubmitted(ID int64, m *messages.SignedMessage) error {
+	p.mapLock.RLock()
 	info := p.Clients[ID]
+	p.mapLock.RUnlock()
 	if info == nil {
-		return errors.ClientNotFoundError()
+		return errors.Rejected("unknown client")
 	}
 	if !common.ValidateSignature(info.SignatureKey, m) {
-		return errors.SignatureError()
+		return errors.Rejected("signature")
 	}
 	p.markLock.Lock()
 	defer p.markLock.Unlock()
 	if info.submitted {
-		return errors.Duplicate()
+		return errors.Rejected("duplicate submission")
 	}
 	info.submitted = true
 	return nil
 }
 
+// check a message was signed by a registered client
+func (p *MessagePreparer) VerifyClient(ID int64, m *messages.SignedMessage) error {
+	p.mapLock.RLock()
+	info := p.Clients[ID]
+	p.mapLock.RUnlock()
+	if info == nil {
+		return errors.Rejected("unknown client")
+	}
+	if !common.ValidateSignature(info.SignatureKey, m) {
+		return errors.Rejected("signature")
+	}
+	return nil
+}
+
+// the client gets no more tokens, and cannot register again
+// its established paths are revoked separately, by the servers on them
 func (p *MessagePreparer) RevokeClient(ID int64) {
-	p.Clients[ID] = nil
+	p.mapLock.Lock()
+	defer p.mapLock.Unlock()
+	delete(p.Clients, ID)
+	p.revoked[ID] = true
 }
 
 func (p *MessagePreparer) HandleTokenRequest(m *messages.SignedMessage) (*messages.SignedMessage, error) {
@@ -108,6 +138,7 @@ func (p *MessagePreparer) HandleTokenRequest(m *messages.SignedMessage) (*messag
 	} else {
 		info.signed = m.Layer
 	}
+	tokensIssued.Inc()
 	return response, nil
 }
 
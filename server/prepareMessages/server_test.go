@@ -0,0 +1,38 @@
+package prepareMessages
+
+import (
+	"testing"
+
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+func TestRegisterRevoked(t *testing.T) {
+	c := &common.CommonState{}
+	cli, err := NewClient(c, 42, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	register := func() *messages.SignedMessage {
+		req := NewClientRequest{ID: cli.ID, VerificationKey: cli.verificationKey}
+		m := messages.NewSignedMessage(req.Len(), 0, -1, int(cli.ID), 0, 0, 1, messages.NetworkMessage_ClientRegister)
+		req.PackTo(m.Data)
+		common.SignMessage(cli.submissionKey, m)
+		return m
+	}
+	p := NewMessagePreparer(c, nil, 0)
+	if err := p.RegisterClient(register()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.RegisterClient(register()).(*errors.RejectedError); !ok {
+		t.Fatalf("Registered twice")
+	}
+	p.RevokeClient(cli.ID)
+	// a revoked client retrying must not block the server
+	for i := 0; i < 3; i++ {
+		if _, ok := p.RegisterClient(register()).(*errors.RevokedError); !ok {
+			t.Fatalf("Revoked client registered again")
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package prepareMessages
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+// The secret state a client needs to resume after a restart without registering again
+// It is kept encrypted under a passphrase (unlike MarshallableClient, which is for test records)
+// The secrets of the path keys and the public keys are derived from the signing keys, so are not stored
+type ClientState struct {
+	ID            int64
+	Group         int
+	Round         int // the last round the client took part in
+	SubmissionKey crypto.SigningKey
+	PathKeys      []*PathKey
+	Receipts      [][]byte
+}
+
+const CLIENT_STATE_VERSION = 1
+const clientStateHeaderSize = 4 + 8 + 4 + 8 + crypto.SIGNING_KEY_SIZE + 4
+const pathKeyHeaderSize = crypto.SIGNING_KEY_SIZE + 8 + 8
+
+func (t *Client) State() *ClientState {
+	return &ClientState{
+		ID:            t.ID,
+		Group:         t.group,
+		Round:         t.Common.Round,
+		SubmissionKey: t.submissionKey,
+		PathKeys:      t.PathKeys,
+		Receipts:      t.Receipts,
+	}
+}
+
+// The client with this state, and the public values of c
+func (s *ClientState) Client(c *common.CommonState) (*Client, error) {
+	t := &Client{
+		ID:              s.ID,
+		submissionKey:   s.SubmissionKey,
+		verificationKey: s.SubmissionKey.VerificationKey(),
+		group:           s.Group,
+		PathKeys:        s.PathKeys,
+		Receipts:        s.Receipts,
+
+		Common:         c,
+		CombinedKey:    c.CombinedKey,
+		GroupPublicKey: c.GroupPublicKey,
+	}
+	for _, k := range s.PathKeys {
+		secret, err := k.SigningKey.ToScalar()
+		if err != nil {
+			return nil, err
+		}
+		k.Secret = *secret
+	}
+	if len(s.PathKeys) > 0 {
+		vk := s.PathKeys[0].SigningKey.VerificationKey()
+		t.routingKey = vk.LookupKey()
+		// the last key is the anonymous key of the path
+		t.AnonymousVerificationKey = s.PathKeys[len(s.PathKeys)-1].SigningKey.VerificationKey()
+	}
+	return t, nil
+}
+
+func (s *ClientState) Len() int {
+	l := clientStateHeaderSize
+	for _, k := range s.PathKeys {
+		l += pathKeyHeaderSize + 4 + len(k.Shared) + 4 + len(k.PrevShared)
+	}
+	l += 4
+	for _, r := range s.Receipts {
+		l += 4 + len(r)
+	}
+	return l
+}
+
+// version, id, group, round, submission key, path keys, receipts
+func (s *ClientState) PackTo(b []byte) {
+	if len(b) != s.Len() {
+		panic(errors.LengthInvalidError())
+	}
+	binary.LittleEndian.PutUint32(b[:4], CLIENT_STATE_VERSION)
+	binary.LittleEndian.PutUint64(b[4:12], uint64(s.ID))
+	binary.LittleEndian.PutUint32(b[12:16], uint32(s.Group))
+	binary.LittleEndian.PutUint64(b[16:24], uint64(s.Round))
+	copy(b[24:24+crypto.SIGNING_KEY_SIZE], s.SubmissionKey)
+	pos := 24 + crypto.SIGNING_KEY_SIZE
+	binary.LittleEndian.PutUint32(b[pos:pos+4], uint32(len(s.PathKeys)))
+	pos += 4
+	for _, k := range s.PathKeys {
+		copy(b[pos:pos+crypto.SIGNING_KEY_SIZE], k.SigningKey)
+		pos += crypto.SIGNING_KEY_SIZE
+		binary.LittleEndian.PutUint64(b[pos:pos+8], uint64(k.ServerID))
+		binary.LittleEndian.PutUint64(b[pos+8:pos+16], uint64(k.PrevServerID))
+		pos += 16
+		pos = putBytes(b, pos, k.Shared)
+		pos = putBytes(b, pos, k.PrevShared)
+	}
+	binary.LittleEndian.PutUint32(b[pos:pos+4], uint32(len(s.Receipts)))
+	pos += 4
+	for _, r := range s.Receipts {
+		pos = putBytes(b, pos, r)
+	}
+}
+
+func (s *ClientState) InterpretFrom(b []byte) error {
+	if len(b) < clientStateHeaderSize {
+		return errors.LengthInvalidError()
+	}
+	if binary.LittleEndian.Uint32(b[:4]) != CLIENT_STATE_VERSION {
+		return errors.VersionError()
+	}
+	s.ID = int64(binary.LittleEndian.Uint64(b[4:12]))
+	s.Group = int(binary.LittleEndian.Uint32(b[12:16]))
+	s.Round = int(binary.LittleEndian.Uint64(b[16:24]))
+	s.SubmissionKey = crypto.SigningKey(append([]byte{}, b[24:24+crypto.SIGNING_KEY_SIZE]...))
+	pos := 24 + crypto.SIGNING_KEY_SIZE
+	numKeys := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+	pos += 4
+	s.PathKeys = make([]*PathKey, 0, numKeys)
+	for i := 0; i < numKeys; i++ {
+		if pos+pathKeyHeaderSize > len(b) {
+			return errors.LengthInvalidError()
+		}
+		k := &PathKey{}
+		k.SigningKey = crypto.SigningKey(append([]byte{}, b[pos:pos+crypto.SIGNING_KEY_SIZE]...))
+		pos += crypto.SIGNING_KEY_SIZE
+		k.ServerID = int64(binary.LittleEndian.Uint64(b[pos : pos+8]))
+		k.PrevServerID = int64(binary.LittleEndian.Uint64(b[pos+8 : pos+16]))
+		pos += 16
+		var shared, prevShared []byte
+		shared, pos = getBytes(b, pos)
+		prevShared, pos = getBytes(b, pos)
+		if pos < 0 {
+			return errors.LengthInvalidError()
+		}
+		k.Shared = shared
+		k.PrevShared = prevShared
+		s.PathKeys = append(s.PathKeys, k)
+	}
+	if pos+4 > len(b) {
+		return errors.LengthInvalidError()
+	}
+	numReceipts := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+	pos += 4
+	s.Receipts = make([][]byte, 0, numReceipts)
+	for i := 0; i < numReceipts; i++ {
+		var r []byte
+		r, pos = getBytes(b, pos)
+		if pos < 0 {
+			return errors.LengthInvalidError()
+		}
+		s.Receipts = append(s.Receipts, r)
+	}
+	if pos != len(b) {
+		return errors.LengthInvalidError()
+	}
+	return nil
+}
+
+func (s *ClientState) Marshal() []byte {
+	b := make([]byte, s.Len())
+	s.PackTo(b)
+	return b
+}
+
+// length prefixed bytes, returning the next position
+func putBytes(b []byte, pos int, data []byte) int {
+	binary.LittleEndian.PutUint32(b[pos:pos+4], uint32(len(data)))
+	copy(b[pos+4:], data)
+	return pos + 4 + len(data)
+}
+
+// returns a negative position if b is too short, or if pos already is
+func getBytes(b []byte, pos int) ([]byte, int) {
+	if pos < 0 || pos+4 > len(b) {
+		return nil, -1
+	}
+	l := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+	pos += 4
+	if l > len(b)-pos {
+		return nil, -1
+	}
+	if l == 0 {
+		return nil, pos
+	}
+	return append([]byte{}, b[pos:pos+l]...), pos + l
+}
+
+// Encrypt the state under the passphrase,
+// and write to a temporary file and rename so a crash never leaves a partial state
+func WriteClientState(fn string, passphrase []byte, s *ClientState) error {
+	box, err := crypto.PassphraseSeal(s.Marshal(), passphrase)
+	if err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(box)
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+func ReadClientState(fn string, passphrase []byte) (*ClientState, error) {
+	box, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	b, err := crypto.PassphraseOpen(box, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	s := &ClientState{}
+	err = s.InterpretFrom(b)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
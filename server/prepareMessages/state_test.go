@@ -0,0 +1,104 @@
+package prepareMessages
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+func TestClientState(t *testing.T) {
+	c := &common.CommonState{Round: 7}
+	cli, err := NewClient(c, 42, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numLayers := 3
+	cli.PathKeys = make([]*PathKey, numLayers+1)
+	for i := range cli.PathKeys {
+		_, sk := crypto.NewSigningKeyPair()
+		secret, _ := sk.ToScalar()
+		k := &PathKey{SigningKey: sk, Secret: *secret, ServerID: int64(i), PrevServerID: int64(i - 1)}
+		// the last key is only shared with the previous server
+		if i < numLayers {
+			k.Shared = make([]byte, 32)
+			rand.Read(k.Shared)
+		}
+		if i > 0 {
+			k.PrevShared = make([]byte, 32)
+			rand.Read(k.PrevShared)
+		}
+		cli.PathKeys[i] = k
+	}
+	vk := cli.PathKeys[0].SigningKey.VerificationKey()
+	cli.routingKey = vk.LookupKey()
+	cli.AnonymousVerificationKey = cli.PathKeys[numLayers].SigningKey.VerificationKey()
+	cli.Receipts = [][]byte{make([]byte, 24), make([]byte, 24), make([]byte, 24)}
+	for _, r := range cli.Receipts {
+		rand.Read(r)
+	}
+
+	fn := filepath.Join(t.TempDir(), "client.state")
+	passphrase := []byte("correct horse")
+	err = WriteClientState(fn, passphrase, cli.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the keys are not on disk in the clear
+	box, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(box, cli.submissionKey) || bytes.Contains(box, cli.PathKeys[1].SigningKey) {
+		t.Fatalf("Secret keys written in the clear")
+	}
+
+	s, err := ReadClientState(fn, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Round != 7 {
+		t.Fatalf("Round %d not restored", s.Round)
+	}
+	loaded, err := s.Client(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != cli.ID || loaded.group != cli.group || !bytes.Equal(loaded.submissionKey, cli.submissionKey) || !bytes.Equal(loaded.verificationKey, cli.verificationKey) {
+		t.Fatalf("Client identity not restored")
+	}
+	if loaded.routingKey != cli.routingKey || !bytes.Equal(loaded.AnonymousVerificationKey, cli.AnonymousVerificationKey) {
+		t.Fatalf("Public path keys not derived")
+	}
+	for i, k := range loaded.PathKeys {
+		o := cli.PathKeys[i]
+		if !bytes.Equal(k.SigningKey, o.SigningKey) || k.Secret.Equal(o.Secret.Scalar) != 1 || k.ServerID != o.ServerID || k.PrevServerID != o.PrevServerID ||
+			!bytes.Equal(k.Shared, o.Shared) || !bytes.Equal(k.PrevShared, o.PrevShared) {
+			t.Fatalf("Path key %d not restored", i)
+		}
+	}
+	for i, r := range loaded.Receipts {
+		if !bytes.Equal(r, cli.Receipts[i]) {
+			t.Fatalf("Receipt %d not restored", i)
+		}
+	}
+
+	_, err = ReadClientState(fn, []byte("wrong"))
+	if _, ok := err.(*errors.PassphraseError); !ok {
+		t.Fatalf("Opened with the wrong passphrase: %v", err)
+	}
+	box[len(box)-1] ^= 1
+	err = os.WriteFile(fn, box, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadClientState(fn, passphrase)
+	if _, ok := err.(*errors.PassphraseError); !ok {
+		t.Fatalf("Changed state accepted: %v", err)
+	}
+}
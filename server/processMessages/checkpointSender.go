@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/nizk"
 	"github.com/simonlangowski/lightning1/errors"
 	"github.com/simonlangowski/lightning1/network"
 	"github.com/simonlangowski/lightning1/network/buffers"
@@ -15,15 +16,18 @@ import (
 type Progress struct {
 	boomerang  []byte
 	partialKey *crypto.DHPublicKey
+	clientKey  *crypto.DHPublicKey // decrypted by each member of the group
 	group      int
 	key        *BootstrapKey
 	used       bool
+	dropped    bool // a member of the group did not prove its partial decryption
 }
 
 type CheckpointSender struct {
 	c               *common.CommonState
 	reverseMessages map[crypto.LookupKey]*Progress
-	toGroupBuffers  map[int]*buffers.MemReadWriter
+	toGroupBuffers  map[int]buffers.Buffer
+	blamed          map[int][]int // group, members whose partial decryptions did not verify
 	mu              sync.Mutex
 }
 
@@ -31,23 +35,29 @@ func NewCheckpointSender(c *common.CommonState, layer int) *CheckpointSender {
 	s := &CheckpointSender{
 		c:               c,
 		reverseMessages: make(map[crypto.LookupKey]*Progress),
-		toGroupBuffers:  make(map[int]*buffers.MemReadWriter),
+		toGroupBuffers:  make(map[int]buffers.Buffer),
+		blamed:          make(map[int][]int),
 	}
 	for i := 0; i < c.NumGroups; i++ {
-		s.toGroupBuffers[i] = buffers.NewMemReadWriter(checkpoint.TOKEN_MESSAGE_LENGTH, c.GroupBinSize, c.Shufflers[i])
+		s.toGroupBuffers[i] = buffers.NewBuffer(checkpoint.TOKEN_MESSAGE_LENGTH, c.GroupBinSize, c.Shufflers[i])
 	}
 	return s
 }
 
 func (c *CheckpointSender) AddReverseMessage(boomerang []byte, info *checkpoint.CheckpointInfo, key *BootstrapKey, group int) error {
+	clientKey, err := info.AnonymousVerificationKey.ToCurvePoint()
+	if err != nil {
+		return errors.BadElementError()
+	}
 	p := &Progress{
 		boomerang:  boomerang,
 		partialKey: crypto.ZeroPoint(),
+		clientKey:  clientKey,
 		used:       false,
 		key:        key,
 		group:      group,
 	}
-	err := c.toGroupBuffers[group].Write(info.Marshal())
+	err = c.toGroupBuffers[group].Write(info.Marshal())
 	if err != nil {
 		return err
 	}
@@ -79,6 +89,10 @@ func (c *CheckpointSender) SendAndRecieve(m *network.ConnectionManager) error {
 				}
 				groupLocks[gid].Lock()
 				err = c.HandleResponse(sm, gid)
+				if _, ok := err.(*errors.BadDecryptionError); ok {
+					// the messages of the group are dropped once every member has responded
+					err = nil
+				}
 				if err != nil {
 					done <- err
 				}
@@ -101,25 +115,68 @@ func (c *CheckpointSender) SendAndRecieve(m *network.ConnectionManager) error {
 	return nil
 }
 
+// Add the partial decryptions of a member of the group, once their proofs verify with the member's share of the group key
 func (c *CheckpointSender) HandleResponse(sm *messages.SignedMessage, group int) error {
 	if !c.c.Verify(sm) {
 		return errors.SignatureError()
 	}
-	for pos := 0; pos < len(sm.Data); pos += checkpoint.RESPONSE_LENGTH {
-		cm := checkpoint.CheckpointResponse{}
-		err := cm.InterpretFrom(sm.Data[pos : pos+checkpoint.RESPONSE_LENGTH])
-		if err != nil {
-			return err
+	n := len(sm.Data) / checkpoint.RESPONSE_LENGTH
+	responses := make([]checkpoint.CheckpointResponse, n)
+	progress := make([]*Progress, n)
+	clientKeys := make([]*crypto.DHPublicKey, n)
+	partialKeys := make([]*crypto.DHPublicKey, n)
+	proofs := make([]*nizk.DecryptionProof, n)
+	shareKey := c.shareKey(group, sm.Sender)
+	ok := shareKey != nil && len(sm.Data)%checkpoint.RESPONSE_LENGTH == 0
+	for i := 0; ok && i < n; i++ {
+		pos := i * checkpoint.RESPONSE_LENGTH
+		ok = responses[i].InterpretFrom(sm.Data[pos:pos+checkpoint.RESPONSE_LENGTH]) == nil
+		c.mu.Lock()
+		progress[i] = c.reverseMessages[responses[i].PublicKey]
+		c.mu.Unlock()
+		ok = ok && progress[i] != nil && progress[i].group == group
+		if ok {
+			clientKeys[i] = progress[i].clientKey
+			partialKeys[i] = &responses[i].PartialKey
+			proofs[i] = &responses[i].Proof
+		}
+	}
+	if !ok || !nizk.BatchVerifyDecryptions(shareKey, clientKeys, partialKeys, proofs) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.blamed[group] = append(c.blamed[group], sm.Sender)
+		return errors.BadDecryption(group, []int{sm.Sender})
+	}
+	for i, p := range progress {
+		p.partialKey = p.partialKey.Accumulate(partialKeys[i])
+	}
+	return nil
+}
+
+// the public key of the member's share of the group key
+func (c *CheckpointSender) shareKey(group, server int) *crypto.DHPublicKey {
+	if group >= len(c.c.GroupShareKeys) {
+		return nil
+	}
+	for position, sid := range c.c.GroupConfigs.Groups[int64(group)].Servers {
+		if int(sid) == server && position < len(c.c.GroupShareKeys[group]) {
+			return &c.c.GroupShareKeys[group][position]
 		}
-		p := c.reverseMessages[cm.PublicKey]
-		p.partialKey = p.partialKey.Accumulate(&cm.PartialKey)
 	}
 	return nil
 }
 
 func (c *CheckpointSender) DecryptGroup(group int) error {
+	c.mu.Lock()
+	blamed := len(c.blamed[group]) > 0
+	c.mu.Unlock()
 	for _, s := range c.reverseMessages {
 		if s.group == group {
+			if blamed {
+				// without every partial decryption the boomerang cannot be opened
+				s.dropped = true
+				continue
+			}
 			err := c.HandleOne(s)
 			if err != nil {
 				return err
@@ -147,13 +204,31 @@ func (c *CheckpointSender) HandleOne(s *Progress) error {
 // the decrypted boomerang messages
 // attach the keys from path establishment
 func (c *CheckpointSender) GetDecrypted() ([][]byte, []*BootstrapKey) {
-	decrypted := make([][]byte, len(c.reverseMessages))
-	keys := make([]*BootstrapKey, len(c.reverseMessages))
-	idx := 0
+	decrypted := make([][]byte, 0, len(c.reverseMessages))
+	keys := make([]*BootstrapKey, 0, len(c.reverseMessages))
 	for _, p := range c.reverseMessages {
-		decrypted[idx] = p.boomerang
-		keys[idx] = p.key
-		idx += 1
+		if !p.dropped {
+			decrypted = append(decrypted, p.boomerang)
+			keys = append(keys, p.key)
+		}
 	}
 	return decrypted, keys
 }
+
+// the keys of the messages of groups with a member that did not prove its partial decryption
+func (c *CheckpointSender) Dropped() []*BootstrapKey {
+	keys := make([]*BootstrapKey, 0)
+	for _, p := range c.reverseMessages {
+		if p.dropped {
+			keys = append(keys, p.key)
+		}
+	}
+	return keys
+}
+
+// the members of each group whose partial decryptions did not verify
+func (c *CheckpointSender) Blamed() map[int][]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blamed
+}
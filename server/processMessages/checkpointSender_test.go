@@ -0,0 +1,104 @@
+package processMessages
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/crypto/nizk"
+	"github.com/simonlangowski/lightning1/crypto/token"
+	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/network/messages"
+	"github.com/simonlangowski/lightning1/server/checkpoint"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+func TestCheckpointPartialDecryptions(t *testing.T) {
+	groups := map[int64]*config.Group{
+		0: {Gid: 0, Servers: []int64{0, 1}},
+		1: {Gid: 1, Servers: []int64{1, 2}},
+	}
+	states := common.NewMockCommonStates(3, &common.CommonState{
+		NumServers:   3,
+		NumLayers:    1,
+		NumGroups:    len(groups),
+		GroupBinSize: 4,
+		GroupConfigs: &config.Groups{Groups: groups},
+		Shufflers:    []*config.Shuffler{config.NewPRGShuffler(rand.Reader), config.NewPRGShuffler(rand.Reader)},
+	})
+	sender := states[0]
+	// every group shares the same key, split among its members
+	groupSecret, groupKey := crypto.NewDHKeyPair()
+	shares := make([][]*crypto.DHPrivateKey, len(groups))
+	sender.GroupShareKeys = make([][]crypto.DHPublicKey, len(groups))
+	for gid := range shares {
+		shares[gid] = crypto.AdditiveShares(&groupSecret, 2)
+		for _, s := range shares[gid] {
+			sender.GroupShareKeys[gid] = append(sender.GroupShareKeys[gid], *s.PublicKey())
+		}
+	}
+
+	// one boomerang for each group
+	c := NewCheckpointSender(sender, 0)
+	message := []byte("boomerang")
+	infos := make([]*checkpoint.CheckpointInfo, len(groups))
+	keys := make([]*BootstrapKey, len(groups))
+	for gid := range infos {
+		pk, sk := crypto.NewSigningKeyPair()
+		secret, _ := sk.ToScalar()
+		nonce := crypto.Nonce(sender.NumLayers, sender.NumLayers, sender.MyId)
+		boomerang := crypto.SignedSecretSeal(message, &nonce, secret.SharedKey(&groupKey), sk)
+		infos[gid] = &checkpoint.CheckpointInfo{AnonymousVerificationKey: pk, Token: *token.SkipToken(pk)}
+		keys[gid] = &BootstrapKey{VerificationKey: pk}
+		err := c.AddReverseMessage(boomerang, infos[gid], keys[gid], gid)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second member of the second group does not use its share
+	liar := 2
+	for gid := range infos {
+		for position, sid := range groups[int64(gid)].Servers {
+			clientKey, _ := infos[gid].AnonymousVerificationKey.ToCurvePoint()
+			share := shares[gid][position]
+			if int(sid) == liar && gid == 1 {
+				share = crypto.RandomCurveScalar()
+			}
+			proof, partialKey := nizk.NewDecryptionProof(clientKey, share)
+			r := checkpoint.CheckpointResponse{PublicKey: infos[gid].AnonymousVerificationKey.LookupKey(), PartialKey: *partialKey, Proof: *proof}
+			m := messages.NewSignedMessage(r.Len(), 0, 0, int(sid), gid, sender.MyId, 1, messages.NetworkMessage_GroupCheckpointToken)
+			r.PackTo(m.Data)
+			states[sid].Sign(m)
+			err := c.HandleResponse(m, gid)
+			if int(sid) == liar && gid == 1 {
+				if bad, ok := err.(*errors.BadDecryptionError); !ok || bad.Group != 1 || bad.Servers[0] != liar {
+					t.Fatalf("Bad partial decryption was not blamed: %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for gid := range infos {
+		err := c.DecryptGroup(gid)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blamed := c.Blamed()
+	if len(blamed) != 1 || len(blamed[1]) != 1 || blamed[1][0] != liar {
+		t.Fatalf("Wrong members blamed: %v", blamed)
+	}
+	decrypted, decryptedKeys := c.GetDecrypted()
+	if len(decrypted) != 1 || decryptedKeys[0] != keys[0] || !bytes.Equal(decrypted[0], message) {
+		t.Fatalf("Boomerang of the honest group was not decrypted")
+	}
+	dropped := c.Dropped()
+	if len(dropped) != 1 || dropped[0] != keys[1] {
+		t.Fatalf("Boomerang of the group with a bad member was not dropped")
+	}
+}
@@ -1,11 +1,16 @@
 package processMessages
 
 import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
 	"sync"
 
 	"github.com/simonlangowski/lightning1/config"
 	"github.com/simonlangowski/lightning1/crypto"
 	"github.com/simonlangowski/lightning1/errors"
+	"github.com/simonlangowski/lightning1/server/checkpoint"
 	"github.com/simonlangowski/lightning1/server/common"
 )
 
@@ -19,9 +24,6 @@ type BootstrapKey struct {
 	// The client's verification key on link l-1 to l
 	VerificationKey crypto.VerificationKey
 
-	//
-	ExpandedVerificationKey *crypto.ExpandedVerificationKey
-
 	// server at l+1
 	NextServer int
 	// the shared key corresponding to the link l to l+1
@@ -31,6 +33,13 @@ type BootstrapKey struct {
 
 	ExpandedOutgoingVerificationKey *crypto.ExpandedVerificationKey
 
+	// the keys of the current lightning round, see crypto.RatchetScalar
+	roundLookup                  crypto.LookupKey
+	roundVerificationKey         crypto.VerificationKey
+	roundExpandedVerificationKey *crypto.ExpandedVerificationKey
+	roundOutgoingLookup          crypto.LookupKey
+	roundAnonymousKey            crypto.VerificationKey // in the last layer, the key the trustees check the final message with
+
 	used bool // set to true when used
 }
 
@@ -38,26 +47,73 @@ type KeyLookupTable struct {
 	table        map[crypto.LookupKey]*BootstrapKey // by IncomingLookupKey
 	reverseTable map[crypto.LookupKey]*BootstrapKey // by OutgoingLookupKey - used when routing boomerang or in reverse
 	secretKey    *crypto.DHPrivateKey               // the secret key for this layer
-	mu           sync.Mutex
+	revoked      map[crypto.LookupKey]*BootstrapKey // keys of revoked paths in either direction, whose envelopes are skipped
+	// messages use the lookup keys of the round, see crypto.RatchetLookup
+	// lightning rounds also use the ratcheted verification keys of the round
+	roundTable        map[crypto.LookupKey]*BootstrapKey
+	roundReverseTable map[crypto.LookupKey]*BootstrapKey
+	roundRevoked      map[crypto.LookupKey]bool
+	round             int  // -1 before the first round the table is ratcheted in
+	last              bool // the outgoing keys go to the trustees
+	mu                sync.Mutex
+
+	c     *common.CommonState
+	layer int
+	links map[int]crypto.DHSharedKey // the keys shared with the neighbouring servers
 }
 
-func NewKeyLookupTable(c *common.CommonState) *KeyLookupTable {
+func NewKeyLookupTable(c *common.CommonState, layer int) *KeyLookupTable {
 	t := &KeyLookupTable{
-		table:        make(map[crypto.LookupKey]*BootstrapKey),
-		reverseTable: make(map[crypto.LookupKey]*BootstrapKey),
-		secretKey:    &c.ServerSecretKey,
+		table:             make(map[crypto.LookupKey]*BootstrapKey),
+		reverseTable:      make(map[crypto.LookupKey]*BootstrapKey),
+		revoked:           make(map[crypto.LookupKey]*BootstrapKey),
+		roundTable:        make(map[crypto.LookupKey]*BootstrapKey),
+		roundReverseTable: make(map[crypto.LookupKey]*BootstrapKey),
+		roundRevoked:      make(map[crypto.LookupKey]bool),
+		round:             -1,
+		secretKey:         &c.ServerSecretKey,
+		c:                 c,
+		layer:             layer,
+		links:             make(map[int]crypto.DHSharedKey),
 	}
 	return t
 }
 
 func (t *KeyLookupTable) AddKey(key crypto.VerificationKey, sharedKey crypto.DHSharedKey, prev, next int, nextKey crypto.VerificationKey) (*BootstrapKey, error) {
+	b, err := t.newKey(key, sharedKey, prev, next, nextKey)
+	if err != nil {
+		return nil, err
+	}
 	l := key.LookupKey()
 	rl := nextKey.LookupKey()
+	// the incoming keys are expanded by ratchetKey, since lightning rounds verify the keys of the round
+	// for the outgoing keys in path establishment rounds:
+	// if config.PreExpandKeys {
+	// 	b.ExpandedOutgoingVerificationKey, err = b.OutgoingVerificationKey.ExpandKey()
+	// 	if err != nil {
+	// 		return nil, err
+	// 	}
+	// }
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table[l] = b
+	t.reverseTable[rl] = b
+	if t.round >= 0 {
+		// added during a lightning round
+		err = t.ratchetKey(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (t *KeyLookupTable) newKey(key crypto.VerificationKey, sharedKey crypto.DHSharedKey, prev, next int, nextKey crypto.VerificationKey) (*BootstrapKey, error) {
 	pt, err := nextKey.ToCurvePoint()
 	if err != nil {
 		return nil, errors.BadElementError()
 	}
-	b := &BootstrapKey{
+	return &BootstrapKey{
 		SharedKey:               sharedKey,
 		VerificationKey:         key.Copy(),
 		OutgoingSharedKey:       t.secretKey.SharedKey(pt),
@@ -65,24 +121,154 @@ func (t *KeyLookupTable) AddKey(key crypto.VerificationKey, sharedKey crypto.DHS
 		PrevServer:              prev,
 		NextServer:              next,
 		used:                    false,
+	}, nil
+}
+
+// Re-index the table with the keys of the lightning round
+// in the last layer, also ratchet the anonymous keys the final messages are sent to the trustees with
+func (t *KeyLookupTable) Ratchet(round int, last bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = last
+	t.resetRound(round)
+	for _, b := range t.table {
+		err := t.ratchetKey(b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Re-index the table with the lookup keys of a path establishment round, which the boomerang messages return under
+func (t *KeyLookupTable) RatchetLookups(round int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resetRound(round)
+	for _, b := range t.table {
+		t.ratchetLookups(b)
+		t.indexRound(b)
+	}
+}
+
+// called holding the lock
+func (t *KeyLookupTable) resetRound(round int) {
+	t.round = round
+	t.roundTable = make(map[crypto.LookupKey]*BootstrapKey, len(t.table))
+	t.roundReverseTable = make(map[crypto.LookupKey]*BootstrapKey, len(t.table))
+	t.roundRevoked = make(map[crypto.LookupKey]bool, len(t.revoked))
+	for l, b := range t.revoked {
+		// each revoked key is under both of its lookup keys
+		if l == b.VerificationKey.LookupKey() {
+			t.ratchetLookups(b)
+			t.revokeRound(b)
+		}
+	}
+}
+
+// the lookup keys of the round in both directions, under the secrets of the links
+func (t *KeyLookupTable) ratchetLookups(b *BootstrapKey) {
+	b.roundLookup = crypto.RatchetLookup(t.incomingLink(b), b.VerificationKey.LookupKey(), t.round)
+	if !t.last {
+		// the trustees find the final messages by the anonymous keys
+		b.roundOutgoingLookup = crypto.RatchetLookup(t.link(b.NextServer), b.OutgoingVerificationKey.LookupKey(), t.round)
 	}
+}
+
+func (t *KeyLookupTable) indexRound(b *BootstrapKey) {
+	t.roundTable[b.roundLookup] = b
+	if !t.last {
+		t.roundReverseTable[b.roundOutgoingLookup] = b
+	}
+}
+
+func (t *KeyLookupTable) revokeRound(b *BootstrapKey) {
+	delete(t.roundTable, b.roundLookup)
+	t.roundRevoked[b.roundLookup] = true
+	if !t.last {
+		delete(t.roundReverseTable, b.roundOutgoingLookup)
+		t.roundRevoked[b.roundOutgoingLookup] = true
+	}
+}
+
+// the first layer arrives from the client, which shares the key of the envelope with this server
+func (t *KeyLookupTable) incomingLink(b *BootstrapKey) crypto.DHSharedKey {
+	if t.layer == 0 {
+		return b.SharedKey
+	}
+	return t.link(b.PrevServer)
+}
+
+// the key shared with a neighbouring server, as for cover traffic
+func (t *KeyLookupTable) link(sid int) crypto.DHSharedKey {
+	if sid < 0 || sid >= len(t.c.ServerPublicKeys) {
+		return nil
+	}
+	k, ok := t.links[sid]
+	if !ok {
+		k = t.secretKey.SharedKey(&t.c.ServerPublicKeys[sid])
+		t.links[sid] = k
+	}
+	return k
+}
+
+func (t *KeyLookupTable) ratchetKey(b *BootstrapKey) error {
+	var err error
+	t.ratchetLookups(b)
+	// the incoming key is ratcheted with the key shared with the client
+	b.roundVerificationKey, err = b.VerificationKey.Ratchet(crypto.RatchetScalar(b.SharedKey, t.round))
+	if err != nil {
+		return errors.BadElementError()
+	}
+	b.roundExpandedVerificationKey = nil
 	if config.PreExpandKeys {
-		// in lightning rounds
-		b.ExpandedVerificationKey, err = b.VerificationKey.ExpandKey()
+		b.roundExpandedVerificationKey, err = b.roundVerificationKey.ExpandKey()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		// in path establishment rounds
-		// b.ExpandedOutgoingVerificationKey, err = b.OutgoingVerificationKey.ExpandKey()
-		// if err != nil {
-		// 	return nil, err
-		// }
 	}
+	if t.last {
+		// ratcheted with the key shared with the last server, which proves the ratchet to the trustees when it registers the key
+		b.roundAnonymousKey, err = b.OutgoingVerificationKey.Ratchet(crypto.RatchetScalar(b.OutgoingSharedKey, t.round))
+		if err != nil {
+			return errors.BadElementError()
+		}
+	}
+	t.indexRound(b)
+	return nil
+}
+
+// Find the key of an envelope by the lookup key of the round, incoming or, for boomerang messages, outgoing
+func (t *KeyLookupTable) LookupRound(key *crypto.LookupKey, reverse bool) *BootstrapKey {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.table[l] = b
-	t.reverseTable[rl] = b
-	return b, nil
+	if reverse {
+		return t.roundReverseTable[*key]
+	}
+	return t.roundTable[*key]
+}
+
+func (t *KeyLookupTable) IsRevokedRound(key *crypto.LookupKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.roundRevoked[*key]
+}
+
+// the anonymous keys of the round in the last layer, by the group the final messages go to
+// each with the proof that it is the ratchet of the key the group allowed for its path
+func (t *KeyLookupTable) AnonymousKeys() map[int][]checkpoint.AnonymousKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make(map[int][]checkpoint.AnonymousKey)
+	for _, b := range t.table {
+		k, err := checkpoint.NewAnonymousKey(b.OutgoingVerificationKey, crypto.RatchetScalar(b.OutgoingSharedKey, t.round))
+		if err != nil {
+			// not a point, so it was not ratcheted either
+			continue
+		}
+		keys[b.NextServer] = append(keys[b.NextServer], *k)
+	}
+	return keys
 }
 
 func (t *KeyLookupTable) Lookup(key *crypto.LookupKey, reverse bool) *BootstrapKey {
@@ -99,9 +285,62 @@ func (t *KeyLookupTable) NumKeys() int {
 	return len(t.table)
 }
 
-func (t *KeyLookupTable) RevokeKey(key *crypto.VerificationKey) {
-	delete(t.table, key.LookupKey())
-	// delete(t.reverseTable, key.LookupKey())
+// Remove the key from both tables, and skip its envelopes in this and every later round
+// returns the removed key, or nil if it was not in the table
+func (t *KeyLookupTable) RevokeKey(key *crypto.LookupKey, reverse bool) *BootstrapKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b *BootstrapKey
+	if reverse {
+		b = t.reverseTable[*key]
+	} else {
+		b = t.table[*key]
+	}
+	if b == nil {
+		return nil
+	}
+	l := b.VerificationKey.LookupKey()
+	rl := b.OutgoingVerificationKey.LookupKey()
+	delete(t.table, l)
+	delete(t.reverseTable, rl)
+	t.revoked[l] = b
+	t.revoked[rl] = b
+	if t.round >= 0 {
+		t.revokeRound(b)
+	}
+	return b
+}
+
+func (t *KeyLookupTable) IsRevoked(key *crypto.LookupKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.revoked[*key] != nil
+}
+
+// the incoming keys from a previous server, which in the first layer is the client
+func (t *KeyLookupTable) KeysFrom(prev int) []crypto.LookupKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]crypto.LookupKey, 0)
+	for l, k := range t.table {
+		if k.PrevServer == prev {
+			keys = append(keys, l)
+		}
+	}
+	return keys
+}
+
+// keys that have not been used since the last reset
+func (t *KeyLookupTable) UnusedKeys() []*BootstrapKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	unused := make([]*BootstrapKey, 0)
+	for _, k := range t.table {
+		if !k.used {
+			unused = append(unused, k)
+		}
+	}
+	return unused
 }
 
 func (t *KeyLookupTable) ResetUsage() {
@@ -110,65 +349,144 @@ func (t *KeyLookupTable) ResetUsage() {
 	}
 }
 
-/*
-const size = 2 * (crypto.POINT_SIZE + crypto.KEY_SIZE + 8)
+// On disk the table is a version, the number of keys, and then each key
+// followed by the number of revoked keys, and then each revoked key in the same way,
+// since the lookup keys of a round are found from the keys of the links
+// Both lookup tables index the same keys, so the reverse table is rebuilt on load
+const TABLE_VERSION = 3
+const tableHeaderSize = 8
+const tableEntrySize = crypto.POINT_SIZE + 2*crypto.KEY_SIZE + 2*8
 
-func (table *KeyLookupTable) LoadTableFromFile(fn string) error {
+func (t *KeyLookupTable) LoadTableFromFile(fn string) error {
 	f, err := os.Open(fn)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	r := bufio.NewReader(f)
-	b := make([]byte, size)
-	for err != io.EOF {
-		_, err = r.Read(b)
-		if err != nil && err != io.EOF {
+	header := make([]byte, tableHeaderSize)
+	_, err = io.ReadFull(r, header)
+	if err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(header[:4]) != TABLE_VERSION {
+		return errors.VersionError()
+	}
+	numKeys := int(binary.LittleEndian.Uint32(header[4:]))
+	for i := 0; i < numKeys; i++ {
+		err = t.readEntry(r, false)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.ReadFull(r, header[:4])
+	if err != nil {
+		return err
+	}
+	numRevoked := int(binary.LittleEndian.Uint32(header[:4]))
+	for i := 0; i < numRevoked; i++ {
+		err = t.readEntry(r, true)
+		if err != nil {
 			return err
 		}
-		key := &BootstrapKey{}
-		pos := 0
-		// key.ClientKey.InterpretFrom(b[pos : pos+crypto.POINT_SIZE])
-		// pos += crypto.POINT_SIZE
-		key.SharedKey.InterpretFrom(b[pos : pos+crypto.POINT_SIZE])
-		pos += crypto.POINT_SIZE
-		copy(key.IncomingLookupKey[:], b[pos:pos+crypto.KEY_SIZE])
-		pos += crypto.KEY_SIZE
-		key.NextServer = int(binary.LittleEndian.Uint64(b[pos : pos+8]))
-		pos += 8
-		copy(key.OutgoingLookupKey[:], b[pos:pos+crypto.KEY_SIZE])
-		pos += crypto.KEY_SIZE
-		key.PrevServer = int(binary.LittleEndian.Uint64(b[pos : pos+8]))
-
-		table.table[key.IncomingLookupKey] = key
-		table.reverseTable[key.OutgoingLookupKey] = key
 	}
 	return nil
 }
 
-func (table *KeyLookupTable) WriteTableToFile(fn string) error {
-	f, err := os.Create(fn)
+func (t *KeyLookupTable) readEntry(r io.Reader, revoked bool) error {
+	b := make([]byte, tableEntrySize)
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return err
+	}
+	pos := 0
+	sharedKey := crypto.DHSharedKey(b[pos : pos+crypto.POINT_SIZE])
+	pos += crypto.POINT_SIZE
+	key := crypto.VerificationKey(b[pos : pos+crypto.KEY_SIZE])
+	pos += crypto.KEY_SIZE
+	nextKey := crypto.VerificationKey(b[pos : pos+crypto.KEY_SIZE])
+	pos += crypto.KEY_SIZE
+	prev := int(binary.LittleEndian.Uint64(b[pos : pos+8]))
+	pos += 8
+	next := int(binary.LittleEndian.Uint64(b[pos : pos+8]))
+	if !revoked {
+		_, err = t.AddKey(key, sharedKey, prev, next, nextKey)
+		return err
+	}
+	k, err := t.newKey(key, sharedKey, prev, next, nextKey)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.revoked[key.LookupKey()] = k
+	t.revoked[nextKey.LookupKey()] = k
+	return nil
+}
+
+func packEntry(b []byte, key *BootstrapKey) {
+	pos := 0
+	copy(b[pos:pos+crypto.POINT_SIZE], key.SharedKey)
+	pos += crypto.POINT_SIZE
+	copy(b[pos:pos+crypto.KEY_SIZE], key.VerificationKey)
+	pos += crypto.KEY_SIZE
+	copy(b[pos:pos+crypto.KEY_SIZE], key.OutgoingVerificationKey)
+	pos += crypto.KEY_SIZE
+	binary.LittleEndian.PutUint64(b[pos:pos+8], uint64(key.PrevServer))
+	pos += 8
+	binary.LittleEndian.PutUint64(b[pos:pos+8], uint64(key.NextServer))
+}
+
+// write to a temporary file and rename so a crash never leaves a partial table
+func (t *KeyLookupTable) WriteTableToFile(fn string) error {
+	tmp := fn + ".tmp"
+	f, err := os.Create(tmp)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 	w := bufio.NewWriter(f)
-	defer w.Flush()
-	t := make([]byte, 8)
-	for _, key := range table.table {
-		// write each key to the file
-		// w.Write(key.ClientKey.Marshal())
-		w.Write(key.SharedKey.Marshal())
-		w.Write(key.IncomingLookupKey[:])
-		binary.LittleEndian.PutUint64(t, uint64(key.NextServer))
-		w.Write(t)
-		w.Write(key.OutgoingLookupKey[:])
-		binary.LittleEndian.PutUint64(t, uint64(key.PrevServer))
-		_, err = w.Write(t)
+	t.mu.Lock()
+	header := make([]byte, tableHeaderSize)
+	binary.LittleEndian.PutUint32(header[:4], TABLE_VERSION)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(t.table)))
+	w.Write(header)
+	b := make([]byte, tableEntrySize)
+	for _, key := range t.table {
+		packEntry(b, key)
+		_, err = w.Write(b)
 		if err != nil {
-			return err
+			break
 		}
 	}
-	return nil
+	// each revoked key is under both of its lookup keys
+	revoked := make([]*BootstrapKey, 0, len(t.revoked)/2)
+	for l, key := range t.revoked {
+		if l == key.VerificationKey.LookupKey() {
+			revoked = append(revoked, key)
+		}
+	}
+	if err == nil {
+		binary.LittleEndian.PutUint32(header[:4], uint32(len(revoked)))
+		_, err = w.Write(header[:4])
+	}
+	for _, key := range revoked {
+		if err != nil {
+			break
+		}
+		packEntry(b, key)
+		_, err = w.Write(b)
+	}
+	t.mu.Unlock()
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, fn)
 }
-*/
@@ -0,0 +1,276 @@
+package processMessages
+
+import (
+	"bytes"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+func TestKeyTableFile(t *testing.T) {
+	c := common.NewMockCommonStates(1, nil)[0]
+	table := NewKeyLookupTable(c, 0)
+	keys := make([]crypto.VerificationKey, 10)
+	nextKeys := make([]crypto.VerificationKey, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.NewSigningKeyPair()
+		nextKeys[i], _ = crypto.NewSigningKeyPair()
+		p, _ := keys[i].ToCurvePoint()
+		_, err := table.AddKey(keys[i], c.ServerSecretKey.SharedKey(p), i, i+1, nextKeys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fn := filepath.Join(t.TempDir(), "layer0.keys")
+	err := table.WriteTableToFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewKeyLookupTable(c, 0)
+	err = loaded.LoadTableFromFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.NumKeys() != len(keys) {
+		t.Fatalf("Loaded %d keys, expected %d", loaded.NumKeys(), len(keys))
+	}
+	for i := range keys {
+		l := keys[i].LookupKey()
+		original := table.Lookup(&l, false)
+		forward := loaded.Lookup(&l, false)
+		rl := nextKeys[i].LookupKey()
+		reverse := loaded.Lookup(&rl, true)
+		if forward == nil || forward != reverse {
+			t.Fatalf("Key %d not found in both tables", i)
+		}
+		if forward.PrevServer != i || forward.NextServer != i+1 {
+			t.Fatalf("Servers do not match")
+		}
+		if !bytes.Equal(forward.SharedKey, original.SharedKey) || !bytes.Equal(forward.OutgoingSharedKey, original.OutgoingSharedKey) {
+			t.Fatalf("Shared keys do not match")
+		}
+		if !bytes.Equal(forward.OutgoingVerificationKey, nextKeys[i]) {
+			t.Fatalf("Verification keys do not match")
+		}
+	}
+}
+
+func TestRevokeKey(t *testing.T) {
+	// the next servers of the keys
+	c := common.NewMockCommonStates(5, nil)[0]
+	table := NewKeyLookupTable(c, 0)
+	keys := make([]crypto.VerificationKey, 4)
+	nextKeys := make([]crypto.VerificationKey, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.NewSigningKeyPair()
+		nextKeys[i], _ = crypto.NewSigningKeyPair()
+		p, _ := keys[i].ToCurvePoint()
+		_, err := table.AddKey(keys[i], c.ServerSecretKey.SharedKey(p), i, i+1, nextKeys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// revoked by its outgoing key, as by the next server on the path
+	rl := nextKeys[1].LookupKey()
+	b := table.RevokeKey(&rl, true)
+	if b == nil || b.PrevServer != 1 {
+		t.Fatalf("Wrong key revoked")
+	}
+	if table.RevokeKey(&rl, true) != nil {
+		t.Fatalf("Key revoked twice")
+	}
+	l := keys[1].LookupKey()
+	if table.NumKeys() != len(keys)-1 || table.Lookup(&l, false) != nil || !table.IsRevoked(&l) || !table.IsRevoked(&rl) {
+		t.Fatalf("Key not removed from both tables")
+	}
+	if from := table.KeysFrom(2); len(from) != 1 || from[0] != keys[2].LookupKey() {
+		t.Fatalf("Wrong keys from previous server")
+	}
+
+	// envelopes under the revoked key are skipped without an error
+	c.NumLayers = 1
+	err := table.Ratchet(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := NewOnionParser(c, table, false)
+	p, _ := keys[1].ToCurvePoint()
+	roundKey := crypto.RatchetLookup(c.ServerSecretKey.SharedKey(p), l, 0)
+	envelope := common.LightningEnvelope{Key: roundKey, SignedCiphertext: make([]byte, crypto.SIGNATURE_SIZE+16)}
+	decrypted, key, err := parser.AuthenticatedOnionParse(nil, envelope.Marshal())
+	if decrypted != nil || key != nil || err != nil {
+		t.Fatalf("Revoked envelope was not skipped")
+	}
+	decryptions, batchKeys, errs := parser.AuthenticatedOnionParseBatch(nil, [][]byte{envelope.Marshal()})
+	if decryptions[0] != nil || batchKeys[0] != nil || errs[0] != nil {
+		t.Fatalf("Revoked envelope was not skipped in batch")
+	}
+
+	// revocations are kept with the table
+	fn := filepath.Join(t.TempDir(), "layer0.keys")
+	err = table.WriteTableToFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewKeyLookupTable(c, 0)
+	err = loaded.LoadTableFromFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.NumKeys() != len(keys)-1 || !loaded.IsRevoked(&l) || !loaded.IsRevoked(&rl) {
+		t.Fatalf("Revocations not loaded")
+	}
+	// with the keys of their links, so that they are revoked in later rounds
+	err = loaded.Ratchet(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsRevokedRound(&roundKey) {
+		t.Fatalf("Loaded revocation not found in a later round")
+	}
+}
+
+func TestRatchetKeys(t *testing.T) {
+	c := common.NewMockCommonStates(1, nil)[0]
+	table := NewKeyLookupTable(c, 0)
+	keys := make([]crypto.VerificationKey, 4)
+	signingKeys := make([]crypto.SigningKey, len(keys))
+	nextSigningKeys := make([]crypto.SigningKey, len(keys))
+	add := func(i int) {
+		var nextKey crypto.VerificationKey
+		keys[i], signingKeys[i] = crypto.NewSigningKeyPair()
+		nextKey, nextSigningKeys[i] = crypto.NewSigningKeyPair()
+		p, _ := keys[i].ToCurvePoint()
+		_, err := table.AddKey(keys[i], c.ServerSecretKey.SharedKey(p), i, 0, nextKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < len(keys)-1; i++ {
+		add(i)
+	}
+	anonymous := make(map[string]bool)
+	for round := 0; round < 2; round++ {
+		err := table.Ratchet(round, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if round == 1 {
+			// keys added during a round are ratcheted when added
+			add(len(keys) - 1)
+		}
+		for i := range keys {
+			if i == len(keys)-1 && round == 0 {
+				continue
+			}
+			l := keys[i].LookupKey()
+			if table.LookupRound(&l, false) != nil {
+				t.Fatalf("Found key %d under its lookup key of path establishment", i)
+			}
+			// the client finds the lookup key of the round with the key it shares with the first server
+			s, _ := signingKeys[i].ToScalar()
+			shared := s.SharedKey(&c.ServerPublicKeys[0])
+			rl := crypto.RatchetLookup(shared, l, round)
+			b := table.LookupRound(&rl, false)
+			if b == nil {
+				t.Fatalf("Key %d not found in round %d", i, round)
+			}
+			// the key the client signs with in this round
+			signingKey := signingKeys[i].Ratchet(crypto.RatchetScalar(shared, round))
+			if !bytes.Equal(b.roundVerificationKey, signingKey.VerificationKey()) || bytes.Equal(b.roundVerificationKey, keys[i]) {
+				t.Fatalf("Wrong verification key of key %d in round %d", i, round)
+			}
+			// and the anonymous key of the final message
+			s, _ = nextSigningKeys[i].ToScalar()
+			anonymousKey := nextSigningKeys[i].Ratchet(crypto.RatchetScalar(s.SharedKey(&c.ServerPublicKeys[0]), round))
+			if !bytes.Equal(b.roundAnonymousKey, anonymousKey.VerificationKey()) {
+				t.Fatalf("Wrong anonymous key of key %d in round %d", i, round)
+			}
+		}
+		for _, k := range table.AnonymousKeys()[0] {
+			if anonymous[string(k.Key)] {
+				t.Fatalf("Anonymous key reused in round %d", round)
+			}
+			anonymous[string(k.Key)] = true
+			if !k.Verify() {
+				t.Fatalf("Anonymous key not bound to its allowed key in round %d", round)
+			}
+		}
+	}
+	if len(anonymous) != 2*len(keys)-1 {
+		t.Fatalf("Expected %d anonymous keys, got %d", 2*len(keys)-1, len(anonymous))
+	}
+}
+
+func TestRatchetLinks(t *testing.T) {
+	shufflers := make([]*config.Shuffler, 4)
+	for i := range shufflers {
+		shufflers[i] = config.NewPRGShuffler(rand.Reader)
+	}
+	states := common.NewMockCommonStates(4, &common.CommonState{
+		NumServers:          4,
+		BinSize:             1,
+		OnionMessageLengths: []int{128, 128, 128},
+		Shufflers:           shufflers,
+	})
+	// a path from a client through servers 0, 1 and 2
+	keys := make([]crypto.VerificationKey, 4)
+	signingKeys := make([]crypto.SigningKey, len(keys))
+	for i := range keys {
+		keys[i], signingKeys[i] = crypto.NewSigningKeyPair()
+	}
+	tables := make([]*KeyLookupTable, 3)
+	path := make([]*BootstrapKey, len(tables))
+	prev := 10
+	for layer := range tables {
+		tables[layer] = NewKeyLookupTable(states[layer], layer)
+		p, _ := keys[layer].ToCurvePoint()
+		var err error
+		path[layer], err = tables[layer].AddKey(keys[layer], states[layer].ServerSecretKey.SharedKey(p), prev, layer+1, keys[layer+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev = layer
+	}
+	outsider := states[3]
+	for round := 0; round < 2; round++ {
+		for layer, table := range tables {
+			err := table.Ratchet(round, layer == len(tables)-1)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// the next server finds the envelope under the key the previous server sends it with
+		for layer := 1; layer < len(tables); layer++ {
+			if tables[layer].LookupRound(&path[layer-1].roundOutgoingLookup, false) != path[layer] {
+				t.Fatalf("Layer %d not found under the key of the link in round %d", layer, round)
+			}
+			// which no other server finds
+			l := keys[layer].LookupKey()
+			link := outsider.ServerSecretKey.SharedKey(&outsider.ServerPublicKeys[layer])
+			if crypto.RatchetLookup(link, l, round) == path[layer].roundLookup {
+				t.Fatalf("Lookup key of layer %d found without the link", layer)
+			}
+		}
+		// boomerang messages return under the keys of their round
+		boomerangRound := round + 10
+		tables[0].RatchetLookups(boomerangRound)
+		states[1].Round = boomerangRound
+		router := NewLightningRouter(states[1], 1, true)
+		l := keys[1].LookupKey()
+		reverseKey := crypto.RatchetLookup(router.link(0), l, boomerangRound)
+		if tables[0].LookupRound(&reverseKey, true) != path[0] || tables[0].LookupRound(&l, true) != nil {
+			t.Fatalf("Boomerang key of round %d not found", boomerangRound)
+		}
+	}
+	// revoked in both directions
+	l := keys[1].LookupKey()
+	tables[0].RevokeKey(&l, true)
+	if !tables[0].IsRevokedRound(&path[0].roundLookup) || !tables[0].IsRevokedRound(&path[0].roundOutgoingLookup) {
+		t.Fatalf("Revoked key not revoked in the round")
+	}
+}
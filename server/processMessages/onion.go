@@ -19,9 +19,14 @@ type OnionParser struct {
 }
 
 type LightningRouter struct {
-	OutgoingBuffers map[int]*buffers.MemReadWriter
+	OutgoingBuffers map[int]buffers.Buffer
+	overflow
+	// boomerang messages return under the lookup keys of the round, found with the keys shared with the previous servers
+	round int
+	links []crypto.DHSharedKey
 }
 
+// boomerang messages arrive under the lookup keys of the path establishment round, which the table is indexed by at the start of the round
 func NewOnionParser(c *common.CommonState, table *KeyLookupTable, reverse bool) *OnionParser {
 	return &OnionParser{
 		c:        c,
@@ -34,54 +39,125 @@ func NewOnionParser(c *common.CommonState, table *KeyLookupTable, reverse bool)
 // Decypt the message as lightning messages
 // Check that each key is used exactly once
 // Return the next destinations if set
+// An envelope whose key has been found, but whose signature has not been checked
+type openedEnvelope struct {
+	envelope        common.LightningEnvelope
+	key             *BootstrapKey
+	verificationKey crypto.VerificationKey
+	expandedKey     *crypto.ExpandedVerificationKey
+	decryptionKey   crypto.DHSharedKey
+	signedData      []byte
+	signature       crypto.Signature
+}
+
+// A revoked envelope is skipped, returning a nil key and no error
 func (o *OnionParser) AuthenticatedOnionParse(metadata *messages.Metadata, message []byte) ([]byte, *BootstrapKey, error) {
+	oe, err := o.open(message)
+	if err != nil || oe == nil {
+		return nil, nil, err
+	}
+	errors.DebugPrint("Verifying %v on %v with %v", oe.signedData, oe.signature, oe.verificationKey.PublicKey())
+	ok := false
+	if oe.expandedKey != nil {
+		ok = crypto.VerifyExpanded(oe.expandedKey, oe.signedData, oe.signature)
+	} else {
+		ok = crypto.Verify(oe.verificationKey, oe.signedData, oe.signature)
+	}
+	if !ok {
+		return nil, nil, errors.DecryptionFailure()
+	}
+	return o.decrypt(oe)
+}
+
+// The first error of a chunk, which the chunk is reported with
+func FirstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse a chunk of envelopes, checking all of their signatures in one batch
+// returns the error of each envelope, nil for those decrypted or skipped
+func (o *OnionParser) AuthenticatedOnionParseBatch(metadata *messages.Metadata, chunk [][]byte) ([][]byte, []*BootstrapKey, []error) {
+	opened := make([]*openedEnvelope, len(chunk))
+	errs := make([]error, len(chunk))
+	batch := crypto.BatchVerifier(len(chunk))
+	for i := range chunk {
+		opened[i], errs[i] = o.open(chunk[i])
+		if errs[i] != nil || opened[i] == nil {
+			continue
+		}
+		oe := opened[i]
+		if oe.expandedKey != nil {
+			crypto.AddExpandedToBatch(batch, oe.expandedKey, oe.signedData, oe.signature)
+		} else {
+			crypto.AddToBatch(batch, oe.verificationKey, oe.signedData, oe.signature)
+		}
+	}
+	valid := crypto.VerifyBatch(batch)
+	decryptions := make([][]byte, len(chunk))
+	keys := make([]*BootstrapKey, len(chunk))
+	pos := 0
+	for i := range chunk {
+		if errs[i] == nil && opened[i] != nil {
+			if !valid[pos] {
+				errs[i] = errors.DecryptionFailure()
+			} else {
+				decryptions[i], keys[i], errs[i] = o.decrypt(opened[i])
+			}
+			pos++
+		}
+	}
+	return decryptions, keys, errs
+}
+
+func (o *OnionParser) layerNumber() int {
 	// to make the nonce different for the boomerang messages
 	layer := o.c.Layer
 	if o.reverse {
 		layer += o.c.NumLayers
 	}
-	round := o.c.Round
-	server := o.c.MyId
-	nonce := crypto.Nonce(round, layer, server)
+	return layer
+}
 
-	lm := common.LightningEnvelope{}
-	err := lm.InterpretFrom(message)
+// find the key of the envelope
+// returns nil without an error if the key was revoked
+func (o *OnionParser) open(message []byte) (*openedEnvelope, error) {
+	oe := &openedEnvelope{}
+	err := oe.envelope.InterpretFrom(message)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	key := o.keyTable.Lookup(&lm.Key, o.reverse)
-	if key == nil {
-		return nil, nil, errors.KeyNotFound()
+	// envelopes use the lookup keys of the round
+	key := o.keyTable.LookupRound(&oe.envelope.Key, o.reverse)
+	if key == nil && o.keyTable.IsRevokedRound(&oe.envelope.Key) {
+		return nil, nil
+	} else if key == nil {
+		return nil, errors.KeyNotFound()
 	}
-
-	var verificationKey crypto.VerificationKey
-	var decryptionKey crypto.DHSharedKey
-	var expandedKey *crypto.ExpandedVerificationKey
+	oe.key = key
 	if !o.reverse {
-		decryptionKey = key.SharedKey
-		verificationKey = key.VerificationKey
-		expandedKey = key.ExpandedVerificationKey
-	} else {
-		verificationKey = key.OutgoingVerificationKey
-		decryptionKey = key.OutgoingSharedKey
-		expandedKey = key.ExpandedOutgoingVerificationKey
-	}
-	// or send to signature co processor
-	m := lm.GetSignedData(round, layer, server)
-	s := lm.GetSignature()
-	errors.DebugPrint("Verifying %v on %v with %v", m, s, verificationKey.PublicKey())
-
-	ok := false
-	if expandedKey != nil {
-		ok = crypto.VerifyExpanded(expandedKey, m, s)
+		oe.decryptionKey = key.SharedKey
+		oe.verificationKey = key.roundVerificationKey
+		oe.expandedKey = key.roundExpandedVerificationKey
 	} else {
-		ok = crypto.Verify(verificationKey, m, s)
-	}
-	if !ok {
-		return nil, nil, errors.DecryptionFailure()
+		oe.verificationKey = key.OutgoingVerificationKey
+		oe.decryptionKey = key.OutgoingSharedKey
+		oe.expandedKey = key.ExpandedOutgoingVerificationKey
 	}
+	oe.signedData = oe.envelope.GetSignedData(o.c.Round, o.layerNumber(), o.c.MyId)
+	oe.signature = oe.envelope.GetSignature()
+	return oe, nil
+}
 
-	decrypted := crypto.SecretOpen(lm.SignedCiphertext, &nonce, decryptionKey)
+// after the signature has been checked, decrypt and mark the key used
+func (o *OnionParser) decrypt(oe *openedEnvelope) ([]byte, *BootstrapKey, error) {
+	nonce := crypto.Nonce(o.c.Round, o.layerNumber(), o.c.MyId)
+	decrypted := crypto.SecretOpen(oe.envelope.SignedCiphertext, &nonce, oe.decryptionKey)
+	key := oe.key
 	o.usageLock.Lock()
 	if key.used {
 		o.usageLock.Unlock()
@@ -93,19 +169,27 @@ func (o *OnionParser) AuthenticatedOnionParse(metadata *messages.Metadata, messa
 	return decrypted, key, nil
 }
 
+// keys revoked during the layer are no longer expected, even if their envelopes were counted
 func (o *OnionParser) AllKeysAccountedFor() bool {
 	o.usageLock.Lock()
 	defer o.usageLock.Unlock()
-	ok := o.count == o.keyTable.NumKeys()
+	ok := o.count >= o.keyTable.NumKeys() && len(o.keyTable.UnusedKeys()) == 0
 	if ok {
 		o.keyTable.ResetUsage()
 	}
 	return ok
 }
 
+// the keys whose messages have not arrived in this layer
+func (o *OnionParser) MissingKeys() []*BootstrapKey {
+	o.usageLock.Lock()
+	defer o.usageLock.Unlock()
+	return o.keyTable.UnusedKeys()
+}
+
 func NewLightningRouter(c *common.CommonState, layer int, reverse bool) *LightningRouter {
 	l := &LightningRouter{
-		OutgoingBuffers: make(map[int]*buffers.MemReadWriter),
+		OutgoingBuffers: make(map[int]buffers.Buffer),
 	}
 	var length int
 	if reverse {
@@ -114,7 +198,14 @@ func NewLightningRouter(c *common.CommonState, layer int, reverse bool) *Lightni
 		length = c.OnionMessageLengths[layer+1]
 	}
 	for i := 0; i < c.NumServers; i++ {
-		l.OutgoingBuffers[i] = buffers.NewMemReadWriter(length, c.BinSize, c.Shufflers[i])
+		l.OutgoingBuffers[i] = buffers.NewBuffer(length, c.BinSize, c.Shufflers[i])
+	}
+	if reverse {
+		l.round = c.Round
+		l.links = make([]crypto.DHSharedKey, len(c.ServerPublicKeys))
+		for i := range l.links {
+			l.links[i] = c.ServerSecretKey.SharedKey(&c.ServerPublicKeys[i])
+		}
 	}
 	return l
 }
@@ -127,14 +218,21 @@ func (l *LightningRouter) AuthenticatedOnionPack(decrypted []byte, k *BootstrapK
 	}
 	if reverse {
 		dest = k.PrevServer
-		m.Key = k.VerificationKey.LookupKey()
+		m.Key = crypto.RatchetLookup(l.link(dest), k.VerificationKey.LookupKey(), l.round)
 	} else {
 		dest = k.NextServer
-		m.Key = k.OutgoingVerificationKey.LookupKey()
+		m.Key = k.roundOutgoingLookup
 	}
 	err := l.OutgoingBuffers[dest].Write(m.Marshal())
 	if err != nil {
-		return err
+		return l.drop(k, err)
 	}
 	return nil
 }
+
+func (l *LightningRouter) link(sid int) crypto.DHSharedKey {
+	if sid < 0 || sid >= len(l.links) {
+		return nil
+	}
+	return l.links[sid]
+}
@@ -0,0 +1,32 @@
+package processMessages
+
+import (
+	"sync"
+
+	"github.com/simonlangowski/lightning1/errors"
+)
+
+// The keys of messages that did not fit in the bin of their link
+// the messages are dropped instead of failing the layer, and the server revokes their paths once the layer is done
+type overflow struct {
+	overflowLock sync.Mutex
+	overflowed   []*BootstrapKey
+}
+
+// records the key if the error is an overflow, which is then not an error
+func (o *overflow) drop(k *BootstrapKey, err error) error {
+	if _, ok := err.(*errors.OverflowError); !ok {
+		return err
+	}
+	o.overflowLock.Lock()
+	defer o.overflowLock.Unlock()
+	o.overflowed = append(o.overflowed, k)
+	return nil
+}
+
+// the keys of the messages dropped so far
+func (o *overflow) Overflowed() []*BootstrapKey {
+	o.overflowLock.Lock()
+	defer o.overflowLock.Unlock()
+	return o.overflowed
+}
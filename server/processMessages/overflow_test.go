@@ -0,0 +1,48 @@
+package processMessages
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/simonlangowski/lightning1/config"
+	"github.com/simonlangowski/lightning1/crypto"
+	"github.com/simonlangowski/lightning1/server/common"
+)
+
+func TestLinkOverflow(t *testing.T) {
+	template := &common.CommonState{
+		NumServers:          1,
+		BinSize:             2,
+		OnionMessageLengths: []int{0, 128},
+		Shufflers:           []*config.Shuffler{config.NewPRGShuffler(rand.Reader)},
+	}
+	c := common.NewMockCommonStates(1, template)[0]
+	table := NewKeyLookupTable(c, 0)
+	router := NewLightningRouter(c, 0, false)
+	keys := make([]*BootstrapKey, c.BinSize+1)
+	for i := range keys {
+		key, _ := crypto.NewSigningKeyPair()
+		nextKey, _ := crypto.NewSigningKeyPair()
+		p, _ := key.ToCurvePoint()
+		var err error
+		keys[i], err = table.AddKey(key, c.ServerSecretKey.SharedKey(p), i, 0, nextKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// every message goes to the same server, so the last one does not fit in the bin
+	for i := range keys {
+		err := router.AuthenticatedOnionPack(make([]byte, crypto.SIGNATURE_SIZE+16), keys[i], false)
+		if err != nil {
+			t.Fatalf("Overflow was not handled: %v", err)
+		}
+	}
+	overflowed := router.Overflowed()
+	if len(overflowed) != 1 || overflowed[0] != keys[c.BinSize] {
+		t.Fatalf("Wrong keys overflowed")
+	}
+	router.OutgoingBuffers[0].Shuffle(nil)
+	if router.OutgoingBuffers[0].NumMessages() != c.BinSize {
+		t.Fatalf("Overflowing message was kept")
+	}
+}
@@ -14,10 +14,11 @@ type PathEstablishmentParser struct {
 	c               *common.CommonState
 	table           *KeyLookupTable
 	layer           int
-	OutgoingBuffers map[int]*buffers.MemReadWriter
+	OutgoingBuffers map[int]buffers.Buffer
 	// It might be a lot more efficient to only send to one group (e.g a group containing this server).  It depends on how well the groups are balance
 	// But then the signature only has to be checked by one group instead of many groups
 	Checkpoint *CheckpointSender
+	overflow
 }
 
 func NewPathEstablishmentParser(c *common.CommonState, table *KeyLookupTable, layer int, checkpoint *CheckpointSender) *PathEstablishmentParser {
@@ -25,61 +26,116 @@ func NewPathEstablishmentParser(c *common.CommonState, table *KeyLookupTable, la
 		c:               c,
 		table:           table,
 		layer:           layer,
-		OutgoingBuffers: make(map[int]*buffers.MemReadWriter),
+		OutgoingBuffers: make(map[int]buffers.Buffer),
 		Checkpoint:      checkpoint,
 	}
 	if checkpoint == nil {
 		for i := 0; i < c.NumServers; i++ {
-			p.OutgoingBuffers[i] = buffers.NewMemReadWriter(c.PathMessageLengths[layer+1], c.BinSize, c.Shufflers[i])
+			p.OutgoingBuffers[i] = buffers.NewBuffer(c.PathMessageLengths[layer+1], c.BinSize, c.Shufflers[i])
 		}
 	}
 	return p
 }
 
+// A path establishment message that has been decrypted, but whose tokens have not been checked
+type parsedPathMessage struct {
+	source    int
+	envelope  common.PathEstablishmentEnvelope
+	info      common.PathEstablishmentInfo
+	sharedKey crypto.DHSharedKey
+}
+
 func (p *PathEstablishmentParser) ParseRecordAndGetNext(metadata *messages.Metadata, message []byte) ([]byte, *BootstrapKey, error) {
+	pm, err := p.parse(metadata, message)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !VerifyToken(p.c.CombinedKey, &pm.envelope.InToken, p.c.Round, p.c.Round, pm.source, pm.envelope.InKey) {
+		return nil, nil, errors.TokenInvalid()
+	}
+	if !VerifyToken(p.c.CombinedKey, &pm.info.OutToken, p.c.Round+1, p.c.Round+1, p.c.MyId, pm.info.OutKey) {
+		return nil, nil, errors.TokenInvalid()
+	}
+	return p.recordAndGetNext(pm)
+}
+
+// Parse a chunk of messages, checking all of their tokens in one batch
+// returns the error of each message, nil for those recorded
+func (p *PathEstablishmentParser) ParseRecordAndGetNextBatch(metadata *messages.Metadata, chunk [][]byte) ([][]byte, []*BootstrapKey, []error) {
+	parsed := make([]*parsedPathMessage, len(chunk))
+	errs := make([]error, len(chunk))
+	batch := token.NewBatchVerifier(p.c.CombinedKey, 2*len(chunk))
+	for i := range chunk {
+		parsed[i], errs[i] = p.parse(metadata, chunk[i])
+		if errs[i] != nil {
+			continue
+		}
+		pm := parsed[i]
+		batch.Add(&pm.envelope.InToken, common.TokenContent(pm.envelope.InKey, p.c.Round, p.c.Round, pm.source))
+		batch.Add(&pm.info.OutToken, common.TokenContent(pm.info.OutKey, p.c.Round+1, p.c.Round+1, p.c.MyId))
+	}
+	valid := batch.Verify()
+	boomerangs := make([][]byte, len(chunk))
+	keys := make([]*BootstrapKey, len(chunk))
+	pos := 0
+	for i := range chunk {
+		if errs[i] == nil {
+			// tokens were added in pairs
+			if !valid[pos] || !valid[pos+1] {
+				errs[i] = errors.TokenInvalid()
+			} else {
+				boomerangs[i], keys[i], errs[i] = p.recordAndGetNext(parsed[i])
+			}
+			pos += 2
+		}
+	}
+	return boomerangs, keys, errs
+}
+
+// check the incoming key and decrypt
+func (p *PathEstablishmentParser) parse(metadata *messages.Metadata, message []byte) (*parsedPathMessage, error) {
 	boomerangLength := p.c.BoomerangMessageLengths[p.layer]
 	round := p.c.Round
 	layer := p.c.Layer
 	server := p.c.MyId
 	nonce := crypto.Nonce(round, layer, server)
-	source := metadata.Sender
+	pm := &parsedPathMessage{source: metadata.Sender}
 
-	pm := common.PathEstablishmentEnvelope{}
-	err := pm.InterpretFrom(message)
+	err := pm.envelope.InterpretFrom(message)
 	if err != nil {
-		return nil, nil, err
-	}
-
-	// TODO: Batch verification of tokens?
-	if !VerifyToken(p.c.CombinedKey, &pm.InToken, p.c.Round, p.c.Round, source, pm.InKey) {
-		return nil, nil, errors.TokenInvalid()
+		return nil, err
 	}
-	tokenHash := pm.InToken.Hash()
+	tokenHash := pm.envelope.InToken.Hash()
 	if p.c.HashToServer(&tokenHash) != uint64(p.c.MyId) {
-		return nil, nil, errors.WrongServerError()
+		return nil, errors.WrongServerError()
 	}
 
-	inPoint, err := pm.InKey.ToCurvePoint()
+	inPoint, err := pm.envelope.InKey.ToCurvePoint()
 	if err != nil {
-		return nil, nil, errors.BadElementError()
+		return nil, errors.BadElementError()
 	}
 
-	sharedKey := p.table.secretKey.SharedKey(inPoint)
-	if !crypto.Verify(pm.InKey, pm.GetSignedData(round, layer, server), pm.ReadSignature()) {
-		return nil, nil, errors.DecryptionFailure()
+	pm.sharedKey = p.table.secretKey.SharedKey(inPoint)
+	if !crypto.Verify(pm.envelope.InKey, pm.envelope.GetSignedData(round, layer, server), pm.envelope.ReadSignature()) {
+		return nil, errors.DecryptionFailure()
 	}
-	decrypted := crypto.SecretOpen(pm.SignedCiphertext, &nonce, sharedKey)
-	pi := common.PathEstablishmentInfo{}
-	err = pi.InterpretFrom(decrypted, boomerangLength)
+	decrypted := crypto.SecretOpen(pm.envelope.SignedCiphertext, &nonce, pm.sharedKey)
+	err = pm.info.InterpretFrom(decrypted, boomerangLength)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	if !VerifyToken(p.c.CombinedKey, &pi.OutToken, p.c.Round+1, p.c.Round+1, p.c.MyId, pi.OutKey) {
-		return nil, nil, errors.TokenInvalid()
-	}
-	tokenHash = pi.OutToken.Hash()
+	return pm, nil
+}
+
+// after the tokens have been checked, record the key and forward the message
+func (p *PathEstablishmentParser) recordAndGetNext(pm *parsedPathMessage) ([]byte, *BootstrapKey, error) {
+	round := p.c.Round
+	layer := p.c.Layer
+	server := p.c.MyId
+	pi := &pm.info
+	tokenHash := pi.OutToken.Hash()
 	next := int(p.c.HashToServer(&tokenHash))
-	key, err := p.table.AddKey(pm.InKey, sharedKey, source, next, pi.OutKey)
+	key, err := p.table.AddKey(pm.envelope.InKey, pm.sharedKey, pm.source, next, pi.OutKey)
 	if err != nil {
 		return nil, nil, errors.BadElementError()
 	}
@@ -107,7 +163,8 @@ func (p *PathEstablishmentParser) ParseRecordAndGetNext(metadata *messages.Metad
 		err = p.Checkpoint.AddReverseMessage(pi.BoomerangEnvelope, &cm, key, group)
 	}
 	if err != nil {
-		return nil, nil, err
+		// an overflowing message is skipped like a missing one
+		return nil, nil, p.drop(key, err)
 	}
 
 	return pi.BoomerangEnvelope, key, nil
@@ -8,29 +8,30 @@ import (
 
 type TrusteeRouter struct {
 	c               *common.CommonState
-	OutgoingBuffers map[int]*buffers.MemReadWriter
+	OutgoingBuffers map[int]buffers.Buffer
+	overflow
 }
 
 func NewTrusteeRouter(c *common.CommonState, layer int) *TrusteeRouter {
 	t := &TrusteeRouter{
 		c:               c,
-		OutgoingBuffers: make(map[int]*buffers.MemReadWriter),
+		OutgoingBuffers: make(map[int]buffers.Buffer),
 	}
 	for i := 0; i < c.NumGroups; i++ {
-		t.OutgoingBuffers[i] = buffers.NewMemReadWriter(c.OnionMessageLengths[layer], c.GroupBinSize, c.Shufflers[i])
+		t.OutgoingBuffers[i] = buffers.NewBuffer(c.OnionMessageLengths[layer], c.GroupBinSize, c.Shufflers[i])
 	}
 	return t
 }
 
 func (t *TrusteeRouter) Pack(decrypted []byte, destination *BootstrapKey) error {
 	pm := common.FinalLightningMessage{
-		AnonymousVerificationKey: destination.OutgoingVerificationKey,
+		AnonymousVerificationKey: destination.roundAnonymousKey,
 		Signature:                decrypted[:crypto.SIGNATURE_SIZE],
 		Message:                  decrypted[crypto.SIGNATURE_SIZE:],
 	}
 	err := t.OutgoingBuffers[destination.NextServer].Write(pm.Marshal())
 	if err != nil {
-		return err
+		return t.drop(destination, err)
 	}
 	return nil
 }
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/simonlangowski/lightning1/config"
+	coord "github.com/simonlangowski/lightning1/coordinator/messages"
+	"google.golang.org/protobuf/proto"
+)
+
+// Run lightning rounds on the clock of the schedule, without a coordinator
+// The paths must already be established (or loaded with SetKeyDirectory)
+// Each round is set up as soon as the previous round completes, so clients submitting early wait for it,
+// and is started when its submission window closes
+// A round that completes late delays the next round rather than skipping it, so that the servers stay in the same round
+// Returns after the number of rounds of the schedule, or runs forever if it is 0
+func (s *Server) RunSchedule(schedule *coord.Schedule) error {
+	ctx := context.Background()
+	round := config.NextOpenRound(schedule, time.Now())
+	for n := int64(0); schedule.NumRounds == 0 || n < schedule.NumRounds; n++ {
+		info := proto.Clone(schedule.Round).(*coord.RoundInfo)
+		info.Round = int64(round)
+		info.PathEstablishment = false
+		_, err := s.RoundSetup(ctx, info)
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Until(config.SubmissionDeadline(schedule, round)))
+		_, err = s.RoundStart(ctx, info)
+		if err != nil {
+			return err
+		}
+		round++
+		if behind := time.Since(config.SubmissionDeadline(schedule, round)); behind > 0 {
+			log.Printf("%d: round %d is %v behind the schedule", s.CommonState.MyId, round, behind)
+		}
+	}
+	return nil
+}
@@ -1,27 +1,35 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log"
 	"net"
 	"os"
 	"runtime/pprof"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/simonlangowski/lightning1/config"
 	coord "github.com/simonlangowski/lightning1/coordinator/messages"
 	"github.com/simonlangowski/lightning1/crypto"
-	"github.com/simonlangowski/lightning1/crypto/pairing/mcl"
 	"github.com/simonlangowski/lightning1/crypto/token"
 	"github.com/simonlangowski/lightning1/errors"
 	"github.com/simonlangowski/lightning1/network"
 	"github.com/simonlangowski/lightning1/network/buffers"
 	"github.com/simonlangowski/lightning1/network/messages"
 	"github.com/simonlangowski/lightning1/network/synchronization"
+	"github.com/simonlangowski/lightning1/server/audit"
+	"github.com/simonlangowski/lightning1/server/beacon"
+	"github.com/simonlangowski/lightning1/server/blame"
 	"github.com/simonlangowski/lightning1/server/checkpoint"
 	"github.com/simonlangowski/lightning1/server/common"
+	"github.com/simonlangowski/lightning1/server/keyExchange"
 	"github.com/simonlangowski/lightning1/server/prepareMessages"
 	"github.com/simonlangowski/lightning1/server/processMessages"
+	"google.golang.org/protobuf/proto"
 )
 
 type Server struct {
@@ -39,8 +47,9 @@ type Server struct {
 	lastLayer   int
 	finalRouter *processMessages.TrusteeRouter
 	// path establishment boomerangs
-	receiptLayer int
-	receipts     map[int64][]byte
+	receiptLayer   int
+	receipts       map[int64][]byte
+	receiptBuckets *prepareMessages.ReceiptBuckets // receipts that stopped at this server beyond the first layer
 	// middle layers
 	lightingRouters []*processMessages.LightningRouter
 	// process path establishment messages
@@ -55,17 +64,37 @@ type Server struct {
 	roundComplete   *sync.Cond
 	mu              sync.RWMutex
 	receiptLock     sync.Mutex
-	started         bool
+	accusations     []*blame.Accusation
+	received        map[receivedBatch]batchEvidence // the batches received in this round
+	verdicts        []*blame.Verdict                // tracebacks of the paths clients complained about
+	repairs         *blame.RepairVotes              // the group members that asked to revoke each path
+	blameLock       sync.Mutex
+	// revocations sent while finishing a layer, which are delivered before the batch of the layer is sent
+	revocations sync.WaitGroup
+	// messages dropped since the last churn report, because their links overflowed
+	overflowed int
+	// servers that missed a deadline here since the last churn report
+	silent       map[int64]bool
+	started      bool
+	keyDirectory string // key tables are checkpointed here if set
+	audit        *audit.Log
+	keyExchange  *keyExchange.KeyExchange
+	groupKeys    map[int32]*keyExchange.GroupKeys // from the key exchange, for each group this server is in
+	beacon       *beacon.Beacon
 	coord.UnimplementedCoordinatorHandlerServer
 }
 
 func NewServer(configs *config.Servers, groups *config.Groups, handler *Handlers, addr string) *Server {
 	myId, _ := network.FindConfig(addr, configs.Servers)
 	s := &Server{
-		GroupAliases: make(map[int32]*groupMember),
-		CommonState:  common.NewCommonState(configs.Servers, myId, groups),
-		Keys:         make([]*processMessages.KeyLookupTable, 0),
-		handler:      handler,
+		GroupAliases:   make(map[int32]*groupMember),
+		CommonState:    common.NewCommonState(configs.Servers, myId, groups),
+		Keys:           make([]*processMessages.KeyLookupTable, 0),
+		handler:        handler,
+		receiptBuckets: prepareMessages.NewReceiptBuckets(),
+		received:       make(map[receivedBatch]batchEvidence),
+		repairs:        blame.NewRepairVotes(),
+		silent:         make(map[int64]bool),
 	}
 	config.InitLogger(s.CommonState.MyId)
 	for gid, cfg := range groups.Groups {
@@ -76,6 +105,8 @@ func NewServer(configs *config.Servers, groups *config.Groups, handler *Handlers
 			}
 		}
 	}
+	s.keyExchange = keyExchange.NewKeyExchange(s.CommonState)
+	s.beacon = beacon.NewBeacon(s.CommonState)
 	s.roundComplete = sync.NewCond(s.mu.RLocker())
 	s.TcpConnections = network.NewConnectionManager(s.CommonState.Configs, s.CommonState.MyId)
 	handler.SetServer(s)
@@ -100,12 +131,37 @@ func (s *Server) Connect() error {
 // process messages received directly from clients
 func (s *Server) HandleSubmissionMessage(m *messages.SignedMessage) (*messages.SignedMessage, error) {
 	// Signature will be checked in signed encryption.
+	if m.Round != s.CommonState.Round || s.synchronizer.Layer() != 0 {
+		// the round already started
+		return nil, errors.Late(m.Round)
+	}
 	s.synchronizer.Sync(0)
 	if s.pathRound {
 		return nil, s.handlePathMessage(&m.Metadata, m.Data)
-	} else {
-		return nil, s.handleLightningMessage(&m.Metadata, m.Data)
 	}
+	err := s.handleLightningMessage(&m.Metadata, m.Data)
+	if err == nil && s.isRevokedSubmission(m.Data) {
+		// the path was revoked, after an overflow or a complaint, so tell the client to establish a new one
+		return s.pathRevokedNotice(m.Data[:crypto.KEY_SIZE]), nil
+	}
+	return nil, err
+}
+
+func (s *Server) isRevokedSubmission(data []byte) bool {
+	if len(data) < crypto.KEY_SIZE {
+		return false
+	}
+	envelope := common.LightningEnvelope{}
+	envelope.InterpretFrom(data)
+	return s.Keys[0].IsRevokedRound(&envelope.Key)
+}
+
+// the signed lookup key of the round, which the client checks against its submission
+func (s *Server) pathRevokedNotice(key []byte) *messages.SignedMessage {
+	resp := messages.NewSignedMessage(len(key), s.CommonState.Round, 0, s.CommonState.MyId, 0, 0, 1, messages.NetworkMessage_ClientPathRevoked)
+	copy(resp.Data, key)
+	s.CommonState.Sign(resp)
+	return resp
 }
 
 /*
@@ -157,9 +213,32 @@ func (s *Server) checkMessage(m *messages.Metadata) error {
 func (s *Server) handleLightningMessage(m *messages.Metadata, message []byte) error {
 	layer := s.CommonState.Layer
 	decryption, key, err := s.onionParsers[layer].AuthenticatedOnionParse(m, message)
-	if err != nil {
+	countParsed(layer, []*processMessages.BootstrapKey{key}, []error{err})
+	if err != nil || key == nil {
 		return err
 	}
+	return s.forwardLightningMessage(layer, decryption, key)
+}
+
+// a chunk of lightning messages, whose signatures are checked together
+func (s *Server) handleLightningMessages(m *messages.Metadata, chunk [][]byte) error {
+	layer := s.CommonState.Layer
+	decryptions, keys, errs := s.onionParsers[layer].AuthenticatedOnionParseBatch(m, chunk)
+	countParsed(layer, keys, errs)
+	err := processMessages.FirstError(errs)
+	for i := range decryptions {
+		if keys[i] == nil {
+			continue
+		}
+		e := s.forwardLightningMessage(layer, decryptions[i], keys[i])
+		if err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *Server) forwardLightningMessage(layer int, decryption []byte, key *processMessages.BootstrapKey) error {
 	if layer != s.lastLayer {
 		return s.lightingRouters[layer].AuthenticatedOnionPack(decryption, key, false)
 	} else {
@@ -171,10 +250,32 @@ func (s *Server) handleLightningMessage(m *messages.Metadata, message []byte) er
 func (s *Server) handlePathMessage(m *messages.Metadata, message []byte) error {
 	layer := s.CommonState.Layer
 	boomerangMessage, key, err := s.pathEstablishmentRouters[layer].ParseRecordAndGetNext(m, message)
-	if err != nil {
+	countParsed(layer, []*processMessages.BootstrapKey{key}, []error{err})
+	if err != nil || key == nil {
 		return err
 	}
+	return s.forwardPathMessage(layer, boomerangMessage, key)
+}
+
+// a chunk of path messages, whose tokens are checked together
+func (s *Server) handlePathMessages(m *messages.Metadata, chunk [][]byte) error {
+	layer := s.CommonState.Layer
+	boomerangMessages, keys, errs := s.pathEstablishmentRouters[layer].ParseRecordAndGetNextBatch(m, chunk)
+	countParsed(layer, keys, errs)
+	err := processMessages.FirstError(errs)
+	for i := range boomerangMessages {
+		if keys[i] == nil {
+			continue
+		}
+		e := s.forwardPathMessage(layer, boomerangMessages[i], keys[i])
+		if err == nil {
+			err = e
+		}
+	}
+	return err
+}
 
+func (s *Server) forwardPathMessage(layer int, boomerangMessage []byte, key *processMessages.BootstrapKey) error {
 	if layer == 0 {
 		s.recordReceipts(boomerangMessage, key)
 	} else if layer != s.lastLayer {
@@ -189,9 +290,33 @@ func (s *Server) handlePathMessage(m *messages.Metadata, message []byte) error {
 func (s *Server) HandleBoomerangMessage(m *messages.Metadata, message []byte) error {
 	layer := s.CommonState.Layer
 	decryption, key, err := s.onionParsers[layer].AuthenticatedOnionParse(m, message)
-	if err != nil {
+	countParsed(layer, []*processMessages.BootstrapKey{key}, []error{err})
+	if err != nil || key == nil {
 		return err
 	}
+	return s.forwardBoomerangMessage(layer, decryption, key)
+}
+
+// a chunk of boomerang messages, whose signatures are checked together
+func (s *Server) HandleBoomerangMessages(m *messages.Metadata, chunk [][]byte) error {
+	layer := s.CommonState.Layer
+	decryptions, keys, errs := s.onionParsers[layer].AuthenticatedOnionParseBatch(m, chunk)
+	countParsed(layer, keys, errs)
+	err := processMessages.FirstError(errs)
+	for i := range decryptions {
+		if keys[i] == nil {
+			continue
+		}
+		e := s.forwardBoomerangMessage(layer, decryptions[i], keys[i])
+		if err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *Server) forwardBoomerangMessage(layer int, decryption []byte, key *processMessages.BootstrapKey) error {
+	var err error
 	if layer > s.receiptLayer {
 		err = s.lightingRouters[layer].AuthenticatedOnionPack(decryption, key, true)
 	} else if layer == 0 {
@@ -199,8 +324,9 @@ func (s *Server) HandleBoomerangMessage(m *messages.Metadata, message []byte) er
 	} else if layer == s.receiptLayer {
 		// the receipt has passed through at least one honest server with high probability and no server has complained
 		// so we can stop passing the receipt backwards
-		// no one would actually check these because that would break anonymity
-		// these are just stored/checked for test purposes
+		// the client fetches the bucket of its receipt from every server (see prepareMessages.ReceiptBuckets)
+		s.receiptBuckets.Add(decryption)
+		// these are also kept for the coordinator to check in tests
 		s.receiptLock.Lock()
 		defer s.receiptLock.Unlock()
 		s.receipts[int64(len(s.receipts))] = decryption
@@ -223,9 +349,17 @@ func (s *Server) OnThreshold(layer int) (int, int) {
 	s.mu.Lock()
 	if layer != s.pathLayer {
 		if !s.onionParsers[layer].AllKeysAccountedFor() {
-			panic(errors.MissingMessages())
+			s.blameMissingMessages(layer)
 		}
 	}
+	if !s.pathRound && layer == s.lastLayer {
+		s.revokeOverflowed(layer, s.finalRouter.Overflowed(), true)
+	} else if !s.pathRound || layer != s.pathLayer {
+		s.revokeOverflowed(layer, s.lightingRouters[layer].Overflowed(), true)
+	} else {
+		// the next server never saw the key
+		s.revokeOverflowed(layer, s.pathEstablishmentRouters[layer].Overflowed(), false)
+	}
 	// setup next layer
 	nextLayer := layer + s.direction
 	// track layer
@@ -240,12 +374,13 @@ func (s *Server) OnThreshold(layer int) (int, int) {
 		s.lightingRouters[nextLayer] = processMessages.NewLightningRouter(s.CommonState, nextLayer, true)
 	}
 	// start sending messages to next layer
-	go func(lBufs map[int]*buffers.MemReadWriter) {
+	go func(lBufs map[int]buffers.Buffer) {
 		var err error = nil
 		if layer == s.receiptLayer {
 			// Mark round completed
 			// Release receipts
 			// Wait for all clients to confirm receipt delivery and then continue
+			buffers.CloseAll(lBufs)
 			s.isRoundComplete = true
 			s.roundComplete.Broadcast()
 		} else {
@@ -256,10 +391,13 @@ func (s *Server) OnThreshold(layer int) (int, int) {
 					// boomerang messages
 					t = messages.NetworkMessage_ServerMessageReverse
 				}
+				s.revocations.Wait()
 				err = s.TcpConnections.SendShuffleMessages(lBufs, s.CommonState, nextLayer, t)
 			} else {
 				if !s.pathRound {
 					// send to trustees
+					buffers.CloseAll(lBufs)
+					s.revocations.Wait()
 					_, err := s.TcpConnections.SendGroupShuffleMessages(s.finalRouter.OutgoingBuffers, s.CommonState, messages.NetworkMessage_GroupCheckpointSignature, 0)
 					if err != nil {
 						panic(err)
@@ -270,10 +408,17 @@ func (s *Server) OnThreshold(layer int) (int, int) {
 					// route through anytrust group
 					checkpoint := s.pathEstablishmentRouters[layer].Checkpoint
 					// this waits for all groups to respond
+					start := time.Now()
 					err = checkpoint.SendAndRecieve(s.TcpConnections)
+					checkpointLatency.Since(start)
 					if err != nil {
 						panic(err)
 					}
+					for group, members := range checkpoint.Blamed() {
+						log.Printf("%d: members %v of group %d sent partial decryptions that did not verify in round %d", s.CommonState.MyId, members, group, s.CommonState.Round)
+					}
+					// the boomerangs of those groups cannot be decrypted, so their paths are revoked
+					s.revokeDropped(layer, checkpoint.Dropped(), false, "decryption")
 					decryptions, keys := checkpoint.GetDecrypted()
 					// unless there's only one layer, this is never the receipt layer as well
 					for idx := range decryptions {
@@ -282,7 +427,9 @@ func (s *Server) OnThreshold(layer int) (int, int) {
 							panic(err)
 						}
 					}
+					s.revokeOverflowed(layer, s.lightingRouters[layer].Overflowed(), false)
 					// send back boomerang messages
+					s.revocations.Wait()
 					err = s.TcpConnections.SendShuffleMessages(s.lightingRouters[layer].OutgoingBuffers, s.CommonState, s.CommonState.Layer, messages.NetworkMessage_ServerMessageReverse)
 				}
 			}
@@ -290,7 +437,13 @@ func (s *Server) OnThreshold(layer int) (int, int) {
 		if err != nil {
 			panic(err)
 		}
-		// free memory - actually needs to be stored until end of round for blame protocols (e.g on disk?)
+		// including those of the receipt layer, which sends no batch
+		s.revocations.Wait()
+		if s.pathRound {
+			// the keys for this layer are final
+			s.checkpointKeys(layer)
+		}
+		// free memory - the received batches are kept in the audit log for blame
 		s.onionParsers[layer] = nil
 		s.lightingRouters[layer] = nil
 		// do not let next onThreshold start until this one completes
@@ -300,10 +453,56 @@ func (s *Server) OnThreshold(layer int) (int, int) {
 	return s.CommonState.NumServers, nextLayer
 }
 
+// Called by the synchronizer at the start of each layer
+// Only layers with messages from other servers have a deadline
+func (s *Server) Deadline(layer int) time.Duration {
+	var messageLength int
+	if s.pathRound {
+		if layer > s.pathLayer || layer < s.receiptLayer {
+			return 0
+		} else if layer == s.pathLayer {
+			messageLength = s.CommonState.PathMessageLengths[layer]
+		} else {
+			messageLength = s.CommonState.OnionMessageLengths[layer+1]
+		}
+	} else {
+		if layer < 1 || layer > s.lastLayer {
+			// clients submit to the first layer
+			return 0
+		}
+		messageLength = s.CommonState.OnionMessageLengths[layer]
+	}
+	dataLen := s.CommonState.BinSize * s.CommonState.NumServers * messageLength
+	return config.MinLayerTimeout + config.LayerTimeoutFactor*network.BandwidthTimeout(dataLen)
+}
+
+// Called by the synchronizer when servers miss the deadline of a layer
+// The layer continues without them, but their users are only routed to a replacement once the coordinator
+// drops them for every server, otherwise the servers would route the same tokens differently
+func (s *Server) OnTimeout(layer int, silent []int) {
+	log.Printf("%d: servers %v missed the deadline for round %d layer %d", s.CommonState.MyId, silent, s.CommonState.Round, layer)
+	s.blameLock.Lock()
+	defer s.blameLock.Unlock()
+	for _, sid := range silent {
+		s.silent[int64(sid)] = true
+	}
+}
+
+// apply the servers dropped by the coordinator, including those dropped by other servers
+func (s *Server) dropServers(ids []int64) {
+	s.CommonState.DropServers(ids)
+	dropped := make([]int, len(ids))
+	for i, sid := range ids {
+		dropped[i] = int(sid)
+	}
+	s.synchronizer.Drop(dropped)
+}
+
 func (s *Server) SetupNewPathEstablishmentRound(numLayers, receipt_size, boomerangLimit int, last bool) {
 	s.pathLayer = 0
 	s.receiptLayer = 0
 	s.receipts = make(map[int64][]byte)
+	s.receiptBuckets = prepareMessages.NewReceiptBuckets()
 	s.lastLayer = numLayers - 1
 	s.pathRound = true
 	s.direction = -1
@@ -316,7 +515,8 @@ func (s *Server) SetupNewPathEstablishmentRound(numLayers, receipt_size, boomera
 }
 
 // index of last layer e.g 0 for one layer
-func (s *Server) SetupNewLightningRound(numLayers, payloadSize int) {
+// if fragmented, the trustee groups reassemble the fragments of messages sent across several slots
+func (s *Server) SetupNewLightningRound(numLayers, payloadSize int, fragmented bool) error {
 	s.lastLayer = numLayers - 1
 	s.receiptLayer = -1
 	s.pathLayer = -1
@@ -330,46 +530,49 @@ func (s *Server) SetupNewLightningRound(numLayers, payloadSize int) {
 	s.lightingRouters[0] = processMessages.NewLightningRouter(s.CommonState, 0, false)
 	s.finalRouter = processMessages.NewTrusteeRouter(s.CommonState, s.lastLayer+1)
 	for _, g := range s.GroupAliases {
-		g.NewLightningRound(s.lastLayer + 1)
-	}
-}
-
-// func (s *Server) DoKeyExchange(c *network.Caller) error {
-// 	done := make(chan error)
-// 	for _, g := range s.groupAliases {
-// 		go func(g *groupMember) {
-// 			err := g.ExchangeKeys(c)
-// 			if err != nil {
-// 				done <- err
-// 			}
-// 			done <- nil
-// 		}(g)
-// 	}
-// 	for range s.groupAliases {
-// 		err := <-done
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-// 	for _, g := range s.groupAliases {
-// 		s.commonState.SetKeys(g.GetPublicKeys())
-// 		break
-// 	}
-// 	return nil
-// }
-
-// func (s *Server) HandleInformationRequest() (*messages.SignedMessage, error) {
-// 	i := keyExchange.InformationRequest{
-// 		Round:       uint32(s.commonState.Round),
-// 		NumLayers:   uint32(s.commonState.NumLayers),
-// 		SigningKeys: s.commonState.VerificationKeys,
-// 	}
-// 	m := &messages.SignedMessage{
-// 		Data: make([]byte, i.Len()),
-// 	}
-// 	i.PackTo(m.Data)
-// 	return m, nil
-// }
+		g.NewLightningRound(s.lastLayer+1, fragmented)
+	}
+	// the paths are reused with the keys of this round
+	for layer, table := range s.Keys {
+		err := table.Ratchet(s.CommonState.Round, layer == s.lastLayer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Give each anytrust group the anonymous keys of this round of the paths ending at this server
+// the groups only accept keys proven to be ratchets of the keys of paths established through this server
+func (s *Server) registerAnonymousKeys() {
+	var wg sync.WaitGroup
+	for gid, keys := range s.Keys[s.lastLayer].AnonymousKeys() {
+		// sorted, so that the order says nothing about the paths
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i].Key, keys[j].Key) < 0 })
+		for _, sid := range s.CommonState.GroupConfigs.Groups[int64(gid)].Servers {
+			if s.CommonState.IsDropped(int(sid)) {
+				continue
+			}
+			m := checkpoint.NewAnonymousKeys(s.CommonState, gid, int(sid), keys)
+			if int(sid) == s.CommonState.MyId {
+				err := s.GroupAliases[int32(gid)].CheckpointState.HandleAnonymousKeys(m)
+				if err != nil {
+					log.Printf("%d: could not register anonymous keys with group %d: %v", s.CommonState.MyId, gid, err)
+				}
+				continue
+			}
+			wg.Add(1)
+			go func(gid, sid int) {
+				defer wg.Done()
+				_, err := s.Caller.SendSignedMessage(sid, m)
+				if err != nil {
+					log.Printf("%d: could not register anonymous keys with server %d of group %d: %v", s.CommonState.MyId, sid, gid, err)
+				}
+			}(gid, int(sid))
+		}
+	}
+	wg.Wait()
+}
 
 func (s *Server) RoundSetup(_ context.Context, m *coord.RoundInfo) (*coord.Empty, error) {
 	s.mu.Lock()
@@ -384,29 +587,46 @@ func (s *Server) RoundSetup(_ context.Context, m *coord.RoundInfo) (*coord.Empty
 		errors.MonitorMemory("server", s.CommonState.MyId, m.Interval)
 	}
 	s.CommonState.Round = int(m.Round)
+	roundGauge.Set(float64(m.Round))
 	s.CommonState.BinSize = int(m.BinSize)
 	// TODO: chernoff on M messages / n * numGroups (rather than n * n * L for regular bin size)
 	s.CommonState.GroupBinSize = int(m.BinSize) * s.CommonState.NumServers
 	s.CommonState.NumLayers = int(m.NumLayers)
 	s.CommonState.Layer = 0
 	s.isRoundComplete = false
+	s.blameLock.Lock()
+	s.received = make(map[receivedBatch]batchEvidence)
+	s.blameLock.Unlock()
 	s.synchronizer = synchronization.NewSynchronizer(s.CommonState.Round, 0, s.CommonState.NumServers, s)
+	s.dropServers(append(m.DroppedServers, s.CommonState.DroppedServers()...))
 	numLayers := int(m.NumLayers)
 	if m.Round == 0 {
 		s.Keys = make([]*processMessages.KeyLookupTable, numLayers)
 		for i := range s.Keys {
-			s.Keys[i] = processMessages.NewKeyLookupTable(s.CommonState)
+			s.Keys[i] = processMessages.NewKeyLookupTable(s.CommonState, i)
 		}
+	} else if len(s.Keys) != numLayers {
+		// rejoining after a restart requires the keys loaded in SetKeyDirectory
+		return nil, errors.KeyNotFound()
+	}
+	if m.Round == 0 || s.onionParsers == nil {
 		s.onionParsers = make([]*processMessages.OnionParser, numLayers)
 		s.lightingRouters = make([]*processMessages.LightningRouter, numLayers)
 	}
 	if m.PathEstablishment {
 		s.SetupNewPathEstablishmentRound(int(m.NumLayers), int(m.MessageSize), int(m.BoomerangLimit), m.LastLayer)
 	} else {
-		s.SetupNewLightningRound(int(m.NumLayers), int(m.MessageSize))
+		err := s.SetupNewLightningRound(int(m.NumLayers), int(m.MessageSize), m.SlotsPerUser > 0)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// this will allow processing of messages for this round
 	s.handler.SetRound(s.CommonState.Round)
+	if !m.PathEstablishment {
+		// after the round is set, since the groups wait for their own setup of the round
+		s.registerAnonymousKeys()
+	}
 	// log.Printf("%d: round setup", s.CommonState.MyId)
 	return &coord.Empty{}, nil
 }
@@ -433,13 +653,20 @@ func (s *Server) RoundStart(_ context.Context, m *coord.RoundInfo) (*coord.Empty
 
 	if s.pathRound {
 		s.mu.Lock()
+		s.dropServers(m.DroppedServers)
 		s.CommonState.Round = int(m.Round)
+		roundGauge.Set(float64(m.Round))
 		s.pathLayer = int(m.NextLayer)
 		s.CommonState.Layer = s.pathLayer
 		s.isRoundComplete = false
 		startingLayer := s.pathLayer - 1
 		s.receiptLayer = int(m.ReceiptLayer)
 		s.receipts = make(map[int64][]byte)
+		s.receiptBuckets = prepareMessages.NewReceiptBuckets()
+		// once per round, for each layer the boomerang messages return through
+		for layer := s.receiptLayer; layer < s.pathLayer; layer++ {
+			s.Keys[layer].RatchetLookups(s.CommonState.Round)
+		}
 		checkpoint := (*processMessages.CheckpointSender)(nil)
 		if s.pathLayer == s.lastLayer {
 			checkpoint = processMessages.NewCheckpointSender(s.CommonState, s.pathLayer)
@@ -529,55 +756,98 @@ func (s *Server) GetReceipt(m *messages.SignedMessage) (*messages.SignedMessage,
 	if len(receipt) == 0 {
 		return nil, errors.ClientNotFoundError()
 	}
-	resp := messages.NewSignedMessage(len(receipt), s.CommonState.Round, s.CommonState.Layer, s.CommonState.MyId, 0, 0, 1, m.Type)
+	// addressed to the client, so it can show the receipt in a complaint
+	resp := messages.NewSignedMessage(len(receipt), s.CommonState.Round, s.CommonState.Layer, s.CommonState.MyId, 0, int(c.ID), 1, m.Type)
 	copy(resp.Data, receipt)
 	s.CommonState.Sign(resp)
 	return resp, nil
 }
 
+// Any client can fetch a bucket of the receipts that stopped at this server, without identifying itself
+func (s *Server) GetReceiptBucket(m *messages.SignedMessage) (*messages.SignedMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for !s.isRoundComplete {
+		s.roundComplete.Wait()
+	}
+	r := prepareMessages.ReceiptBucketRequest{}
+	err := r.InterpretFrom(m.Data)
+	if err != nil {
+		return nil, err
+	}
+	bucket := s.receiptBuckets.Get(r.Bucket)
+	resp := messages.NewSignedMessage(len(bucket), s.CommonState.Round, s.CommonState.Layer, s.CommonState.MyId, 0, 0, 1, m.Type)
+	copy(resp.Data, bucket)
+	s.CommonState.Sign(resp)
+	return resp, nil
+}
+
+// Called first with no keys to run the key exchange, which returns the public keys
+// Then called with the public keys agreed on by all servers
 func (s *Server) KeySet(_ context.Context, info *coord.KeyInformation) (*coord.KeyInformation, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	// set keys for each group based on the info
-
-	if s.CommonState.CombinedKey == nil {
-		tokenPublicKey := &token.TokenPublicKey{}
-		groupPublicKey := crypto.DHPublicKey{}
-		err := tokenPublicKey.InterpretFrom(info.TokenPublicKey)
-		if err != nil {
-			return nil, err
-		}
-		err = groupPublicKey.InterpretFrom(info.GroupKey)
-		if err != nil {
-			return nil, err
-		}
-
-		s.CommonState.CombinedKey = tokenPublicKey
-		s.CommonState.GroupPublicKey = groupPublicKey
+	if len(info.TokenPublicKey) == 0 {
+		return s.exchangeKeys()
 	}
-
-	g := s.GroupAliases[int32(info.GroupId)]
-
-	if g == nil {
-		return &coord.KeyInformation{}, nil
+	tokenPublicKey := &token.TokenPublicKey{}
+	groupPublicKey := crypto.DHPublicKey{}
+	err := tokenPublicKey.InterpretFrom(info.TokenPublicKey)
+	if err != nil {
+		return nil, err
 	}
-	tokenShare := mcl.Fr{}
-	groupShare := &crypto.DHPrivateKey{}
-
-	err := tokenShare.InterpretFrom(info.TokenKeyShare)
+	err = groupPublicKey.InterpretFrom(info.GroupKey)
 	if err != nil {
 		return nil, err
 	}
-	tokenSigningKey := token.NewTokenSigningKey(&tokenShare)
-	err = groupShare.InterpretFrom(info.GroupShare)
+	// set keys for each group from the exchange
+	for gid, g := range s.GroupAliases {
+		keys := s.groupKeys[gid]
+		if keys == nil {
+			return nil, errors.KeyNotFound()
+		}
+		if !keys.TokenPublicKey.IsEqual(&tokenPublicKey.X) || !keys.GroupPublicKey.Equals(&groupPublicKey) || !proto.Equal(shareKeys(keys), info.TokenShareKeys[int64(gid)]) {
+			return nil, errors.GroupAgreementError()
+		}
+		g.SetKeys(keys.SigningKey(), &keys.GroupShare)
+	}
+	err = s.CommonState.SetPublicGroupKeys(info.TokenShareKeys)
 	if err != nil {
 		return nil, err
 	}
-
-	g.SetKeys(tokenSigningKey, groupShare)
+	s.CommonState.CombinedKey = tokenPublicKey
+	s.CommonState.GroupPublicKey = groupPublicKey
 	return &coord.KeyInformation{}, nil
 }
 
+// The servers this server stopped waiting for, and the messages it dropped since the last report
+func (s *Server) GetChurn(_ context.Context, _ *coord.RoundInfo) (*coord.ChurnReport, error) {
+	s.blameLock.Lock()
+	overflowed := s.overflowed
+	s.overflowed = 0
+	silent := make([]int64, 0, len(s.silent))
+	for sid := range s.silent {
+		silent = append(silent, sid)
+	}
+	s.silent = make(map[int64]bool)
+	s.blameLock.Unlock()
+	return &coord.ChurnReport{ServerId: int64(s.CommonState.MyId), Silent: silent, Overflowed: int64(overflowed)}, nil
+}
+
+// Run the randomness beacon with the other servers, to choose the groups of an epoch
+func (s *Server) RunBeacon(_ context.Context, request *coord.BeaconTranscript) (*coord.BeaconTranscript, error) {
+	s.mu.Lock()
+	if s.Caller == nil {
+		err := s.Connect()
+		if err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+	s.mu.Unlock()
+	return s.beacon.Run(s.Caller, request)
+}
+
 func (s *Server) ReadStream(m *messages.Metadata, conn net.Conn) *network.ConnectionReader {
 	numMessages := m.NumMessages
 	var messageSize int